@@ -23,14 +23,66 @@
 //   - 0: Success
 //   - 1: Parse error or runtime error
 //   - 2: Usage error or invalid query syntax
+//   - 5: Error raised by the query with error(), as in
+//     'if .port < 1024 then error("privileged port") else . end'
+//     or in an edit such as
+//     '.port |= if . < 1024 then error("privileged port") else . end'
 //
 // # Query Syntax
 //
-// Queries use dot-separated paths:
+// Queries use dot-separated paths, combined with pipes:
 //
 //   - '.key' - access top-level key
 //   - '.nested.key' - access nested key
 //   - '.array' - access array values
+//   - '.array[0]' - access an array element (negative indexes count from the end)
+//   - '.array[1:3]' - slice an array
+//   - '.array[]' or '.table.*' - every element or value, one result per line
+//   - '.a | .b' - pipe each result of one filter into the next
+//   - '.table | keys' - sorted keys of a table
+//   - '.servers | sort_by(.port) | map(.name)' - jq collection builtins
+//   - '.port | toml_type' - exact TOML type, such as integer or local-date
+//   - '.version | split(".") | .[0]' - string and regex builtins
+//   - '"\(.host):\(.port)"', '.row | @csv' - string interpolation and formats
+//   - '.blob | fromjson', '.server | tojson' - parse and serialize embedded JSON, TOML and YAML
+//   - '.port + 1', '.defaults * .prod' - arithmetic; integers stay integers
+//   - '.expires < (now | dateadd("days"; 30))' - TOML-aware dates; also strftime, strptime
+//   - '.a.b?', '.timeout // 30' - optional paths and defaults
+//   - 'if .tls then .port else 80 end', 'try .a catch "none"' - conditionals and errors
+//   - '.servers[] | select(.port > 1024 and .enabled)' - filter with conditions
+//   - '{name: .project.name, hosts: [.servers[].host]}' - build new tables and arrays
+//   - '.min as $m | .servers[] | select(.port > $m)' - variables
+//   - 'def hosts: [.servers[].host]; hosts' - user-defined functions
+//   - 'reduce .items[] as $i (0; . + $i.size)' - reductions; also foreach, limit, range
+//   - '[paths(. == {})]', 'del(.servers[] | select(.port < 1024))' - paths
+//   - '[.. | .password?]', 'walk(f)', 'tostream' - recursive descent and rewrites
+//   - '.build.number += 1', '.name |= ascii_downcase', '.timeout //= 30' - update-assignments
+//
+// Values from scripts are passed as variables rather than spliced into the
+// query: --arg NAME VALUE defines $NAME as a string, --argjson and --argtoml
+// parse JSON and TOML values. Set expressions accept them too:
+//
+//	tmq --arg v "$VERSION" config.toml '.version = $v' -i
+//
+// Several edits are applied to one parsed document with -e, repeatable
+// and taking statements separated by commas or newlines, and with
+// --from-file, which reads them from a script with "#" comments. The file
+// is written once, and not at all if any statement fails:
+//
+//	tmq -e '.version = $v, .build.number += 1' -e 'del(.debug)' --arg v 2.0 config.toml -i
+//	tmq --from-file release.tmq config.toml -i
+//
+// Functions are defined with def, and shared ones are kept in .tmq module
+// files found through -L DIR:
+//
+//	tmq -L lib pyproject.toml 'import "deps" as deps; deps::effective'
+//
+// Integers stay exact 64-bit integers in every output format. With -o json,
+// NaN and the infinities are written as the strings "nan", "inf" and
+// "-inf", and --bigint-strings writes integers beyond 2^53 as strings for
+// JavaScript consumers:
+//
+//	tmq ids.toml -o json --bigint-strings
 //
 // # Examples
 //
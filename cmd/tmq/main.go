@@ -8,8 +8,12 @@
 package main
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/BurntSushi/toml"
@@ -21,13 +25,45 @@ import (
 
 // Global variables for operations
 var (
-	outputFormat converter.OutputFormat = converter.FormatTOML
-	inplace      bool
-	operation    string // "query", "set", or "delete"
-	operationArg string
-	dryRun       bool // Dry-run mode
+	outputFormat  converter.OutputFormat = converter.FormatTOML
+	inplace       bool
+	operation     string // "query", "set", "delete" or "edit"
+	operationArg  string
+	statements    []string               // Edit statements from -e, --expr and --from-file
+	dryRun        bool                   // Dry-run mode
+	bigIntStrings bool                   // Write integers beyond 2^53 as JSON strings
+	variables     map[string]interface{} // Variables from --arg, --argjson and --argtoml
+	libraryPaths  []string               // Query module search path from -L
 )
 
+// flagValues is the number of values that follow each flag taking any
+var flagValues = map[string]int{
+	"-o":             1,
+	"--output":       1,
+	"--compare":      1,
+	"--schema":       1,
+	"--arg":          2,
+	"--argjson":      2,
+	"--argtoml":      2,
+	"-L":             1,
+	"--library-path": 1,
+	"-e":             1,
+	"--expr":         1,
+	"--from-file":    1,
+}
+
+// switchFlags are the flags that take no value
+var switchFlags = map[string]bool{
+	"-h":               true,
+	"--help":           true,
+	"--version":        true,
+	"-i":               true,
+	"--inplace":        true,
+	"--validate":       true,
+	"--dry-run":        true,
+	"--bigint-strings": true,
+}
+
 var (
 	AZ_VERSION string = "1.0.3"
 	AZ_UPDATE  string = "2026-02-22"
@@ -40,6 +76,7 @@ const (
 	ExitUsageError    = 2 // Invalid arguments or usage
 	ExitSecurityError = 3 // Security violation (path traversal, etc.)
 	ExitFileError     = 4 // File operation error
+	ExitUserError     = 5 // Error raised by the query with error()
 )
 
 func main() {
@@ -59,10 +96,15 @@ func main() {
 	var positional []string
 	for i := 0; i < len(args); i++ {
 		arg := args[i]
-		if strings.HasPrefix(arg, "-") {
-			// This is a flag, will be handled in second pass
+		if isFlag(arg) {
+			// This is a flag, will be handled in second pass; its values
+			// are not positional arguments
+			i += flagValues[arg]
 			continue
 		}
+		// Other arguments starting with "-" are kept for now: they are
+		// queries such as "-.offset", or unknown flags dropped once the
+		// variables a query may use are known
 		positional = append(positional, arg)
 	}
 
@@ -99,6 +141,8 @@ func main() {
 			i++ // Skip the file path value
 		case arg == "--dry-run":
 			dryRun = true
+		case arg == "--bigint-strings":
+			bigIntStrings = true
 		case arg == "-o" || arg == "--output":
 			if i+1 >= len(args) {
 				fmt.Fprintf(os.Stderr, "Error: -o flag requires a format argument\n")
@@ -111,6 +155,51 @@ func main() {
 			}
 			outputFormat = format
 			i++ // Skip the format value
+		case arg == "--arg" || arg == "--argjson" || arg == "--argtoml":
+			if i+2 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s flag requires a name and a value\n", arg)
+				os.Exit(2)
+			}
+			value, err := parseArgValue(arg, args[i+2])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %s %s: %v\n", arg, args[i+1], err)
+				os.Exit(2)
+			}
+			if variables == nil {
+				variables = make(map[string]interface{})
+			}
+			variables[args[i+1]] = value
+			i += 2 // Skip the name and value
+		case arg == "-L" || arg == "--library-path":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s flag requires a directory argument\n", arg)
+				os.Exit(2)
+			}
+			libraryPaths = append(libraryPaths, args[i+1])
+			i++ // Skip the directory
+		case arg == "-e" || arg == "--expr":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: %s flag requires an expression argument\n", arg)
+				os.Exit(2)
+			}
+			statements = append(statements, modifier.SplitStatements(args[i+1])...)
+			i++ // Skip the expression
+		case arg == "--from-file":
+			if i+1 >= len(args) {
+				fmt.Fprintf(os.Stderr, "Error: --from-file flag requires a file path argument\n")
+				os.Exit(2)
+			}
+			if err := validateFilePath(args[i+1]); err != nil {
+				formatError("SECURITY_ERROR", fmt.Sprintf("Invalid script path '%s'", args[i+1]), err.Error(), "Use safe file paths without directory traversal")
+				os.Exit(ExitSecurityError)
+			}
+			script, err := os.ReadFile(args[i+1])
+			if err != nil {
+				formatError("FILE_ERROR", fmt.Sprintf("Failed to read script '%s'", args[i+1]), err.Error(), "Check the file exists and is readable")
+				os.Exit(ExitFileError)
+			}
+			statements = append(statements, modifier.SplitStatements(string(script))...)
+			i++ // Skip the file path
 		case strings.HasPrefix(arg, "-o="):
 			formatStr := strings.TrimPrefix(arg, "-o=")
 			format, err := converter.ParseOutputFormat(formatStr)
@@ -122,11 +211,19 @@ func main() {
 		}
 	}
 
-	// Process positional arguments
-	// Check if last argument looks like an operation
-	if len(positional) > 0 {
+	// Unknown flags are ignored
+	positional = slices.DeleteFunc(positional, func(arg string) bool {
+		return strings.HasPrefix(arg, "-") && !compilesAsQuery(arg)
+	})
+
+	// Process positional arguments. With edit statements every positional
+	// argument is a file; otherwise check if the last one is an operation
+	if len(statements) > 0 {
+		operation = "edit"
+		filePaths = positional
+	} else if len(positional) > 0 {
 		lastArg := positional[len(positional)-1]
-		if strings.Contains(lastArg, "=") || strings.HasPrefix(lastArg, "del(") || strings.HasPrefix(lastArg, ".") || strings.Contains(lastArg, "[") || strings.Contains(lastArg, "]") {
+		if isOperation(lastArg) {
 			operationArg = lastArg
 			operation = determineOperation(lastArg)
 			// All preceding args are files
@@ -138,7 +235,7 @@ func main() {
 	}
 
 	// Set defaults
-	if operationArg == "" {
+	if operation == "" {
 		operation = "query"
 	}
 	if len(filePaths) == 0 {
@@ -155,9 +252,59 @@ func main() {
 	}
 }
 
+// isFlag reports whether arg is one of the flags of tmq
+func isFlag(arg string) bool {
+	_, ok := flagValues[arg]
+	return ok || switchFlags[arg] || strings.HasPrefix(arg, "-o=")
+}
+
+// isOperation reports whether the last positional argument is a query, set
+// or delete expression rather than a file path. Besides the arguments that
+// look like operations, any argument naming no existing file that compiles
+// as a query is one, such as "keys" or "10 % 3".
+func isOperation(arg string) bool {
+	if looksLikeOperation(arg) {
+		return true
+	}
+	if _, err := os.Stat(arg); err == nil {
+		return false
+	}
+	return compilesAsQuery(arg)
+}
+
+// compilesAsQuery reports whether arg is a valid query
+func compilesAsQuery(arg string) bool {
+	_, err := query.NewWithOptions(arg, queryOptions())
+	return err == nil
+}
+
+// looksLikeOperation reports whether a positional argument has the syntax
+// of a query, set or delete expression rather than of a file path
+func looksLikeOperation(arg string) bool {
+	if strings.Contains(arg, "=") || strings.HasPrefix(arg, "del(") || strings.HasPrefix(arg, ".") || strings.ContainsAny(arg, "[]|({$;") {
+		return true
+	}
+	// Conditionals and formats need none of the punctuation above, as in
+	// "if .enabled then .port else .fallback end" or "@base64"
+	return strings.HasPrefix(arg, "if ") || strings.HasPrefix(arg, "try ") || strings.HasPrefix(arg, "@")
+}
+
+// parseArgValue converts the value of --arg (a string), --argjson or
+// --argtoml into a query variable
+func parseArgValue(flag, text string) (interface{}, error) {
+	switch flag {
+	case "--argjson":
+		return converter.ParseJSONValue(text)
+	case "--argtoml":
+		return converter.ParseTOMLValue(text)
+	default:
+		return text, nil
+	}
+}
+
 // determineOperation determines the type of operation from the argument
 func determineOperation(arg string) string {
-	if strings.Contains(arg, "=") {
+	if query.AssignmentOperator(arg, variables) != "" {
 		return "set"
 	}
 	if strings.HasPrefix(arg, "del(") && strings.HasSuffix(arg, ")") {
@@ -170,9 +317,24 @@ func determineOperation(arg string) string {
 func outputData(data interface{}, format converter.OutputFormat) {
 	switch format {
 	case converter.FormatTOML:
-		fmt.Printf("%+v\n", data)
+		// Strings print raw so that query results are easy to use in
+		// scripts; TOML has no null, so it prints as in jq
+		switch v := data.(type) {
+		case string:
+			fmt.Println(v)
+			return
+		case nil:
+			fmt.Println("null")
+			return
+		}
+		tomlStr, err := converter.ConvertToTOML(data)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: Failed to convert to TOML: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(tomlStr)
 	case converter.FormatJSON:
-		jsonStr, err := converter.ConvertToJSON(data)
+		jsonStr, err := converter.ConvertToJSONWithOptions(data, converter.JSONOptions{BigIntStrings: bigIntStrings})
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error: Failed to convert to JSON: %v\n", err)
 			os.Exit(1)
@@ -188,16 +350,67 @@ func outputData(data interface{}, format converter.OutputFormat) {
 	}
 }
 
-// writeTOMLFile writes TOML data back to a file
+// outputResults prints the results of a query, each after prefix. When
+// there are several, TOML tables print as inline tables, one per line:
+// TOML documents written one after another would read as a single one
+// with duplicate keys.
+func outputResults(results []interface{}, format converter.OutputFormat, prefix string) {
+	for _, result := range results {
+		fmt.Print(prefix)
+		if _, ok := result.(map[string]interface{}); ok && format == converter.FormatTOML && len(results) > 1 {
+			value, err := converter.ConvertToTOMLValue(result)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(value)
+			continue
+		}
+		outputData(result, format)
+	}
+}
+
+// writeTOMLFile writes TOML data back to a file. The data is encoded in
+// full and written to a temporary file next to filePath, which then
+// replaces it, so a failure never leaves the file half written. The file
+// keeps its permissions, and symlinks keep pointing at it.
 func writeTOMLFile(filePath string, data interface{}) error {
-	file, err := os.Create(filePath)
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(data); err != nil {
+		return err
+	}
+
+	// Replace the target of a symlink rather than the link itself
+	if resolved, err := filepath.EvalSymlinks(filePath); err == nil {
+		filePath = resolved
+	}
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(filePath); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), "."+filepath.Base(filePath)+".tmp*")
 	if err != nil {
 		return err
 	}
-	defer file.Close()
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filePath)
+}
 
-	encoder := toml.NewEncoder(file)
-	return encoder.Encode(data)
+// queryOptions returns the options queries are compiled with
+func queryOptions() query.Options {
+	return query.Options{Variables: variables, SearchPath: libraryPaths}
 }
 
 // isFilePath checks if a string looks like a file path
@@ -385,6 +598,7 @@ func handleSingleFile(filePath string, useStdin bool, validateMode bool, compare
 // handleBulkFiles processes multiple files
 func handleBulkFiles(filePaths []string, validateMode bool) {
 	var hasErrors bool
+	var hasUserErrors bool // Some query raised an error with error()
 
 	// For bulk operations, we need to handle each file
 	for _, filePath := range filePaths {
@@ -426,12 +640,22 @@ func handleBulkFiles(filePaths []string, validateMode bool) {
 
 		// Handle operations for this file
 		if err := handleOperationsBulk(data, dataMap, filePath); err != nil {
+			var userErr *query.UserError
+			if errors.As(err, &userErr) {
+				formatError("USER_ERROR", fmt.Sprintf("Query raised an error on '%s'", filePath), err.Error(), "Skipping file")
+				hasUserErrors = true
+				continue
+			}
 			formatError("OPERATION_ERROR", fmt.Sprintf("Operation failed on '%s'", filePath), err.Error(), "Skipping file")
 			hasErrors = true
 		}
 	}
 
-	// Exit with appropriate code
+	// Exit with appropriate code; errors raised by the query win, as they
+	// do for a single file
+	if hasUserErrors {
+		os.Exit(ExitUserError)
+	}
 	if hasErrors {
 		os.Exit(1) // Some files had errors
 	}
@@ -450,28 +674,35 @@ func handleOperations(data interface{}, dataMap map[string]interface{}, filePath
 		}
 
 		// Execute query
-		q, err := query.New(operationArg)
+		q, err := query.NewWithOptions(operationArg, queryOptions())
 		if err != nil {
 			formatError("QUERY_ERROR", fmt.Sprintf("Invalid query syntax '%s'", operationArg), err.Error(), "Use '.key' or '.nested.key' syntax")
 			os.Exit(ExitUsageError)
 		}
 
-		result, err := q.Execute(data)
+		results, err := q.Execute(data)
+		var userErr *query.UserError
+		if errors.As(err, &userErr) {
+			formatError("USER_ERROR", fmt.Sprintf("Query raised an error for '%s'", operationArg), err.Error(), "Fix the condition the query reports")
+			os.Exit(ExitUserError)
+		}
 		if err != nil {
 			formatError("RUNTIME_ERROR", fmt.Sprintf("Query execution failed for '%s'", operationArg), err.Error(), "Check query path exists in TOML data")
 			os.Exit(ExitParseError)
 		}
 
-		outputData(result, outputFormat)
+		// Iteration can produce any number of results; print one per line
+		outputResults(results, outputFormat, "")
 
 	case "set":
 		if dryRun {
 			// Dry-run mode: show what would be changed
 			fmt.Printf("DRY RUN: Would set %s in %s\n", operationArg, filePath)
 			// Simulate the operation to show the result
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.SetValue(dataMap, operationArg)
 			if err != nil {
+				exitOnUserError(err)
 				fmt.Fprintf(os.Stderr, "Error: Set operation would fail: %v\n", err)
 				os.Exit(1)
 			}
@@ -479,9 +710,10 @@ func handleOperations(data interface{}, dataMap map[string]interface{}, filePath
 			outputData(dataMap, outputFormat)
 		} else if inplace && !useStdin {
 			// Modify file in-place
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.SetValue(dataMap, operationArg)
 			if err != nil {
+				exitOnUserError(err)
 				formatError("OPERATION_ERROR", "Set operation failed", err.Error(), "Check operation syntax and data types")
 				os.Exit(ExitParseError)
 			}
@@ -494,9 +726,10 @@ func handleOperations(data interface{}, dataMap map[string]interface{}, filePath
 			}
 		} else {
 			// Just modify and output
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.SetValue(dataMap, operationArg)
 			if err != nil {
+				exitOnUserError(err)
 				fmt.Fprintf(os.Stderr, "Error: Set operation failed\n")
 				fmt.Fprintf(os.Stderr, "Details: %v\n", err)
 				os.Exit(1)
@@ -509,9 +742,10 @@ func handleOperations(data interface{}, dataMap map[string]interface{}, filePath
 			// Dry-run mode: show what would be deleted
 			fmt.Printf("DRY RUN: Would delete %s from %s\n", operationArg, filePath)
 			// Simulate the operation to show the result
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.DeleteValue(dataMap, operationArg)
 			if err != nil {
+				exitOnUserError(err)
 				fmt.Fprintf(os.Stderr, "Error: Delete operation would fail: %v\n", err)
 				os.Exit(1)
 			}
@@ -519,9 +753,10 @@ func handleOperations(data interface{}, dataMap map[string]interface{}, filePath
 			outputData(dataMap, outputFormat)
 		} else if inplace && !useStdin {
 			// Modify file in-place
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.DeleteValue(dataMap, operationArg)
 			if err != nil {
+				exitOnUserError(err)
 				formatError("OPERATION_ERROR", "Delete operation failed", err.Error(), "Check operation syntax and path exists")
 				os.Exit(ExitParseError)
 			}
@@ -534,9 +769,10 @@ func handleOperations(data interface{}, dataMap map[string]interface{}, filePath
 			}
 		} else {
 			// Just modify and output
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.DeleteValue(dataMap, operationArg)
 			if err != nil {
+				exitOnUserError(err)
 				fmt.Fprintf(os.Stderr, "Error: Delete operation failed\n")
 				fmt.Fprintf(os.Stderr, "Details: %v\n", err)
 				os.Exit(1)
@@ -544,12 +780,58 @@ func handleOperations(data interface{}, dataMap map[string]interface{}, filePath
 			outputData(dataMap, outputFormat)
 		}
 
+	case "edit":
+		m := modifier.NewWithVariables(variables)
+		if dryRun {
+			// Dry-run mode: show the result of every statement
+			fmt.Printf("DRY RUN: Would apply %d statements to %s\n", len(statements), filePath)
+			if err := m.Apply(dataMap, statements); err != nil {
+				exitOnUserError(err)
+				fmt.Fprintf(os.Stderr, "Error: Edit would fail: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println("Result:")
+			outputData(dataMap, outputFormat)
+		} else if inplace && !useStdin {
+			// Apply every statement, then write the file once; nothing is
+			// written if any statement fails
+			if err := m.Apply(dataMap, statements); err != nil {
+				exitOnUserError(err)
+				formatError("OPERATION_ERROR", "Edit failed", err.Error(), "Fix the statement; the file was not changed")
+				os.Exit(ExitParseError)
+			}
+			if err := writeTOMLFile(filePath, dataMap); err != nil {
+				formatError("FILE_ERROR", fmt.Sprintf("Failed to write file '%s'", filePath), err.Error(), "Check file permissions and disk space")
+				os.Exit(ExitFileError)
+			}
+		} else {
+			// Just modify and output
+			if err := m.Apply(dataMap, statements); err != nil {
+				exitOnUserError(err)
+				fmt.Fprintf(os.Stderr, "Error: Edit failed\n")
+				fmt.Fprintf(os.Stderr, "Details: %v\n", err)
+				os.Exit(1)
+			}
+			outputData(dataMap, outputFormat)
+		}
+
 	default:
 		fmt.Fprintf(os.Stderr, "Error: Unknown operation\n")
 		os.Exit(2)
 	}
 }
 
+// exitOnUserError exits with ExitUserError when err was raised with
+// error() by the query of an edit, so that edits report such errors as
+// queries do
+func exitOnUserError(err error) {
+	var userErr *query.UserError
+	if errors.As(err, &userErr) {
+		formatError("USER_ERROR", "Query raised an error", err.Error(), "Fix the condition the query reports")
+		os.Exit(ExitUserError)
+	}
+}
+
 // handleOperationsBulk handles operations for bulk files (limited operations)
 func handleOperationsBulk(data interface{}, dataMap map[string]interface{}, filePath string) error {
 	switch operation {
@@ -562,18 +844,17 @@ func handleOperationsBulk(data interface{}, dataMap map[string]interface{}, file
 		}
 
 		// Execute query
-		q, err := query.New(operationArg)
+		q, err := query.NewWithOptions(operationArg, queryOptions())
 		if err != nil {
 			return fmt.Errorf("invalid query syntax '%s': %v", operationArg, err)
 		}
 
-		result, err := q.Execute(data)
+		results, err := q.Execute(data)
 		if err != nil {
-			return fmt.Errorf("query execution failed for '%s': %v", operationArg, err)
+			return fmt.Errorf("query execution failed for '%s': %w", operationArg, err)
 		}
 
-		fmt.Printf("%s: ", filePath)
-		outputData(result, outputFormat)
+		outputResults(results, outputFormat, filePath+": ")
 		return nil
 
 	case "set":
@@ -581,20 +862,20 @@ func handleOperationsBulk(data interface{}, dataMap map[string]interface{}, file
 			// Dry-run mode for bulk operations
 			fmt.Printf("%s: DRY RUN: Would set %s\n", filePath, operationArg)
 			// Simulate the operation to show the result
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.SetValue(dataMap, operationArg)
 			if err != nil {
-				return fmt.Errorf("set operation would fail: %v", err)
+				return fmt.Errorf("set operation would fail: %w", err)
 			}
 			fmt.Printf("%s: Result: ", filePath)
 			outputData(dataMap, outputFormat)
 			return nil
 		} else if inplace {
 			// Modify file in-place for bulk operations
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.SetValue(dataMap, operationArg)
 			if err != nil {
-				return fmt.Errorf("set operation failed: %v", err)
+				return fmt.Errorf("set operation failed: %w", err)
 			}
 
 			// Write back to file
@@ -613,20 +894,20 @@ func handleOperationsBulk(data interface{}, dataMap map[string]interface{}, file
 			// Dry-run mode for bulk operations
 			fmt.Printf("%s: DRY RUN: Would delete %s\n", filePath, operationArg)
 			// Simulate the operation to show the result
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.DeleteValue(dataMap, operationArg)
 			if err != nil {
-				return fmt.Errorf("delete operation would fail: %v", err)
+				return fmt.Errorf("delete operation would fail: %w", err)
 			}
 			fmt.Printf("%s: Result: ", filePath)
 			outputData(dataMap, outputFormat)
 			return nil
 		} else if inplace {
 			// Modify file in-place for bulk operations
-			m := modifier.New()
+			m := modifier.NewWithVariables(variables)
 			err := m.DeleteValue(dataMap, operationArg)
 			if err != nil {
-				return fmt.Errorf("delete operation failed: %v", err)
+				return fmt.Errorf("delete operation failed: %w", err)
 			}
 
 			// Write back to file
@@ -640,6 +921,31 @@ func handleOperationsBulk(data interface{}, dataMap map[string]interface{}, file
 			return fmt.Errorf("bulk delete operations require -i (in-place) flag")
 		}
 
+	case "edit":
+		m := modifier.NewWithVariables(variables)
+		if dryRun {
+			// Dry-run mode for bulk operations
+			fmt.Printf("%s: DRY RUN: Would apply %d statements\n", filePath, len(statements))
+			if err := m.Apply(dataMap, statements); err != nil {
+				return fmt.Errorf("edit would fail: %w", err)
+			}
+			fmt.Printf("%s: Result: ", filePath)
+			outputData(dataMap, outputFormat)
+			return nil
+		} else if inplace {
+			// Apply every statement, then write the file once
+			if err := m.Apply(dataMap, statements); err != nil {
+				return fmt.Errorf("edit failed: %w", err)
+			}
+			if err := writeTOMLFile(filePath, dataMap); err != nil {
+				return fmt.Errorf("failed to write file '%s': %v", filePath, err)
+			}
+			fmt.Printf("%s: updated\n", filePath)
+			return nil
+		} else {
+			return fmt.Errorf("bulk edits require -i (in-place) flag")
+		}
+
 	default:
 		return fmt.Errorf("operation '%s' not supported for bulk operations", operation)
 	}
@@ -651,11 +957,21 @@ func printUsage() {
 	fmt.Fprintf(os.Stderr, "       %s < file.toml | %s [options] [operation]\n", os.Args[0], os.Args[0])
 	fmt.Fprintf(os.Stderr, "\nOptions:\n")
 	fmt.Fprintf(os.Stderr, "  -o, --output FORMAT    Output format: toml, json, yaml (default: toml)\n")
+	fmt.Fprintf(os.Stderr, "      --bigint-strings   With -o json, write integers beyond 2^53 as strings\n")
 	fmt.Fprintf(os.Stderr, "  -i, --inplace          Modify file in-place (requires file argument)\n")
 	fmt.Fprintf(os.Stderr, "      --dry-run          Preview changes without applying them\n")
 	fmt.Fprintf(os.Stderr, "      --validate         Validate TOML syntax and structure\n")
 	fmt.Fprintf(os.Stderr, "      --compare FILE     Compare with another TOML file\n")
 	fmt.Fprintf(os.Stderr, "      --schema FILE      Validate against schema file (future)\n")
+	fmt.Fprintf(os.Stderr, "      --arg NAME VALUE   Define $NAME as the string VALUE\n")
+	fmt.Fprintf(os.Stderr, "      --argjson NAME JSON\n")
+	fmt.Fprintf(os.Stderr, "                         Define $NAME as a JSON value\n")
+	fmt.Fprintf(os.Stderr, "      --argtoml NAME TOML\n")
+	fmt.Fprintf(os.Stderr, "                         Define $NAME as a TOML value, e.g. 2024-05-01\n")
+	fmt.Fprintf(os.Stderr, "  -L, --library-path DIR Search DIR for query modules (repeatable)\n")
+	fmt.Fprintf(os.Stderr, "  -e, --expr EDITS       Apply set, update and delete statements separated by\n")
+	fmt.Fprintf(os.Stderr, "                         commas or newlines (repeatable); every argument is a file\n")
+	fmt.Fprintf(os.Stderr, "      --from-file FILE   Apply the statements in FILE, in order with -e\n")
 	fmt.Fprintf(os.Stderr, "  -h, --help             Show this help message\n")
 	fmt.Fprintf(os.Stderr, "      --version          Show version information\n")
 	fmt.Fprintf(os.Stderr, "\nArguments:\n")
@@ -667,12 +983,22 @@ func printUsage() {
 	fmt.Fprintf(os.Stderr, "  2    Usage error or invalid arguments\n")
 	fmt.Fprintf(os.Stderr, "  3    Security error (unsafe file path)\n")
 	fmt.Fprintf(os.Stderr, "  4    File operation error\n")
+	fmt.Fprintf(os.Stderr, "  5    Error raised by the query with error()\n")
 	fmt.Fprintf(os.Stderr, "\nExamples:\n")
 	fmt.Fprintf(os.Stderr, "  %s config.toml '.project.version'\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s config.toml '.servers[].host'\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s config.toml '.servers[] | select(.port > 1024) | .host'\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s pyproject.toml '{name: .project.name, version: .project.version}'\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  cat config.toml | %s '.database.host'\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s config.toml -o json\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s config.toml '.version = \"2.0\"' -i\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s config.toml '.version = \"2.0\"' --dry-run -i\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s config.toml '.build.number += 1' -i\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -e '.version = \"2.0\"' -e 'del(.debug)' config.toml -i\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --from-file release.tmq config.toml -i\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --arg v \"$VERSION\" config.toml '.version = $v' -i\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s --argjson min 1024 config.toml '.servers[] | select(.port >= $min)'\n", os.Args[0])
+	fmt.Fprintf(os.Stderr, "  %s -L ./lib config.toml 'import \"deps\" as deps; deps::effective'\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s --validate config.toml\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s --compare config1.toml config2.toml\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "  %s config.toml 'del(.old_field)' -i\n", os.Args[0])
@@ -1,8 +1,16 @@
 package main
 
 import (
+	"errors"
+	"io"
 	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+
+	"github.com/azolfagharj/tmq/internal/converter"
 )
 
 func TestValidationMode(t *testing.T) {
@@ -105,6 +113,159 @@ func TestIsFilePath(t *testing.T) {
 	}
 }
 
+func TestDetermineOperation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{".key", "query"},
+		{".key = 1", "set"},
+		{`.key = "a==b"`, "set"},
+		{"del(.key)", "delete"},
+		{".servers[] | select(.port == 80)", "query"},
+		{"select(.a != 1 and .b <= 2 and .c >= 3)", "query"},
+		{"{name: .project.name, ok: (.a == 1)}", "query"},
+		{".version = $v", "set"},
+		{". as $root | .a", "query"},
+		{"def hosts: [.servers[].host]; hosts", "query"},
+		{`import "deps" as deps; deps::effective`, "query"},
+		{`if .port < 1024 then error("privileged port") else . end`, "query"},
+		{`if .a == 1 then .b else .c end`, "query"},
+		{".build.number += 1", "set"},
+		{".name |= ascii_downcase", "set"},
+		{".timeout //= 30", "set"},
+		{`.name == "a = b"`, "query"},
+		{`select(.op == "+=")`, "query"},
+		{".a | .b |= 1", "query"},
+		{".nums[0] += 1 | .nums", "query"},
+		{".a |= 1 | .a", "query"},
+		{`title = "y"`, "set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := determineOperation(tt.input); result != tt.expected {
+				t.Errorf("determineOperation(%q) = %q; want %q", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestLooksLikeOperation(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"config.toml", false},
+		{"/path/to/config.toml", false},
+		{".key", true},
+		{"del(.key)", true},
+		{"if .tls then .port else 80 end", true},
+		{"try .a catch .b", true},
+		{"@base64", true},
+		{"\"\\(.host):\\(.port)\"", true},
+		{"iffy.toml", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := looksLikeOperation(tt.input); result != tt.expected {
+				t.Errorf("looksLikeOperation(%q) = %v; want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsOperation(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "length")
+	if err := os.WriteFile(existing, []byte("a = 1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{".key", true},
+		{"keys", true},
+		{"length", true},
+		{"to_entries", true},
+		{"ascii_downcase", true},
+		{"10 % 3", true},
+		{"-.offset", true},
+		{existing, false},
+		{filepath.Join(dir, "missing.toml"), false},
+		{"config", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := isOperation(tt.input); result != tt.expected {
+				t.Errorf("isOperation(%q) = %v; want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestIsFlag(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"-i", true},
+		{"--from-file", true},
+		{"-o=json", true},
+		{"-.offset", false},
+		{"-1", false},
+		{"--unknown", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if result := isFlag(tt.input); result != tt.expected {
+				t.Errorf("isFlag(%q) = %v; want %v", tt.input, result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseArgValue(t *testing.T) {
+	tests := []struct {
+		flag     string
+		text     string
+		expected interface{}
+		wantErr  bool
+	}{
+		{flag: "--arg", text: `1.0" = "x`, expected: `1.0" = "x`},
+		{flag: "--arg", text: "42", expected: "42"},
+		{flag: "--argjson", text: "42", expected: int64(42)},
+		{flag: "--argjson", text: `{"a": [1, 2.5]}`, expected: map[string]interface{}{"a": []interface{}{int64(1), 2.5}}},
+		{flag: "--argjson", text: "{", wantErr: true},
+		{flag: "--argtoml", text: `"text"`, expected: "text"},
+		{flag: "--argtoml", text: "[1, 2]", expected: []interface{}{int64(1), int64(2)}},
+		{flag: "--argtoml", text: "text", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.flag+" "+tt.text, func(t *testing.T) {
+			result, err := parseArgValue(tt.flag, tt.text)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error, got %#v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("parseArgValue(%q, %q) = %#v; want %#v", tt.flag, tt.text, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestValidateFilePath(t *testing.T) {
 	// Create a test file
 	tmpFile, err := os.CreateTemp("", "validate_test_*.toml")
@@ -212,4 +373,196 @@ func TestExitCodes(t *testing.T) {
 	if ExitFileError != 4 {
 		t.Errorf("ExitFileError should be 4, got %d", ExitFileError)
 	}
+	if ExitUserError != 5 {
+		t.Errorf("ExitUserError should be 5, got %d", ExitUserError)
+	}
+}
+
+func TestWriteTOMLFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml")
+	if err := os.WriteFile(path, []byte("old = true\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := writeTOMLFile(path, map[string]interface{}{"version": "2.0"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "version = \"2.0\"\n" {
+		t.Errorf("unexpected content %q", content)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0600 {
+		t.Errorf("expected mode 0600, got %v", info.Mode().Perm())
+	}
+
+	// The temporary file is gone, and a value TOML cannot hold leaves the
+	// file alone
+	if err := writeTOMLFile(path, map[string]interface{}{"bad": make(chan int)}); err == nil {
+		t.Errorf("expected an error for a value TOML cannot hold")
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only config.toml in %s, got %d entries", dir, len(entries))
+	}
+	if after, _ := os.ReadFile(path); string(after) != string(content) {
+		t.Errorf("file changed to %q", after)
+	}
+}
+
+// captureStdout returns what f prints to stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	f()
+	w.Close()
+	out, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestOutputResults(t *testing.T) {
+	servers := []interface{}{
+		map[string]interface{}{"host": "a", "port": int64(80)},
+		map[string]interface{}{"host": "b", "port": int64(8080)},
+	}
+
+	tests := []struct {
+		name     string
+		results  []interface{}
+		format   converter.OutputFormat
+		prefix   string
+		expected string
+	}{
+		{
+			name:     "several tables",
+			results:  servers,
+			format:   converter.FormatTOML,
+			expected: "{ host = \"a\", port = 80 }\n{ host = \"b\", port = 8080 }\n",
+		},
+		{
+			name:     "several tables with a file prefix",
+			results:  servers,
+			format:   converter.FormatTOML,
+			prefix:   "a.toml: ",
+			expected: "a.toml: { host = \"a\", port = 80 }\na.toml: { host = \"b\", port = 8080 }\n",
+		},
+		{
+			name:     "one table is a document",
+			results:  servers[:1],
+			format:   converter.FormatTOML,
+			expected: "host = \"a\"\nport = 80\n",
+		},
+		{
+			name:     "mixed values",
+			results:  []interface{}{"x", int64(1), servers[0]},
+			format:   converter.FormatTOML,
+			expected: "x\n1\n{ host = \"a\", port = 80 }\n",
+		},
+		{
+			name:     "json",
+			results:  []interface{}{int64(1), []interface{}{}},
+			format:   converter.FormatJSON,
+			expected: "1\n[]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t, func() { outputResults(tt.results, tt.format, tt.prefix) })
+			if out != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, out)
+			}
+		})
+	}
+}
+
+// runMain runs tmq with args in a child process, which the test binary
+// becomes through TestRunMain, and returns its stdout and exit code
+func runMain(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRunMain$")
+	cmd.Env = append(os.Environ(), "TMQ_TEST_ARGS="+strings.Join(args, "\n"))
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(out), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), 0
+}
+
+// TestRunMain runs main with the arguments runMain passes
+func TestRunMain(t *testing.T) {
+	args, ok := os.LookupEnv("TMQ_TEST_ARGS")
+	if !ok {
+		t.Skip("runs only as the child process of runMain")
+	}
+	os.Args = append([]string{"tmq"}, strings.Split(args, "\n")...)
+	main()
+	os.Exit(ExitSuccess)
+}
+
+func TestBulkUserError(t *testing.T) {
+	dir := t.TempDir()
+	low := filepath.Join(dir, "low.toml")
+	high := filepath.Join(dir, "high.toml")
+	if err := os.WriteFile(low, []byte("port = 80\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(high, []byte("port = 8080\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	check := `if .port < 1024 then error("privileged port") else .port end`
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+		code     int
+	}{
+		{name: "query", args: []string{low, high, check}, expected: high + ": 8080\n", code: ExitUserError},
+		{name: "update", args: []string{low, high, `.port |= if . < 1024 then error("privileged port") else . + 1 end`, "-i"}, expected: high + ": updated\n", code: ExitUserError},
+		// The update above was written to high.toml
+		{name: "no error", args: []string{high, high, ".port"}, expected: high + ": 8081\n" + high + ": 8081\n", code: ExitSuccess},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, code := runMain(t, tt.args...)
+			if code != tt.code {
+				t.Errorf("expected exit code %d, got %d", tt.code, code)
+			}
+			if out != tt.expected {
+				t.Errorf("expected output %q, got %q", tt.expected, out)
+			}
+		})
+	}
+
+	// The file whose query raised the error is left alone
+	if content, _ := os.ReadFile(low); string(content) != "port = 80\n" {
+		t.Errorf("low.toml changed to %q", content)
+	}
 }
@@ -1,14 +1,16 @@
 // Package converter provides format conversion functionality for tmq.
 //
-// This package handles converting TOML data to JSON and YAML formats,
+// This package handles converting TOML data to TOML, JSON and YAML formats,
 // and vice versa for input formats.
 package converter
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"strings"
 
+	"github.com/BurntSushi/toml"
 	yaml "gopkg.in/yaml.v3"
 )
 
@@ -49,31 +51,168 @@ func ParseOutputFormat(s string) (OutputFormat, error) {
 	}
 }
 
-// ConvertToJSON converts TOML data to JSON string
+// ConvertToJSON converts TOML data to JSON string with the default
+// JSONOptions
 func ConvertToJSON(data interface{}) (string, error) {
-	jsonBytes, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return "", fmt.Errorf("failed to convert to JSON: %w", err)
-	}
-	return string(jsonBytes), nil
+	return ConvertToJSONWithOptions(data, JSONOptions{})
 }
 
-// ConvertToYAML converts TOML data to YAML string
+// ConvertToYAML converts TOML data to YAML string. NaN and the infinities
+// are written as YAML's own .nan, .inf and -.inf. Dates and datetimes are
+// timestamps written as in TOML, keeping local ones local; local times are
+// strings.
 func ConvertToYAML(data interface{}) (string, error) {
-	yamlBytes, err := yaml.Marshal(data)
+	yamlBytes, err := yaml.Marshal(yamlValue(data))
 	if err != nil {
 		return "", fmt.Errorf("failed to convert to YAML: %w", err)
 	}
 	return string(yamlBytes), nil
 }
 
+// ConvertToTOML converts data to a TOML string. Tables become a TOML
+// document; any other value, such as the array or string a query returns,
+// is written as a single TOML value.
+func ConvertToTOML(data interface{}) (string, error) {
+	table, ok := data.(map[string]interface{})
+	if !ok {
+		value, err := formatTOMLValue(data)
+		if err != nil {
+			return "", fmt.Errorf("failed to convert to TOML: %w", err)
+		}
+		return value, nil
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(table); err != nil {
+		return "", fmt.Errorf("failed to convert to TOML: %w", err)
+	}
+	return strings.TrimSuffix(buf.String(), "\n"), nil
+}
+
+// ConvertToTOMLValue converts data to a single-line TOML value, writing
+// tables as inline tables rather than as a document. It suits results
+// printed one per line.
+func ConvertToTOMLValue(data interface{}) (string, error) {
+	value, err := formatTOMLValue(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert to TOML: %w", err)
+	}
+	return value, nil
+}
+
+// ParseJSONValue parses a JSON value into the types the TOML decoder
+// produces: integers become int64, other numbers float64 and objects
+// map[string]interface{}
+func ParseJSONValue(s string) (interface{}, error) {
+	dec := json.NewDecoder(strings.NewReader(s))
+	dec.UseNumber()
+	var value interface{}
+	if err := dec.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid JSON value: %w", err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("invalid JSON value: unexpected data after the value")
+	}
+	return fromJSONNumbers(value)
+}
+
+// fromJSONNumbers replaces the json.Number values inside v
+func fromJSONNumbers(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case json.Number:
+		if i, err := val.Int64(); err == nil {
+			return i, nil
+		}
+		f, err := val.Float64()
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON number %s: %w", val, err)
+		}
+		return f, nil
+	case []interface{}:
+		for i, item := range val {
+			converted, err := fromJSONNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = converted
+		}
+	case map[string]interface{}:
+		for k, item := range val {
+			converted, err := fromJSONNumbers(item)
+			if err != nil {
+				return nil, err
+			}
+			val[k] = converted
+		}
+	}
+	return v, nil
+}
+
+// ParseTOMLValue parses a single TOML value, such as 42, "text",
+// 2024-05-01 or { a = 1 }, as it would appear on the right of a key
+func ParseTOMLValue(s string) (interface{}, error) {
+	var doc map[string]interface{}
+	if _, err := toml.Decode("value = "+s, &doc); err != nil {
+		return nil, fmt.Errorf("invalid TOML value: %w", err)
+	}
+	if len(doc) != 1 {
+		return nil, fmt.Errorf("invalid TOML value: unexpected data after the value")
+	}
+	return doc["value"], nil
+}
+
+// ParseTOMLDocument parses a TOML document into a table
+func ParseTOMLDocument(s string) (map[string]interface{}, error) {
+	doc := map[string]interface{}{}
+	if _, err := toml.Decode(s, &doc); err != nil {
+		return nil, fmt.Errorf("invalid TOML document: %w", err)
+	}
+	return doc, nil
+}
+
+// ParseYAMLValue parses the first YAML document in s into the types the
+// TOML decoder produces. Mappings become map[string]interface{}, with
+// keys that are not strings written out, and timestamps become offset
+// datetimes.
+func ParseYAMLValue(s string) (interface{}, error) {
+	var value interface{}
+	if err := yaml.Unmarshal([]byte(s), &value); err != nil {
+		return nil, fmt.Errorf("invalid YAML value: %w", err)
+	}
+	return fromYAMLTypes(value), nil
+}
+
+// fromYAMLTypes replaces the integers and generic mappings inside v
+func fromYAMLTypes(v interface{}) interface{} {
+	switch val := v.(type) {
+	case int:
+		return int64(val)
+	case uint64:
+		// Only integers beyond the int64 range decode as uint64
+		return float64(val)
+	case []interface{}:
+		for i, item := range val {
+			val[i] = fromYAMLTypes(item)
+		}
+	case map[string]interface{}:
+		for k, item := range val {
+			val[k] = fromYAMLTypes(item)
+		}
+	case map[interface{}]interface{}:
+		table := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			table[fmt.Sprint(k)] = fromYAMLTypes(item)
+		}
+		return table
+	}
+	return v
+}
+
 // ConvertData converts TOML data to the specified output format
 func ConvertData(data interface{}, format OutputFormat) (string, error) {
 	switch format {
 	case FormatTOML:
-		// For TOML output, we could implement TOML encoding here
-		// For now, return an error as it's not implemented yet
-		return "", fmt.Errorf("TOML output format not yet implemented")
+		return ConvertToTOML(data)
 	case FormatJSON:
 		return ConvertToJSON(data)
 	case FormatYAML:
@@ -0,0 +1,105 @@
+package converter
+
+import (
+	"math"
+	"testing"
+)
+
+func TestConvertToJSONWithOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     interface{}
+		opts     JSONOptions
+		expected string
+	}{
+		// non-finite floats
+		{name: "nan", data: math.NaN(), expected: `"nan"`},
+		{name: "infinities", data: []interface{}{math.Inf(1), math.Inf(-1)}, expected: "[\n  \"inf\",\n  \"-inf\"\n]"},
+		{name: "nested in a table", data: map[string]interface{}{"limit": math.Inf(1)}, expected: "{\n  \"limit\": \"inf\"\n}"},
+		{name: "in an array of tables", data: []map[string]interface{}{{"v": math.NaN()}}, expected: "[\n  {\n    \"v\": \"nan\"\n  }\n]"},
+		{name: "finite float", data: 0.5, expected: "0.5"},
+
+		// integers
+		{name: "big integer stays a number", data: int64(9007199254740993), expected: "9007199254740993"},
+		{name: "big integer as a string", data: int64(9007199254740993), opts: JSONOptions{BigIntStrings: true}, expected: `"9007199254740993"`},
+		{name: "negative big integer as a string", data: int64(math.MinInt64), opts: JSONOptions{BigIntStrings: true}, expected: `"-9223372036854775808"`},
+		{name: "largest safe integer", data: int64(9007199254740991), opts: JSONOptions{BigIntStrings: true}, expected: "9007199254740991"},
+		{name: "big integers in a table", data: map[string]interface{}{"id": int64(math.MaxInt64), "n": int64(1)}, opts: JSONOptions{BigIntStrings: true}, expected: "{\n  \"id\": \"9223372036854775807\",\n  \"n\": 1\n}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ConvertToJSONWithOptions(tt.data, tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestJSONValue_LeavesInputAlone(t *testing.T) {
+	data := map[string]interface{}{"v": math.Inf(1), "ids": []interface{}{int64(math.MaxInt64)}}
+	JSONValue(data, JSONOptions{BigIntStrings: true})
+	if !math.IsInf(data["v"].(float64), 1) || data["ids"].([]interface{})[0] != int64(math.MaxInt64) {
+		t.Errorf("expected the input to be unchanged, got %#v", data)
+	}
+}
+
+func TestConvertToYAML_NonFinite(t *testing.T) {
+	data := map[string]interface{}{"a": math.NaN(), "b": math.Inf(1), "c": math.Inf(-1)}
+	result, err := ConvertToYAML(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "a: .nan\nb: .inf\nc: -.inf\n"
+	if result != expected {
+		t.Fatalf("expected %q, got %q", expected, result)
+	}
+
+	// YAML keeps the floats, so they read back unchanged
+	parsed, err := ParseYAMLValue(result)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	table := parsed.(map[string]interface{})
+	if !math.IsNaN(table["a"].(float64)) || !math.IsInf(table["b"].(float64), 1) || !math.IsInf(table["c"].(float64), -1) {
+		t.Errorf("expected nan, inf and -inf, got %#v", table)
+	}
+}
+
+func TestConvert_Datetimes(t *testing.T) {
+	data, err := ParseTOMLDocument("od = 2026-11-01T07:32:00.5-05:00\nldt = 2026-11-01T07:32:00\nld = 2026-11-01\nlt = 07:32:00\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	json, err := ConvertToJSON(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "{\n  \"ld\": \"2026-11-01\",\n  \"ldt\": \"2026-11-01T07:32:00\",\n  \"lt\": \"07:32:00\",\n  \"od\": \"2026-11-01T07:32:00.5-05:00\"\n}"
+	if json != expected {
+		t.Errorf("expected JSON %s, got %s", expected, json)
+	}
+
+	yaml, err := ConvertToYAML(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected = "ld: 2026-11-01\nldt: !!timestamp 2026-11-01T07:32:00\nlt: \"07:32:00\"\nod: 2026-11-01T07:32:00.5-05:00\n"
+	if yaml != expected {
+		t.Errorf("expected YAML %q, got %q", expected, yaml)
+	}
+
+	// In arrays of tables too
+	json, err = ConvertToJSON([]map[string]interface{}{{"d": data["ld"]}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected = "[\n  {\n    \"d\": \"2026-11-01\"\n  }\n]"; json != expected {
+		t.Errorf("expected JSON %s, got %s", expected, json)
+	}
+}
@@ -2,8 +2,12 @@ package converter
 
 import (
 	"encoding/json"
+	"math"
 	"strings"
 	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
 )
 
 // Test constants
@@ -273,20 +277,113 @@ func TestConvertToYAML(t *testing.T) {
 	})
 }
 
+func TestConvertToTOML(t *testing.T) {
+	t.Run("complex data", func(t *testing.T) {
+		data := createComplexTestData()
+		result, err := ConvertToTOML(data)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		assertFormatResult(t, result,
+			[]string{`title = "Test Document"`, "[config.database]", "port = 5432", "[[servers]]", `ip = "192.168.1.1"`},
+			[]string{"map["})
+
+		var decoded map[string]interface{}
+		if _, err := toml.Decode(result, &decoded); err != nil {
+			t.Errorf("generated TOML is invalid: %v\nTOML: %s", err, result)
+		}
+	})
+
+	tests := []struct {
+		name     string
+		data     interface{}
+		expected string
+		errMsg   string
+	}{
+		{name: "string", data: "a \"b\"\n", expected: `"a \"b\"\n"`},
+		{name: "control character", data: "\x01", expected: `"\u0001"`},
+		{name: "integer", data: int64(42), expected: "42"},
+		{name: "whole float", data: 2.0, expected: "2.0"},
+		{name: "float with exponent", data: 1e300, expected: "1e+300"},
+		{name: "infinity", data: math.Inf(-1), expected: "-inf"},
+		{name: "boolean", data: true, expected: "true"},
+		{name: "offset datetime", data: time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC), expected: "2024-05-01T12:30:00Z"},
+		{name: "local date", data: time.Date(2024, 5, 1, 0, 0, 0, 0, time.FixedZone("date-local", 0)), expected: "2024-05-01"},
+		{name: "array", data: []interface{}{"a", int64(1)}, expected: `["a", 1]`},
+		{name: "empty array", data: []interface{}{}, expected: "[]"},
+		{
+			name:     "array of tables",
+			data:     []map[string]interface{}{{"name": "web", "tls port": int64(443)}, {}},
+			expected: `[{ name = "web", "tls port" = 443 }, {}]`,
+		},
+		{name: "null", data: nil, errMsg: "null has no TOML representation"},
+		{name: "null in array", data: []interface{}{nil}, errMsg: "null has no TOML representation"},
+		{name: "unsupported type", data: struct{}{}, errMsg: "cannot encode struct {} as TOML"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ConvertToTOML(tt.data)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestConvertToTOMLValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     interface{}
+		expected string
+		errMsg   string
+	}{
+		{name: "table", data: map[string]interface{}{"host": "a", "port": int64(80)}, expected: `{ host = "a", port = 80 }`},
+		{name: "nested table", data: map[string]interface{}{"db": map[string]interface{}{"port": int64(1)}}, expected: `{ db = { port = 1 } }`},
+		{name: "empty table", data: map[string]interface{}{}, expected: "{}"},
+		{name: "string", data: "a", expected: `"a"`},
+		{name: "null", data: nil, errMsg: "null has no TOML representation"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ConvertToTOMLValue(tt.data)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, result)
+			}
+		})
+	}
+}
+
 func TestConvertData(t *testing.T) {
 	data := createSimpleTestData()
 
 	t.Run("TOML format", func(t *testing.T) {
 		result, err := ConvertData(data, FormatTOML)
-		if err == nil {
-			t.Error("expected error for unimplemented TOML output, got nil")
-		}
-		if !strings.Contains(err.Error(), "TOML output format not yet implemented") {
-			t.Errorf("expected implementation error, got %q", err.Error())
-		}
-		if result != "" {
-			t.Errorf("expected empty result for error case, got %q", result)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
 		}
+
+		assertFormatResult(t, result, []string{`key = "value"`}, []string{})
 	})
 
 	t.Run("JSON format", func(t *testing.T) {
@@ -0,0 +1,145 @@
+package converter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseJSONValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "integer", input: "42", expected: int64(42)},
+		{name: "float", input: "2.5", expected: 2.5},
+		{name: "exponent", input: "1e3", expected: 1000.0},
+		{name: "string", input: `"a \"b\""`, expected: `a "b"`},
+		{name: "null", input: "null", expected: nil},
+		{
+			name:     "nested numbers",
+			input:    `{"ports": [80, 443], "ratio": 0.5}`,
+			expected: map[string]interface{}{"ports": []interface{}{int64(80), int64(443)}, "ratio": 0.5},
+		},
+		{name: "invalid", input: "{", errMsg: "invalid JSON value"},
+		{name: "trailing data", input: "1 2", errMsg: "unexpected data after the value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseJSONValue(tt.input)
+			assertParsedValue(t, result, err, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestParseTOMLValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "integer", input: "42", expected: int64(42)},
+		{name: "string", input: `"text"`, expected: "text"},
+		{name: "literal string", input: `'C:\path'`, expected: `C:\path`},
+		{name: "array", input: "[1, 2]", expected: []interface{}{int64(1), int64(2)}},
+		{name: "inline table", input: "{ a = true }", expected: map[string]interface{}{"a": true}},
+		{name: "bare word", input: "text", errMsg: "invalid TOML value"},
+		{name: "extra key", input: "1\nother = 2", errMsg: "unexpected data after the value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseTOMLValue(tt.input)
+			assertParsedValue(t, result, err, tt.expected, tt.errMsg)
+		})
+	}
+
+	t.Run("local date", func(t *testing.T) {
+		result, err := ParseTOMLValue("2024-05-01")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		date, ok := result.(time.Time)
+		if !ok || date.Format("2006-01-02") != "2024-05-01" {
+			t.Errorf("expected the date 2024-05-01, got %#v", result)
+		}
+		if formatted, _ := ConvertToTOML(result); formatted != "2024-05-01" {
+			t.Errorf("expected the local date to round-trip, got %s", formatted)
+		}
+	})
+}
+
+func TestParseTOMLDocument(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "empty", input: "", expected: map[string]interface{}{}},
+		{name: "keys", input: "a = 1\nb = 'x'", expected: map[string]interface{}{"a": int64(1), "b": "x"}},
+		{name: "tables", input: "[server]\nport = 80", expected: map[string]interface{}{"server": map[string]interface{}{"port": int64(80)}}},
+		{name: "bare value", input: "42", errMsg: "invalid TOML document"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseTOMLDocument(tt.input)
+			assertParsedValue(t, result, err, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestParseYAMLValue(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "integer", input: "42", expected: int64(42)},
+		{name: "float", input: "2.5", expected: 2.5},
+		{name: "string", input: "hello", expected: "hello"},
+		{name: "empty", input: "", expected: nil},
+		{
+			name:     "mapping",
+			input:    "ports:\n  - 80\n  - 443\ntls: true",
+			expected: map[string]interface{}{"ports": []interface{}{int64(80), int64(443)}, "tls": true},
+		},
+		{name: "keys that are not strings", input: "1: one\ntrue: yes", expected: map[string]interface{}{"1": "one", "true": "yes"}},
+		{name: "nested mapping", input: "a:\n  2: x", expected: map[string]interface{}{"a": map[string]interface{}{"2": "x"}}},
+		{name: "huge integer", input: "18446744073709551615", expected: 18446744073709551615.0},
+		{name: "first document", input: "1\n---\n2", expected: int64(1)},
+		{name: "timestamp", input: "2024-05-01T10:00:00Z", expected: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{name: "invalid", input: "a: [", errMsg: "invalid YAML value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ParseYAMLValue(tt.input)
+			assertParsedValue(t, result, err, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func assertParsedValue(t *testing.T, result interface{}, err error, expected interface{}, errMsg string) {
+	t.Helper()
+
+	if errMsg != "" {
+		if err == nil || !strings.Contains(err.Error(), errMsg) {
+			t.Fatalf("expected error containing %q, got %v", errMsg, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %#v, got %#v", expected, result)
+	}
+}
@@ -19,14 +19,43 @@
 //
 //	yamlStr, err := converter.ConvertToYAML(tomlData)
 //
+// Convert data to TOML; tables become a document and other values, such
+// as query results, a single TOML value:
+//
+//	tomlStr, err := converter.ConvertToTOML(data)
+//
 // Convert with specified format:
 //
 //	output, err := converter.ConvertData(data, converter.FormatJSON)
 //
+// Parse embedded documents into the types the TOML decoder produces:
+//
+//	value, err := converter.ParseJSONValue(jsonStr)
+//	table, err := converter.ParseTOMLDocument(tomlStr)
+//	value, err := converter.ParseYAMLValue(yamlStr)
+//
 // # Output Formats
 //
 // Use the -o flag with tmq to specify output format:
 //
 //	tmq config.toml -o json
 //	tmq config.toml -o yaml
+//
+// # Numbers
+//
+// Integers stay exact int64 values and floats stay float64 in every
+// format. JSON has no NaN or infinity, so ConvertToJSON writes them as the
+// strings "nan", "inf" and "-inf"; YAML writes its own .nan, .inf and
+// -.inf. Integers beyond ±(2^53 - 1), which JavaScript rounds, can be
+// written as strings instead:
+//
+//	jsonStr, err := converter.ConvertToJSONWithOptions(data, converter.JSONOptions{BigIntStrings: true})
+//
+// # Datetimes
+//
+// Datetimes are written as in TOML, so the four TOML kinds stay apart:
+// JSON gets strings such as "2026-11-01T07:32:00-05:00", "2026-11-01T07:32:00",
+// "2026-11-01" and "07:32:00", and YAML gets timestamps, with local times,
+// which YAML has no type for, as strings. Local values never gain an
+// offset.
 package converter
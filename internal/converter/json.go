@@ -0,0 +1,85 @@
+package converter
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+// maxSafeInteger is the largest integer a JavaScript number, an IEEE 754
+// double, holds exactly: 2^53 - 1
+const maxSafeInteger = 1<<53 - 1
+
+// JSONOptions controls how values JSON consumers cannot read exactly are
+// written
+type JSONOptions struct {
+	// BigIntStrings writes integers beyond ±(2^53 - 1), which JavaScript
+	// rounds, as decimal strings
+	BigIntStrings bool
+}
+
+// ConvertToJSONWithOptions converts TOML data to a JSON string.
+//
+// JSON has no NaN or infinity, so those floats are written as the strings
+// "nan", "inf" and "-inf", as TOML spells them. JSON has no datetimes
+// either, so they are strings written as in TOML: local dates, times and
+// datetimes carry no offset, unlike offset datetimes.
+func ConvertToJSONWithOptions(data interface{}, opts JSONOptions) (string, error) {
+	jsonBytes, err := json.MarshalIndent(JSONValue(data, opts), "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("failed to convert to JSON: %w", err)
+	}
+	return string(jsonBytes), nil
+}
+
+// JSONValue returns a copy of v that encoding/json can marshal, with
+// non-finite floats and datetimes replaced by their TOML spelling and, if
+// opts ask for it, big integers by strings. Values that need no change are shared with v.
+func JSONValue(v interface{}, opts JSONOptions) interface{} {
+	switch val := v.(type) {
+	case float64:
+		if s, ok := nonFiniteName(val); ok {
+			return s
+		}
+	case time.Time:
+		return FormatTOMLDatetime(val)
+	case int64:
+		if opts.BigIntStrings && (val > maxSafeInteger || val < -maxSafeInteger) {
+			return strconv.FormatInt(val, 10)
+		}
+	case []interface{}:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = JSONValue(item, opts)
+		}
+		return items
+	case []map[string]interface{}:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = JSONValue(item, opts)
+		}
+		return items
+	case map[string]interface{}:
+		table := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			table[k] = JSONValue(item, opts)
+		}
+		return table
+	}
+	return v
+}
+
+// nonFiniteName returns the TOML name of NaN and the infinities
+func nonFiniteName(f float64) (string, bool) {
+	switch {
+	case math.IsNaN(f):
+		return "nan", true
+	case math.IsInf(f, 1):
+		return "inf", true
+	case math.IsInf(f, -1):
+		return "-inf", true
+	}
+	return "", false
+}
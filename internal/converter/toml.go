@@ -0,0 +1,192 @@
+package converter
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// formatTOMLValue writes v as an inline TOML value, with tables as inline
+// tables and their keys sorted
+func formatTOMLValue(v interface{}) (string, error) {
+	switch val := v.(type) {
+	case nil:
+		return "", fmt.Errorf("null has no TOML representation")
+	case string:
+		return QuoteTOMLString(val), nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case int64:
+		return strconv.FormatInt(val, 10), nil
+	case int:
+		return strconv.Itoa(val), nil
+	case float64:
+		return formatTOMLFloat(val), nil
+	case time.Time:
+		return FormatTOMLDatetime(val), nil
+	case []interface{}:
+		return formatTOMLArray(len(val), func(i int) interface{} { return val[i] })
+	case []map[string]interface{}:
+		return formatTOMLArray(len(val), func(i int) interface{} { return val[i] })
+	case map[string]interface{}:
+		keys := make([]string, 0, len(val))
+		for k := range val {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		entries := make([]string, len(keys))
+		for i, k := range keys {
+			item, err := formatTOMLValue(val[k])
+			if err != nil {
+				return "", err
+			}
+			entries[i] = FormatTOMLKey(k) + " = " + item
+		}
+		if len(entries) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(entries, ", ") + " }", nil
+	default:
+		return "", fmt.Errorf("cannot encode %T as TOML", v)
+	}
+}
+
+// formatTOMLArray writes an array of n items
+func formatTOMLArray(n int, item func(int) interface{}) (string, error) {
+	items := make([]string, n)
+	for i := range items {
+		s, err := formatTOMLValue(item(i))
+		if err != nil {
+			return "", err
+		}
+		items[i] = s
+	}
+	return "[" + strings.Join(items, ", ") + "]", nil
+}
+
+// formatTOMLFloat writes a float so that it reads back as a float
+func formatTOMLFloat(f float64) string {
+	if name, ok := nonFiniteName(f); ok {
+		return name
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".e") {
+		s += ".0"
+	}
+	return s
+}
+
+// DatetimeKind is one of the four TOML datetime types, which all decode to
+// time.Time. Kinds are declared in the order datetimes of different kinds
+// sort in.
+type DatetimeKind int
+
+const (
+	OffsetDatetime DatetimeKind = iota
+	LocalDatetime
+	LocalDate
+	LocalTime
+)
+
+func (k DatetimeKind) String() string {
+	switch k {
+	case LocalDatetime:
+		return "local datetime"
+	case LocalDate:
+		return "local date"
+	case LocalTime:
+		return "local time"
+	default:
+		return "offset datetime"
+	}
+}
+
+// HasDate reports whether values of the kind carry a calendar date
+func (k DatetimeKind) HasDate() bool {
+	return k != LocalTime
+}
+
+// HasTime reports whether values of the kind carry a time of day
+func (k DatetimeKind) HasTime() bool {
+	return k != LocalDate
+}
+
+// KindOf returns the TOML type of a datetime from the name of its
+// location, which the TOML decoder sets for local datetimes, dates and
+// times
+func KindOf(t time.Time) DatetimeKind {
+	switch t.Location().String() {
+	case "datetime-local":
+		return LocalDatetime
+	case "date-local":
+		return LocalDate
+	case "time-local":
+		return LocalTime
+	default:
+		return OffsetDatetime
+	}
+}
+
+// FormatTOMLDatetime writes a datetime in the form TOML uses for its kind
+func FormatTOMLDatetime(t time.Time) string {
+	switch KindOf(t) {
+	case LocalDatetime:
+		return t.Format("2006-01-02T15:04:05.999999999")
+	case LocalDate:
+		return t.Format("2006-01-02")
+	case LocalTime:
+		return t.Format("15:04:05.999999999")
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+// FormatTOMLKey writes a key bare when TOML allows it and quoted otherwise,
+// as in a TOML document or a query path
+func FormatTOMLKey(key string) string {
+	if key == "" {
+		return `""`
+	}
+	for _, c := range key {
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-') {
+			return QuoteTOMLString(key)
+		}
+	}
+	return key
+}
+
+// QuoteTOMLString writes s as a TOML basic string, escaping quotes,
+// backslashes and control characters
+func QuoteTOMLString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, c := range s {
+		switch c {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if c < 0x20 || c == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, c)
+			} else {
+				b.WriteRune(c)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
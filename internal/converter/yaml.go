@@ -0,0 +1,42 @@
+package converter
+
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlValue returns a copy of v that yaml.v3 writes without losing the
+// TOML datetime types: dates and datetimes as YAML timestamps written the
+// way TOML writes them, so local ones stay local rather than becoming UTC
+// instants, and local times, which YAML has no type for, as quoted
+// strings. Values that need no change are shared with v.
+func yamlValue(v interface{}) interface{} {
+	switch val := v.(type) {
+	case time.Time:
+		node := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!timestamp", Value: FormatTOMLDatetime(val)}
+		if KindOf(val) == LocalTime {
+			node.Tag, node.Style = "!!str", yaml.DoubleQuotedStyle
+		}
+		return node
+	case []interface{}:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = yamlValue(item)
+		}
+		return items
+	case []map[string]interface{}:
+		items := make([]interface{}, len(val))
+		for i, item := range val {
+			items[i] = yamlValue(item)
+		}
+		return items
+	case map[string]interface{}:
+		table := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			table[k] = yamlValue(item)
+		}
+		return table
+	}
+	return v
+}
@@ -11,6 +11,28 @@
 //	err := mod.SetValue(data, `.project.version = "1.0.0"`)
 //	err := mod.SetValue(data, `.database.port = 5432`)
 //
+// Values may come from variables, which are used as they are, so strings
+// from scripts need no quoting:
+//
+//	mod := modifier.NewWithVariables(map[string]interface{}{"v": version})
+//	err := mod.SetValue(data, `.project.version = $v`)
+//
+// Update-assignments compute the new value from the old one. Their
+// right-hand side is a query, and their left-hand side any path
+// expression:
+//
+//	err := mod.SetValue(data, `.build.number += 1`)
+//	err := mod.SetValue(data, `.name |= ascii_downcase`)
+//	err := mod.SetValue(data, `.servers[].timeout //= 30`)
+//
+// # Edit Scripts
+//
+// Apply runs several set, update and delete statements against one
+// document, changing it only if all of them succeed. SplitStatements
+// splits a script on commas and newlines outside strings and brackets:
+//
+//	err := mod.Apply(data, modifier.SplitStatements(".version = $v, .build.number += 1\ndel(.debug)"))
+//
 // # Delete Operations
 //
 // Delete values using del() syntax:
@@ -20,10 +42,23 @@
 //
 // # Data Types
 //
-// Supported value types in set operations:
-//   - Strings: "hello", 'world'
-//   - Numbers: 42, 3.14
+// The value of a set operation is a TOML value, as on the right of a key
+// in a TOML file:
+//   - Strings: "hello\tworld", 'C:\path' and multi-line strings in triple quotes
+//   - Integers: 42, 1_000, 0xff, 0o755, 0b101
+//   - Floats: 3.14, 1e3, inf, nan
 //   - Booleans: true, false
+//   - Datetimes: 2024-05-01T10:00:00Z, 2024-05-01T10:00:00, 2024-05-01, 10:00:00
+//   - Arrays and inline tables: ["a", "b"], { host = "localhost" }
+//
+// A right-hand side that is not valid TOML but a valid query is run
+// against data, as in jq:
+//
+//	err := mod.SetValue(data, `.release.name = .project.version`)
+//	err := mod.SetValue(data, `.total = .a + .b`)
+//
+// Otherwise a bare word, made of letters, digits and . _ - : / @, such as
+// localhost or 1.2.3-beta, is stored as a string; other text is an error.
 //
 // # Path Navigation
 //
@@ -32,6 +67,38 @@
 //	// Creates nested structure automatically
 //	mod.SetValue(data, `.config.database.host = "localhost"`)
 //
+// Paths use the same TOML key rules as queries, so quoted keys work:
+//
+//	mod.SetValue(data, `."example.com".port = 443`)
+//
+// As in a TOML file, the leading dot of a set expression's key may be left
+// out:
+//
+//	mod.SetValue(data, `title = "x"`)
+//
+// Array elements are addressed by index, including negative indexes and
+// slices, in plain arrays and arrays of tables:
+//
+//	mod.SetValue(data, `.servers[0].port = 8080`)
+//	mod.DeleteValue(data, `del(.servers[-1])`)
+//	mod.DeleteValue(data, `del(.ports[1:3])`)
+//
+// Setting the index just past the end appends, and a missing array is
+// created, so new [[table]] entries need no special syntax. append and
+// insert add elements through an update-assignment:
+//
+//	mod.SetValue(data, `.bin[1] = { name = "tmq" }`)
+//	mod.SetValue(data, `.bin |= append({name: "tmq"})`)
+//	mod.SetValue(data, `.ports |= insert(0; 80)`)
+//
+// Iteration applies the operation to every element or table value:
+//
+//	mod.SetValue(data, `.servers[].enabled = true`)
+//
+// del also accepts a filter and deletes every value it selects:
+//
+//	mod.DeleteValue(data, `del(.servers[] | select(.port < 1024))`)
+//
 // # Error Handling
 //
 // Operations return detailed errors for:
@@ -6,50 +6,108 @@ package modifier
 
 import (
 	"fmt"
-	"strconv"
 	"strings"
+	"unicode"
 
+	"github.com/azolfagharj/tmq/internal/converter"
 	"github.com/azolfagharj/tmq/internal/query"
 )
 
 // Modifier handles TOML modification operations
-type Modifier struct{}
+type Modifier struct {
+	vars map[string]interface{}
+}
 
 // New creates a new TOML modifier
 func New() *Modifier {
 	return &Modifier{}
 }
 
+// NewWithVariables creates a TOML modifier whose set expressions may take
+// their value from vars, as in .version = $v
+func NewWithVariables(vars map[string]interface{}) *Modifier {
+	return &Modifier{vars: vars}
+}
+
 // SetValue sets a value at the specified path in the TOML data
-// Supports syntax like: .key = "value", .nested.key = 42
+// Supports syntax like: .key = "value", .nested.key = 42, .servers[0].port = 8080,
+// ."example.com".port = 443, .version = $v
+//
+// A right-hand side that is not a TOML value but a query, such as
+// .version, $n + 1 or [.a, .b], is run against data: .release = .version.
+//
+// Update-assignments compute the new value from the old one, with a query
+// on the right: .build.number += 1, .name |= ascii_downcase,
+// .timeout //= 30. The operators are |=, +=, -=, *=, /=, %= and //=.
 func (m *Modifier) SetValue(data map[string]interface{}, setExpr string) error {
-	// Parse set expression: ".key = value"
-	parts := strings.SplitN(setExpr, "=", 2)
-	if len(parts) != 2 {
-		return fmt.Errorf("invalid set expression: %s (expected: .key = value)", setExpr)
+	if op := query.AssignmentOperator(setExpr, m.vars); op != "" && op != "=" {
+		return m.updateValue(data, setExpr)
 	}
 
-	path := strings.TrimSpace(parts[0])
-	valueStr := strings.TrimSpace(parts[1])
-
-	// Parse the path
-	q, err := query.New(path)
+	// Parse set expression: ".key = value". The path is parsed first so that
+	// quoted keys containing "=" are not mistaken for the assignment.
+	q, rest, err := query.ParsePrefix(setExpr)
 	if err != nil {
 		return fmt.Errorf("invalid path in set expression: %v", err)
 	}
 
-	// Parse the value
-	value, err := parseValue(valueStr)
-	if err != nil {
-		return fmt.Errorf("invalid value in set expression: %v", err)
+	rest = strings.TrimSpace(rest)
+	if !strings.HasPrefix(rest, "=") {
+		return fmt.Errorf("invalid set expression: %s (expected: .key = value)", setExpr)
+	}
+	valueStr := strings.TrimSpace(rest[1:])
+
+	// Parse the value, or take it from a variable
+	var value interface{}
+	if name, ok := strings.CutPrefix(valueStr, "$"); ok && isIdentifier(name) {
+		if value, ok = m.vars[name]; !ok {
+			return fmt.Errorf("invalid value in set expression: $%s is not defined", name)
+		}
+	} else if value, err = converter.ParseTOMLValue(valueStr); err != nil {
+		// As in jq, a right-hand side that is not a TOML value but a query
+		// runs against data, as in .name = .version or .total = .a + .b
+		// Versions and addresses such as 1.2.3-beta are not queries
+		_, qerr := query.NewWithVariables(valueStr, m.vars)
+		if qerr == nil && !isVersion(valueStr) && !isDecimalInteger(valueStr) {
+			return m.updateValue(data, q.String()+" = ("+valueStr+")")
+		}
+		if value, err = parseValue(valueStr); err != nil {
+			if qerr != nil {
+				return fmt.Errorf("invalid value in set expression: %v; as a query: %v", err, qerr)
+			}
+			return fmt.Errorf("invalid value in set expression: %v", err)
+		}
 	}
 
 	// Set the value
 	return m.setValueAtPath(data, q.Parts(), value)
 }
 
+// updateValue runs an update-assignment as a query against data, which
+// then holds the updated table the query returns
+func (m *Modifier) updateValue(data map[string]interface{}, updateExpr string) error {
+	q, err := query.NewWithVariables(updateExpr, m.vars)
+	if err != nil {
+		return fmt.Errorf("invalid update expression: %v", err)
+	}
+	results, err := q.Execute(data)
+	if err != nil {
+		return err
+	}
+	if len(results) != 1 {
+		return fmt.Errorf("update expression %s produced %d results, expected 1", updateExpr, len(results))
+	}
+	updated, ok := results[0].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("cannot update the root table to a non-table value")
+	}
+	replaceContents(data, updated)
+	return nil
+}
+
 // DeleteValue deletes a value at the specified path
-// Supports syntax like: del(.key), del(.nested.key)
+// Supports syntax like: del(.key), del(.nested.key), del(.servers[-1]),
+// del(.servers[] | select(.port < 1024))
 func (m *Modifier) DeleteValue(data map[string]interface{}, deleteExpr string) error {
 	// Parse delete expression: "del(.key)"
 	if !strings.HasPrefix(deleteExpr, "del(") || !strings.HasSuffix(deleteExpr, ")") {
@@ -58,95 +116,125 @@ func (m *Modifier) DeleteValue(data map[string]interface{}, deleteExpr string) e
 
 	pathStr := deleteExpr[4 : len(deleteExpr)-1] // Extract ".key" from "del(.key)"
 
-	q, err := query.New(pathStr)
+	q, err := query.NewWithVariables(pathStr, m.vars)
 	if err != nil {
 		return fmt.Errorf("invalid path in delete expression: %v", err)
 	}
+	if parts := q.Parts(); parts != nil {
+		return m.deleteValuesAtPaths(data, [][]interface{}{parts})
+	}
 
-	return m.deleteValueAtPath(data, q.Parts())
+	// Filters such as del(.servers[] | select(.port < 1024)) delete every
+	// value they select
+	paths, err := q.Paths(data)
+	if err != nil {
+		return fmt.Errorf("invalid delete expression: %s (expected a path): %w", deleteExpr, err)
+	}
+	return m.deleteValuesAtPaths(data, paths)
 }
 
-// parseValue parses a string value into the appropriate Go type
+// parseValue parses the right-hand side of a set expression as a TOML
+// value: strings with escapes, numbers in every TOML notation, booleans,
+// datetimes, arrays and inline tables. A bare word that is not valid TOML,
+// such as localhost, my-host or 1.2.3, is taken as a string. Anything else,
+// such as text with spaces or operators, and integers TOML rejects, such as
+// 9223372036854775808, are errors rather than strings.
 func parseValue(s string) (interface{}, error) {
-	// Remove quotes if present
-	if len(s) >= 2 && ((s[0] == '"' && s[len(s)-1] == '"') || (s[0] == '\'' && s[len(s)-1] == '\'')) {
-		return s[1 : len(s)-1], nil
+	value, err := converter.ParseTOMLValue(s)
+	if err == nil {
+		return value, nil
 	}
-
-	// Try to parse as boolean
-	if s == "true" {
-		return true, nil
+	if !isBareWord(s) || isDecimalInteger(s) {
+		return nil, err
 	}
-	if s == "false" {
-		return false, nil
+	return s, nil
+}
+
+// isBareWord reports whether s is made of letters, digits and the
+// punctuation of host names, versions and paths: . _ - : / @
+func isBareWord(s string) bool {
+	for _, r := range s {
+		if !(unicode.IsLetter(r) || unicode.IsDigit(r) || strings.ContainsRune("._-:/@", r)) {
+			return false
+		}
 	}
+	return s != ""
+}
 
-	// Try to parse as number
-	if num, err := strconv.ParseFloat(s, 64); err == nil {
-		// Check if it's an integer
-		if strings.Contains(s, ".") {
-			return num, nil
+// isIdentifier reports whether s is a variable name
+func isIdentifier(s string) bool {
+	for i, r := range s {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || i > 0 && r >= '0' && r <= '9') {
+			return false
 		}
-		return int64(num), nil
 	}
+	return s != ""
+}
 
-	// Default to string
-	return s, nil
+// isVersion reports whether s is a bare word that starts with a digit and
+// has more dots than a number can, as versions such as 1.2.3-beta and
+// addresses such as 10.0.0.1 do
+func isVersion(s string) bool {
+	return isBareWord(s) && s[0] >= '0' && s[0] <= '9' && strings.Count(s, ".") >= 2
+}
+
+// isDecimalInteger reports whether s is written as a decimal integer,
+// with an optional sign and underscores
+func isDecimalInteger(s string) bool {
+	if s != "" && (s[0] == '+' || s[0] == '-') {
+		s = s[1:]
+	}
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return false
+	}
+	for _, r := range s {
+		if (r < '0' || r > '9') && r != '_' {
+			return false
+		}
+	}
+	return true
 }
 
 // setValueAtPath sets a value at the specified path in the data structure
-func (m *Modifier) setValueAtPath(data map[string]interface{}, path []string, value interface{}) error {
+func (m *Modifier) setValueAtPath(data map[string]interface{}, path []interface{}, value interface{}) error {
 	if len(path) == 0 {
 		return fmt.Errorf("cannot set root value")
 	}
 
-	current := data
-	for i, key := range path[:len(path)-1] {
-		if next, exists := current[key]; exists {
-			if nextMap, ok := next.(map[string]interface{}); ok {
-				current = nextMap
-			} else {
-				return fmt.Errorf("cannot navigate into %T at %s", next, strings.Join(path[:i+1], "."))
-			}
-		} else {
-			// Create nested map
-			newMap := make(map[string]interface{})
-			current[key] = newMap
-			current = newMap
-		}
+	updated, err := query.SetPath(data, path, value)
+	if err != nil {
+		return err
 	}
-
-	// Set the final value
-	finalKey := path[len(path)-1]
-	current[finalKey] = value
+	replaceContents(data, updated.(map[string]interface{}))
 	return nil
 }
 
-// deleteValueAtPath deletes a value at the specified path
-func (m *Modifier) deleteValueAtPath(data map[string]interface{}, path []string) error {
-	if len(path) == 0 {
-		return fmt.Errorf("cannot delete root value")
-	}
-
-	current := data
-	for i, key := range path[:len(path)-1] {
-		if next, exists := current[key]; exists {
-			if nextMap, ok := next.(map[string]interface{}); ok {
-				current = nextMap
-			} else {
-				return fmt.Errorf("cannot navigate into %T at %s", next, strings.Join(path[:i+1], "."))
-			}
-		} else {
-			return fmt.Errorf("path not found: %s", strings.Join(path[:i+1], "."))
+// deleteValuesAtPaths deletes the values at the specified paths
+func (m *Modifier) deleteValuesAtPaths(data map[string]interface{}, paths [][]interface{}) error {
+	for _, path := range paths {
+		if len(path) == 0 {
+			return fmt.Errorf("cannot delete root value")
 		}
 	}
 
-	// Delete the final key
-	finalKey := path[len(path)-1]
-	if _, exists := current[finalKey]; !exists {
-		return fmt.Errorf("key not found: %s", strings.Join(path, "."))
+	updated, err := query.DeletePaths(data, paths)
+	if err != nil {
+		return err
 	}
-
-	delete(current, finalKey)
+	replaceContents(data, updated.(map[string]interface{}))
 	return nil
 }
+
+// replaceContents makes data hold the entries of updated. The query
+// package leaves its input alone and returns changed copies, while the
+// modifier changes the caller's table.
+func replaceContents(data, updated map[string]interface{}) {
+	for key := range data {
+		if _, ok := updated[key]; !ok {
+			delete(data, key)
+		}
+	}
+	for key, value := range updated {
+		data[key] = value
+	}
+}
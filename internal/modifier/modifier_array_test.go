@@ -0,0 +1,355 @@
+package modifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+	"github.com/azolfagharj/tmq/internal/query"
+)
+
+// createArrayTestData mirrors what the TOML parser produces for plain
+// arrays ([]interface{}) and arrays of tables ([]map[string]interface{})
+func createArrayTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"ports": []interface{}{int64(8080), int64(8443), int64(9000)},
+		"servers": []map[string]interface{}{
+			{"name": "web1", "port": int64(80)},
+			{"name": "web2", "port": int64(81)},
+		},
+	}
+}
+
+func TestSetValue_Arrays(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		key     string
+		want    interface{}
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "set element",
+			expr: `.ports[1] = 1`,
+			key:  "ports",
+			want: []interface{}{int64(8080), int64(1), int64(9000)},
+		},
+		{
+			name: "set negative index",
+			expr: `.ports[-1] = 1`,
+			key:  "ports",
+			want: []interface{}{int64(8080), int64(8443), int64(1)},
+		},
+		{
+			name: "set field in array of tables",
+			expr: `.servers[1].port = 8080`,
+			key:  "servers",
+			want: []map[string]interface{}{
+				{"name": "web1", "port": int64(80)},
+				{"name": "web2", "port": int64(8080)},
+			},
+		},
+		{
+			name: "non-table element turns array of tables into array",
+			expr: `.servers[0] = "gone"`,
+			key:  "servers",
+			want: []interface{}{"gone", map[string]interface{}{"name": "web2", "port": int64(81)}},
+		},
+		{
+			name: "index past the end appends",
+			expr: `.ports[3] = 1`,
+			key:  "ports",
+			want: []interface{}{int64(8080), int64(8443), int64(9000), int64(1)},
+		},
+		{
+			name: "new entry in array of tables",
+			expr: `.servers[2] = { name = "web3", port = 82 }`,
+			key:  "servers",
+			want: []map[string]interface{}{
+				{"name": "web1", "port": int64(80)},
+				{"name": "web2", "port": int64(81)},
+				{"name": "web3", "port": int64(82)},
+			},
+		},
+		{
+			name: "new entry from a field",
+			expr: `.servers[2].name = "web3"`,
+			key:  "servers",
+			want: []map[string]interface{}{
+				{"name": "web1", "port": int64(80)},
+				{"name": "web2", "port": int64(81)},
+				{"name": "web3"},
+			},
+		},
+		{
+			name: "index into missing key creates the array",
+			expr: `.bin[0].name = "tmq"`,
+			key:  "bin",
+			want: []interface{}{map[string]interface{}{"name": "tmq"}},
+		},
+		{name: "index out of range", expr: `.ports[4] = 1`, wantErr: true, errMsg: "index out of range: .ports[4] (length 3)"},
+		{name: "negative index out of range", expr: `.ports[-4] = 1`, wantErr: true, errMsg: "index out of range"},
+		{name: "index into table", expr: `.servers[0][1] = 1`, wantErr: true, errMsg: "cannot navigate into"},
+		{name: "slice needs array value", expr: `.ports[0:1] = 1`, wantErr: true, errMsg: "cannot assign"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := createArrayTestData()
+			err := New().SetValue(data, tt.expr)
+			assertArrayResult(t, data, err, tt.key, tt.want, tt.wantErr, tt.errMsg)
+		})
+	}
+}
+
+func TestSetValue_ArraySlices(t *testing.T) {
+	// Slice assignment needs an array value, which the set syntax cannot
+	// express yet, so exercise setValueAtPath directly
+	one, two := 1, 2
+	data := createArrayTestData()
+	path := []interface{}{"ports", query.Slice{Start: &one, End: &two}}
+
+	err := New().setValueAtPath(data, path, []interface{}{"a", "b"})
+	want := []interface{}{int64(8080), "a", "b", int64(9000)}
+	assertArrayResult(t, data, err, "ports", want, false, "")
+
+	// A path continuing after a slice updates the elements in the slice
+	data = createArrayTestData()
+	path = []interface{}{"servers", query.Slice{Start: &one}, 0, "port"}
+	err = New().setValueAtPath(data, path, int64(1))
+	wantServers := []map[string]interface{}{
+		{"name": "web1", "port": int64(80)},
+		{"name": "web2", "port": int64(1)},
+	}
+	assertArrayResult(t, data, err, "servers", wantServers, false, "")
+}
+
+func TestDeleteValue_Arrays(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		key     string
+		want    interface{}
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "delete element",
+			expr: `del(.ports[1])`,
+			key:  "ports",
+			want: []interface{}{int64(8080), int64(9000)},
+		},
+		{
+			name: "delete last element",
+			expr: `del(.ports[-1])`,
+			key:  "ports",
+			want: []interface{}{int64(8080), int64(8443)},
+		},
+		{
+			name: "delete slice",
+			expr: `del(.ports[:2])`,
+			key:  "ports",
+			want: []interface{}{int64(9000)},
+		},
+		{
+			name: "delete table from array of tables",
+			expr: `del(.servers[0])`,
+			key:  "servers",
+			want: []map[string]interface{}{{"name": "web2", "port": int64(81)}},
+		},
+		{
+			name: "delete key inside array of tables",
+			expr: `del(.servers[1].port)`,
+			key:  "servers",
+			want: []map[string]interface{}{
+				{"name": "web1", "port": int64(80)},
+				{"name": "web2"},
+			},
+		},
+		{
+			name: "delete key in every sliced table",
+			expr: `del(.servers[0:2].name)`,
+			key:  "servers",
+			want: []map[string]interface{}{{"port": int64(80)}, {"port": int64(81)}},
+		},
+		{
+			name: "delete selected elements",
+			expr: `del(.ports[] | select(. > 8080))`,
+			key:  "ports",
+			want: []interface{}{int64(8080)},
+		},
+		{
+			name: "delete selected tables",
+			expr: `del(.servers[] | select(.port == 80))`,
+			key:  "servers",
+			want: []map[string]interface{}{{"name": "web2", "port": int64(81)}},
+		},
+		{
+			name: "delete computed paths",
+			expr: `del(paths(. == 81) as $p | getpath($p))`,
+			key:  "servers",
+			want: []map[string]interface{}{{"name": "web1", "port": int64(80)}, {"name": "web2"}},
+		},
+		{name: "index out of range", expr: `del(.ports[5])`, wantErr: true, errMsg: "index out of range"},
+		{name: "missing key in element", expr: `del(.servers[0].missing)`, wantErr: true, errMsg: "key not found"},
+		{name: "filter instead of path", expr: `del(.servers | keys)`, wantErr: true, errMsg: "expected a path"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := createArrayTestData()
+			err := New().DeleteValue(data, tt.expr)
+			assertArrayResult(t, data, err, tt.key, tt.want, tt.wantErr, tt.errMsg)
+		})
+	}
+}
+
+func TestSetValue_ArrayEdits(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		key     string
+		want    interface{}
+		wantErr bool
+		errMsg  string
+	}{
+		{
+			name: "append",
+			expr: `.ports |= append(9443)`,
+			key:  "ports",
+			want: []interface{}{int64(8080), int64(8443), int64(9000), int64(9443)},
+		},
+		{
+			name: "insert",
+			expr: `.ports |= insert(0; 80)`,
+			key:  "ports",
+			want: []interface{}{int64(80), int64(8080), int64(8443), int64(9000)},
+		},
+		{
+			name: "append a table",
+			expr: `.servers |= append({name: "web3", port: 82})`,
+			key:  "servers",
+			want: []map[string]interface{}{
+				{"name": "web1", "port": int64(80)},
+				{"name": "web2", "port": int64(81)},
+				{"name": "web3", "port": int64(82)},
+			},
+		},
+		{
+			name: "insert a table",
+			expr: `.servers |= insert(1; {name: "lb"})`,
+			key:  "servers",
+			want: []map[string]interface{}{
+				{"name": "web1", "port": int64(80)},
+				{"name": "lb"},
+				{"name": "web2", "port": int64(81)},
+			},
+		},
+		{
+			name: "append to a missing array",
+			expr: `.bin |= append({name: "tmq"})`,
+			key:  "bin",
+			want: []interface{}{map[string]interface{}{"name": "tmq"}},
+		},
+		{name: "insert out of range", expr: `.ports |= insert(5; 1)`, wantErr: true, errMsg: "insert index 5 is out of range"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := createArrayTestData()
+			err := New().SetValue(data, tt.expr)
+			assertArrayResult(t, data, err, tt.key, tt.want, tt.wantErr, tt.errMsg)
+		})
+	}
+}
+
+func TestSetValue_ArraysOfTablesWriteBack(t *testing.T) {
+	data, err := converter.ParseTOMLDocument("[[bin]]\nname = \"one\"\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	mod := New()
+	for _, expr := range []string{
+		`.bin[1] = { name = "two" }`,
+		`.bin |= append({name: "three"})`,
+		`.example[0].name = "demo"`,
+	} {
+		if err := mod.SetValue(data, expr); err != nil {
+			t.Fatalf("%s: unexpected error: %v", expr, err)
+		}
+	}
+
+	// New entries come back out as [[table]] blocks
+	output, err := converter.ConvertToTOML(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n := strings.Count(output, "[[bin]]"); n != 3 {
+		t.Errorf("expected 3 [[bin]] blocks, got %d in:\n%s", n, output)
+	}
+	if !strings.Contains(output, "[[example]]") {
+		t.Errorf("expected an [[example]] block in:\n%s", output)
+	}
+}
+
+func assertArrayResult(t *testing.T, data map[string]interface{}, err error, key string, want interface{}, wantErr bool, errMsg string) {
+	t.Helper()
+
+	if wantErr {
+		if err == nil {
+			t.Errorf("expected error, got nil")
+		} else if errMsg != "" && !strings.Contains(err.Error(), errMsg) {
+			t.Errorf("expected error containing %q, got %q", errMsg, err.Error())
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(data[key], want) {
+		t.Errorf("expected %#v, got %#v", want, data[key])
+	}
+}
+
+func TestModify_Iteration(t *testing.T) {
+	t.Run("set field in every table", func(t *testing.T) {
+		data := createArrayTestData()
+		err := New().SetValue(data, `.servers[].port = 443`)
+		want := []map[string]interface{}{
+			{"name": "web1", "port": int64(443)},
+			{"name": "web2", "port": int64(443)},
+		}
+		assertArrayResult(t, data, err, "servers", want, false, "")
+	})
+
+	t.Run("set every table value", func(t *testing.T) {
+		data := map[string]interface{}{
+			"deps": map[string]interface{}{"a": "1.0", "b": "2.0"},
+		}
+		err := New().SetValue(data, `.deps.* = "*"`)
+		assertArrayResult(t, data, err, "deps", map[string]interface{}{"a": "*", "b": "*"}, false, "")
+	})
+
+	t.Run("delete field from every table", func(t *testing.T) {
+		data := createArrayTestData()
+		err := New().DeleteValue(data, `del(.servers[].name)`)
+		want := []map[string]interface{}{{"port": int64(80)}, {"port": int64(81)}}
+		assertArrayResult(t, data, err, "servers", want, false, "")
+	})
+
+	t.Run("delete every element", func(t *testing.T) {
+		data := createArrayTestData()
+		err := New().DeleteValue(data, `del(.ports[])`)
+		assertArrayResult(t, data, err, "ports", []interface{}{}, false, "")
+	})
+
+	t.Run("iterate scalar", func(t *testing.T) {
+		data := map[string]interface{}{"name": "app"}
+		err := New().SetValue(data, `.name[] = 1`)
+		assertArrayResult(t, data, err, "", nil, true, "cannot navigate into string")
+	})
+}
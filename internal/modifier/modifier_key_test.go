@@ -0,0 +1,82 @@
+package modifier
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModify_QuotedKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		initial  map[string]interface{}
+		expr     string
+		del      bool
+		expected map[string]interface{}
+	}{
+		{
+			name:     "set dotted quoted key",
+			initial:  map[string]interface{}{},
+			expr:     `."example.com".port = 443`,
+			expected: map[string]interface{}{"example.com": map[string]interface{}{"port": int64(443)}},
+		},
+		{
+			name:     "set key containing equals",
+			initial:  map[string]interface{}{},
+			expr:     `."a=b" = "x=y"`,
+			expected: map[string]interface{}{"a=b": "x=y"},
+		},
+		{
+			name:     "set literal key with spaces",
+			initial:  map[string]interface{}{},
+			expr:     `.tool.'black config' = true`,
+			expected: map[string]interface{}{"tool": map[string]interface{}{"black config": true}},
+		},
+		{
+			name:     "set key without leading dot",
+			initial:  map[string]interface{}{"title": "x"},
+			expr:     `title = "y"`,
+			expected: map[string]interface{}{"title": "y"},
+		},
+		{
+			name:     "set nested key without leading dot",
+			initial:  map[string]interface{}{},
+			expr:     `owner."full name" = "x"`,
+			expected: map[string]interface{}{"owner": map[string]interface{}{"full name": "x"}},
+		},
+		{
+			name: "delete quoted key",
+			initial: map[string]interface{}{
+				"tool": map[string]interface{}{"black-config": "x", "other": "y"},
+			},
+			expr:     `del(.tool."black-config")`,
+			del:      true,
+			expected: map[string]interface{}{"tool": map[string]interface{}{"other": "y"}},
+		},
+		{
+			name:     "delete key with escaped quote",
+			initial:  map[string]interface{}{`say "hi"`: "x", "keep": "y"},
+			expr:     `del(."say \"hi\"")`,
+			del:      true,
+			expected: map[string]interface{}{"keep": "y"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New()
+			var err error
+			if tt.del {
+				err = m.DeleteValue(tt.initial, tt.expr)
+			} else {
+				err = m.SetValue(tt.initial, tt.expr)
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.initial, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, tt.initial)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package modifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		expected []string
+	}{
+		{name: "one statement", script: ".a = 1", expected: []string{".a = 1"}},
+		{name: "commas", script: ".a = 1, .b = 2,del(.c)", expected: []string{".a = 1", ".b = 2", "del(.c)"}},
+		{name: "newlines", script: ".a = 1\n.b += 1\r\n", expected: []string{".a = 1", ".b += 1"}},
+		{name: "blank lines and comments", script: "# bump\n\n.a += 1 # build\n  # done\n", expected: []string{".a += 1"}},
+		{name: "array value", script: `.tags = ["a", "b"], .c = 1`, expected: []string{`.tags = ["a", "b"]`, ".c = 1"}},
+		{name: "inline table", script: `.db = { host = "h", port = 1 }`, expected: []string{`.db = { host = "h", port = 1 }`}},
+		{name: "multi-line array", script: ".ports = [\n  80,\n  443,\n]\n.b = 1", expected: []string{".ports = [\n  80,\n  443,\n]", ".b = 1"}},
+		{name: "comma in parentheses", script: "(.a, .b) += 1", expected: []string{"(.a, .b) += 1"}},
+		{name: "comma in a string", script: `.name = "a, b", .c = 'x # y'`, expected: []string{`.name = "a, b"`, `.c = 'x # y'`}},
+		{name: "escaped quote", script: `.name = "say \"a, b\"", .c = 1`, expected: []string{`.name = "say \"a, b\""`, ".c = 1"}},
+		{name: "multi-line string", script: ".text = \"\"\"\nline, one\n\"\"\"\n.c = 1", expected: []string{".text = \"\"\"\nline, one\n\"\"\"", ".c = 1"}},
+		{name: "quoted key", script: `."a,b" = 1`, expected: []string{`."a,b" = 1`}},
+		{name: "empty", script: " \n,\n", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SplitStatements(tt.script); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	vars := map[string]interface{}{"v": "2.0"}
+
+	tests := []struct {
+		name       string
+		statements []string
+		expected   map[string]interface{}
+		errMsg     string
+	}{
+		{
+			name:       "in order",
+			statements: []string{".version = $v", ".build.number += 1", ".build.number *= 2", "del(.debug)"},
+			expected: map[string]interface{}{
+				"version": "2.0",
+				"build":   map[string]interface{}{"number": int64(84)},
+			},
+		},
+		{
+			name:       "later statements see earlier ones",
+			statements: []string{".ports = [80]", ".ports |= append(443)", ".ports[0] = 8080"},
+			expected: map[string]interface{}{
+				"version": "1.0",
+				"build":   map[string]interface{}{"number": int64(41)},
+				"debug":   true,
+				"ports":   []interface{}{int64(8080), int64(443)},
+			},
+		},
+		{name: "failing statement", statements: []string{".version = $v", ".version += 1"}, errMsg: "statement 2 (.version += 1): string and number cannot be added"},
+		{name: "query", statements: []string{".version = $v", ".build"}, errMsg: "statement 2 (.build): not a set, update or delete expression"},
+		{name: "update followed by a pipe", statements: []string{".build.number += 1 | .build"}, errMsg: "statement 1 (.build.number += 1 | .build): not a set, update or delete expression"},
+		{name: "invalid delete", statements: []string{"del(.a.b"}, errMsg: "statement 1 (del(.a.b): not a set, update or delete expression"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{
+				"version": "1.0",
+				"build":   map[string]interface{}{"number": int64(41)},
+				"debug":   true,
+			}
+			original := map[string]interface{}{
+				"version": "1.0",
+				"build":   map[string]interface{}{"number": int64(41)},
+				"debug":   true,
+			}
+
+			err := NewWithVariables(vars).Apply(data, tt.statements)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				// Nothing is applied when a statement fails
+				if !reflect.DeepEqual(data, original) {
+					t.Errorf("data changed to %#v", data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(data, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, data)
+			}
+		})
+	}
+}
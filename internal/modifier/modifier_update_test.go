@@ -0,0 +1,116 @@
+package modifier
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/azolfagharj/tmq/internal/query"
+)
+
+func createUpdateTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  "API",
+		"build": map[string]interface{}{"number": int64(41)},
+		"servers": []map[string]interface{}{
+			{"host": "a", "port": int64(80)},
+			{"host": "b", "port": int64(8080), "timeout": int64(5)},
+		},
+	}
+}
+
+func TestSetValue_Updates(t *testing.T) {
+	vars := map[string]interface{}{"step": int64(10)}
+
+	tests := []struct {
+		name     string
+		expr     string
+		path     []string
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "increment", expr: ".build.number += 1", path: []string{"build", "number"}, expected: int64(42)},
+		{name: "decrement by a variable", expr: ".build.number -= $step", path: []string{"build", "number"}, expected: int64(31)},
+		{name: "multiply", expr: ".build.number *= 2", path: []string{"build", "number"}, expected: int64(82)},
+		{name: "divide", expr: ".build.number /= 2", path: []string{"build", "number"}, expected: 20.5},
+		{name: "modulo", expr: ".build.number %= 10", path: []string{"build", "number"}, expected: int64(1)},
+		{name: "update with a filter", expr: ".name |= ascii_downcase", path: []string{"name"}, expected: "api"},
+		{name: "default", expr: ".timeout //= 30", path: []string{"timeout"}, expected: int64(30)},
+		{name: "default keeps a value", expr: ".name //= \"x\"", path: []string{"name"}, expected: "API"},
+		{name: "equals inside a string", expr: `.name |= . + " = 1"`, path: []string{"name"}, expected: "API = 1"},
+		{name: "every element", expr: ".servers[].timeout //= 30", path: []string{"servers"}, expected: []map[string]interface{}{
+			{"host": "a", "port": int64(80), "timeout": int64(30)},
+			{"host": "b", "port": int64(8080), "timeout": int64(5)},
+		}},
+		{name: "filtered elements", expr: "(.servers[] | select(.port > 1024) | .port) += 1", path: []string{"servers"}, expected: []map[string]interface{}{
+			{"host": "a", "port": int64(80)},
+			{"host": "b", "port": int64(8081), "timeout": int64(5)},
+		}},
+		{name: "remove elements", expr: ".servers |= map(select(.port < 1024))", path: []string{"servers"}, expected: []interface{}{
+			map[string]interface{}{"host": "a", "port": int64(80)},
+		}},
+
+		// errors
+		{name: "invalid right-hand side", expr: ".build.number += ", errMsg: "invalid update expression"},
+		{name: "type mismatch", expr: ".name -= 1", errMsg: "string and number cannot be subtracted"},
+		{name: "several results", expr: ".name += (\"a\", \"b\")", errMsg: "produced 2 results, expected 1"},
+		{name: "followed by a pipe", expr: ".name |= ascii_downcase | .name", errMsg: "invalid set expression"},
+		{name: "replace the root", expr: ". |= 1", errMsg: "cannot update the root table"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := createUpdateTestData()
+			err := NewWithVariables(vars).SetValue(data, tt.expr)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var value interface{} = data
+			for _, key := range tt.path {
+				value = value.(map[string]interface{})[key]
+			}
+			if !reflect.DeepEqual(value, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, value)
+			}
+		})
+	}
+}
+
+func TestSetValue_UserErrors(t *testing.T) {
+	raise := `if . < 1024 then error("privileged port") else . end`
+	tests := []struct {
+		name string
+		run  func(m *Modifier, data map[string]interface{}) error
+	}{
+		{name: "update", run: func(m *Modifier, data map[string]interface{}) error {
+			return m.SetValue(data, ".port |= "+raise)
+		}},
+		{name: "query value", run: func(m *Modifier, data map[string]interface{}) error {
+			return m.SetValue(data, ".port = (.port | "+raise+")")
+		}},
+		{name: "statement", run: func(m *Modifier, data map[string]interface{}) error {
+			return m.Apply(data, []string{".a = 1", ".port |= " + raise})
+		}},
+		{name: "delete", run: func(m *Modifier, data map[string]interface{}) error {
+			return m.DeleteValue(data, "del(.port | "+raise+")")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run(New(), map[string]interface{}{"port": int64(80)})
+			var userErr *query.UserError
+			if !errors.As(err, &userErr) || userErr.Value != "privileged port" {
+				t.Errorf("expected the error raised by the query, got %v", err)
+			}
+		})
+	}
+}
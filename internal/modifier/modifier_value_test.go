@@ -0,0 +1,161 @@
+package modifier
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+func TestSetValue_TOMLValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		expr     string
+		expected interface{}
+		errMsg   string
+	}{
+		// strings
+		{name: "escapes", expr: `.v = "a\tb\u00e9\n"`, expected: "a\tbé\n"},
+		{name: "literal string", expr: `.v = 'C:\path'`, expected: `C:\path`},
+		{name: "multi-line string", expr: ".v = \"\"\"\nline 1\nline 2\"\"\"", expected: "line 1\nline 2"},
+		{name: "multi-line literal string", expr: ".v = '''\nraw \\n'''", expected: `raw \n`},
+		{name: "quotes inside", expr: `.v = "say \"hi\""`, expected: `say "hi"`},
+
+		// numbers
+		{name: "hex integer", expr: `.v = 0xff`, expected: int64(255)},
+		{name: "octal integer", expr: `.v = 0o755`, expected: int64(493)},
+		{name: "binary integer", expr: `.v = 0b101`, expected: int64(5)},
+		{name: "underscores", expr: `.v = 1_000_000`, expected: int64(1000000)},
+		{name: "beyond float precision", expr: `.v = 9007199254740993`, expected: int64(9007199254740993)},
+		{name: "largest integer", expr: `.v = 9223372036854775807`, expected: int64(math.MaxInt64)},
+		{name: "integer overflow", expr: `.v = 9223372036854775808`, errMsg: "invalid TOML value"},
+		{name: "exponent", expr: `.v = 1e3`, expected: 1000.0},
+		{name: "negative float", expr: `.v = -0.5`, expected: -0.5},
+		{name: "infinity", expr: `.v = -inf`, expected: math.Inf(-1)},
+
+		// arrays and tables
+		{name: "array", expr: `.v = ["a", "b"]`, expected: []interface{}{"a", "b"}},
+		{name: "mixed array", expr: `.v = [1, "two", [3]]`, expected: []interface{}{int64(1), "two", []interface{}{int64(3)}}},
+		{name: "inline table", expr: `.v = { host = "localhost", port = 5432 }`, expected: map[string]interface{}{"host": "localhost", "port": int64(5432)}},
+		{name: "array of inline tables", expr: `.v = [{ a = 1 }]`, expected: []interface{}{map[string]interface{}{"a": int64(1)}}},
+
+		// bare words stay strings
+		{name: "bare word", expr: `.v = localhost`, expected: "localhost"},
+		{name: "version number", expr: `.v = 1.2.3`, expected: "1.2.3"},
+		{name: "host name", expr: `.v = my-host.local`, expected: "my-host.local"},
+		{name: "pre-release version", expr: `.v = 1.2.3-beta`, expected: "1.2.3-beta"},
+
+		// errors
+		{name: "unterminated array", expr: `.v = [1, 2`, errMsg: "invalid value in set expression"},
+		{name: "unterminated string", expr: `.v = "abc`, errMsg: "invalid TOML value"},
+		{name: "invalid inline table", expr: `.v = { a = }`, errMsg: "invalid TOML value"},
+		{name: "trailing data", expr: ".v = 1\nother = 2", errMsg: "unexpected data after the value"},
+		{name: "missing value", expr: `.v =`, errMsg: "invalid TOML value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{}
+			err := New().SetValue(data, tt.expr)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(data["v"], tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, data["v"])
+			}
+		})
+	}
+}
+
+func TestSetValue_Datetimes(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: `.v = 2024-05-01T10:00:00Z`, expected: "2024-05-01T10:00:00Z"},
+		{expr: `.v = 2024-05-01T10:00:00+02:00`, expected: "2024-05-01T10:00:00+02:00"},
+		{expr: `.v = 2024-05-01T10:00:00`, expected: "2024-05-01T10:00:00"},
+		{expr: `.v = 2024-05-01`, expected: "2024-05-01"},
+		{expr: `.v = 10:30:00`, expected: "10:30:00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			data := map[string]interface{}{}
+			if err := New().SetValue(data, tt.expr); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, ok := data["v"].(time.Time); !ok {
+				t.Fatalf("expected a datetime, got %#v", data["v"])
+			}
+			// The TOML type must survive writing the value back
+			formatted, err := converter.ConvertToTOML(data["v"])
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if formatted != tt.expected {
+				t.Errorf("expected %s, got %s", tt.expected, formatted)
+			}
+		})
+	}
+}
+
+func TestSetValue_QueryValues(t *testing.T) {
+	vars := map[string]interface{}{"n": int64(1)}
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "path", expr: `.v = .version`, expected: "2.0"},
+		{name: "bare key and path", expr: `v = .build.number`, expected: int64(41)},
+		{name: "pipe", expr: `.v = .build | keys`, expected: []interface{}{"number"}},
+		{name: "variable arithmetic", expr: `.v = $n + 1`, expected: int64(2)},
+		{name: "parenthesized", expr: `.v = (.build | keys)`, expected: []interface{}{"number"}},
+		{name: "pipe in a string is TOML", expr: `.v = "a | b"`, expected: "a | b"},
+		{name: "arithmetic", expr: `.v = 1 + 2`, expected: int64(3)},
+		{name: "arithmetic on values", expr: `.v = .build.number * 2`, expected: int64(82)},
+		{name: "boolean expression", expr: `.v = true and false`, expected: false},
+		{name: "array constructor", expr: `.v = [.version, .version]`, expected: []interface{}{"2.0", "2.0"}},
+		{name: "string interpolation", expr: `.v = "v\(.version)"`, expected: "v2.0"},
+		{name: "function", expr: `.v = (.version | ascii_downcase)`, expected: "2.0"},
+		{name: "function of the root table", expr: `.v = ascii_downcase`, errMsg: "cannot be"},
+		{name: "words with an operator", expr: `.v = foo + bar`, errMsg: "invalid value in set expression"},
+		{name: "words with spaces", expr: `.v = hello world`, errMsg: "invalid value in set expression"},
+		{name: "invalid query", expr: `.v = .version |`, errMsg: "as a query: syntax error at column 11: unexpected end of query"},
+		{name: "failing query", expr: `.v = 1 | keys`, errMsg: "number has no keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{"version": "2.0", "build": map[string]interface{}{"number": int64(41)}}
+			err := NewWithVariables(vars).SetValue(data, tt.expr)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				if _, ok := data["v"]; ok {
+					t.Errorf("expected no value, got %#v", data["v"])
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(data["v"], tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, data["v"])
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package modifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSetValue_Variables(t *testing.T) {
+	vars := map[string]interface{}{
+		"v":     `2.0" = "x`,
+		"port":  int64(8080),
+		"hosts": []interface{}{"a", "b"},
+	}
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected map[string]interface{}
+		errMsg   string
+	}{
+		{
+			name:     "string with quotes and equals",
+			expr:     `.version = $v`,
+			expected: map[string]interface{}{"version": `2.0" = "x`},
+		},
+		{
+			name:     "typed value",
+			expr:     `.server.port = $port`,
+			expected: map[string]interface{}{"server": map[string]interface{}{"port": int64(8080)}},
+		},
+		{
+			name:     "array value",
+			expr:     `.hosts=$hosts`,
+			expected: map[string]interface{}{"hosts": []interface{}{"a", "b"}},
+		},
+		{
+			name:   "undefined variable",
+			expr:   `.version = $missing`,
+			errMsg: "$missing is not defined",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{}
+			err := NewWithVariables(vars).SetValue(data, tt.expr)
+			if tt.errMsg != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+					t.Fatalf("expected error containing %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(data, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, data)
+			}
+		})
+	}
+}
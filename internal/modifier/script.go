@@ -0,0 +1,112 @@
+package modifier
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/azolfagharj/tmq/internal/query"
+)
+
+// SplitStatements splits an edit script into its statements. Statements
+// end at a comma or newline outside strings, parentheses, brackets and
+// braces, so ".a = 1, .b = 2" holds two statements while
+// `.tags = ["a", "b"]` and "(.a, .b) += 1" hold one. "#" starts a comment
+// that runs to the end of the line. Blank statements are dropped.
+func SplitStatements(script string) []string {
+	var statements []string
+	var current strings.Builder
+	flush := func() {
+		if statement := strings.TrimSpace(current.String()); statement != "" {
+			statements = append(statements, statement)
+		}
+		current.Reset()
+	}
+
+	depth := 0
+	for i := 0; i < len(script); {
+		switch c := script[i]; c {
+		case '"', '\'':
+			end := stringEnd(script, i)
+			current.WriteString(script[i:end])
+			i = end
+			continue
+		case '#':
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			continue
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth > 0 {
+				depth--
+			}
+		case ',', '\n':
+			if depth == 0 {
+				flush()
+				i++
+				continue
+			}
+		}
+		current.WriteByte(script[i])
+		i++
+	}
+	flush()
+	return statements
+}
+
+// stringEnd returns the index just past the string that starts at i: a
+// basic or literal string, single-line or in triple quotes. Only basic
+// strings have escapes, and an unterminated single-line string ends at
+// the end of the line.
+func stringEnd(s string, i int) int {
+	delim := s[i : i+1]
+	if strings.HasPrefix(s[i:], strings.Repeat(delim, 3)) {
+		delim = strings.Repeat(delim, 3)
+	}
+	for j := i + len(delim); j < len(s); {
+		switch {
+		case s[j] == '\\' && delim[0] == '"':
+			j += 2
+		case strings.HasPrefix(s[j:], delim):
+			return j + len(delim)
+		case s[j] == '\n' && len(delim) == 1:
+			return j
+		default:
+			j++
+		}
+	}
+	return len(s)
+}
+
+// Apply runs statements against data in order: set expressions,
+// update-assignments and del() expressions, as taken by [Modifier.SetValue]
+// and [Modifier.DeleteValue]. Each statement sees the changes of those
+// before it. data is only changed when every statement succeeds; the
+// error of the first one that fails names it and its position.
+func (m *Modifier) Apply(data map[string]interface{}, statements []string) error {
+	// The query package never changes values it is given, so a copy of
+	// the root table is enough to keep data as it is on failure
+	work := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		work[key] = value
+	}
+
+	for i, statement := range statements {
+		var err error
+		switch {
+		case query.AssignmentOperator(statement, m.vars) != "":
+			err = m.SetValue(work, statement)
+		case strings.HasPrefix(statement, "del(") && strings.HasSuffix(statement, ")"):
+			err = m.DeleteValue(work, statement)
+		default:
+			err = fmt.Errorf("not a set, update or delete expression")
+		}
+		if err != nil {
+			return fmt.Errorf("statement %d (%s): %w", i+1, statement, err)
+		}
+	}
+
+	replaceContents(data, work)
+	return nil
+}
@@ -0,0 +1,92 @@
+package query
+
+// arithmeticUpdates maps the update-assignment operators that combine the
+// old value with the right operand to the operator they apply
+var arithmeticUpdates = map[string]string{
+	"+=": "+", "-=": "-", "*=": "*", "/=": "/", "%=": "%", "//=": "//",
+}
+
+// evalAssign evaluates an assignment, whose left operand is a path
+// expression. As in jq, "lhs |= f" replaces every value lhs selects with
+// the first result of f run against it, and deletes the value when f
+// produces none. The other forms run their right operand against the
+// input and emit one updated copy of the input per result: "lhs = v"
+// stores v at every path and "lhs op= v" replaces each value with
+// "value op v", "//=" keeping values other than false and null.
+func (n *binaryNode) evalAssign(env *environment, in interface{}, emit emitFunc) error {
+	if n.op == "|=" {
+		result, err := updatePaths(env, n.left, in, func(old interface{}) (interface{}, bool, error) {
+			var value interface{}
+			found := false
+			err := evalLimit(env, n.right, old, 1, func(v interface{}) error {
+				value, found = v, true
+				return nil
+			})
+			return value, found, err
+		})
+		if err != nil {
+			return err
+		}
+		return emit(result)
+	}
+
+	return n.right.eval(env, in, func(v interface{}) error {
+		result, err := updatePaths(env, n.left, in, func(old interface{}) (interface{}, bool, error) {
+			switch op := arithmeticUpdates[n.op]; op {
+			case "":
+				return v, true, nil
+			case "//":
+				if isTruthy(old) {
+					return old, true, nil
+				}
+				return v, true, nil
+			default:
+				value, err := binaryOps[op](old, v)
+				return value, true, err
+			}
+		})
+		if err != nil {
+			return err
+		}
+		return emit(result)
+	})
+}
+
+// updatePaths returns in with the value at every path of lhs replaced by
+// the result of update, which is passed the current value, null when it
+// is missing. The paths are found in in before any is changed; those for
+// which update reports no value are deleted once the others are set.
+func updatePaths(env *environment, lhs node, in interface{}, update func(old interface{}) (interface{}, bool, error)) (interface{}, error) {
+	var paths [][]interface{}
+	err := evalPaths(env, lhs, pathValue{path: []interface{}{}, value: in}, func(pv pathValue) error {
+		paths = append(paths, pv.path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := in
+	var deleted [][]interface{}
+	for _, path := range paths {
+		old, err := getPath(result, path)
+		if err != nil {
+			return nil, err
+		}
+		value, ok, err := update(old)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			deleted = append(deleted, path)
+			continue
+		}
+		if result, err = SetPath(result, path, value); err != nil {
+			return nil, err
+		}
+	}
+	if len(deleted) > 0 {
+		return DeletePaths(result, deleted)
+	}
+	return result, nil
+}
@@ -0,0 +1,550 @@
+package query
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+// node is one expression of a compiled query
+type node interface {
+	// eval runs the expression against in and calls emit for every result
+	eval(env *environment, in interface{}, emit emitFunc) error
+	// String renders the expression in query syntax
+	String() string
+}
+
+// emitFunc receives the results of an expression one at a time. Returning
+// an error stops the evaluation.
+type emitFunc func(interface{}) error
+
+// identityNode is "."
+type identityNode struct{}
+
+// recurseNode is "..", every value in the input, each before its contents
+type recurseNode struct{}
+
+// fieldNode is ".key" applied to the results of term
+type fieldNode struct {
+	term node
+	key  string
+}
+
+// indexNode is "[index]" applied to the results of term. The index is
+// evaluated against the input of term, as in jq.
+type indexNode struct {
+	term  node
+	index node
+}
+
+// sliceNode is "[start:end]" applied to the results of term; a nil bound
+// is unbounded
+type sliceNode struct {
+	term  node
+	start node
+	end   node
+}
+
+// iterateNode is "[]" (or ".*") applied to the results of term
+type iterateNode struct {
+	term node
+}
+
+// pipeNode is "left | right"
+type pipeNode struct {
+	left  node
+	right node
+}
+
+// binaryNode is "left op right" for a binary operator
+type binaryNode struct {
+	op    string
+	left  node
+	right node
+}
+
+// tryNode is "try body catch handler", which runs handler against the
+// error that stops body. A nil handler, as in "body?" and "try body",
+// drops the error.
+type tryNode struct {
+	body    node
+	handler node
+}
+
+// ifNode is "if cond then then else els end"; "elif" is an ifNode in els.
+// A nil els passes the input through.
+type ifNode struct {
+	cond node
+	then node
+	els  node
+}
+
+// negateNode is "-term"
+type negateNode struct {
+	term node
+}
+
+// literalNode is a constant number, string, boolean or null
+type literalNode struct {
+	value interface{}
+}
+
+// formatNode is "@name", which formats its input as a string
+type formatNode struct {
+	name   string
+	format formatFunc
+}
+
+// templateNode is a string with interpolations, "text \(query)". Its
+// format, if any, as in @sh "echo \(.msg)", formats the results of the
+// queries; without one they are written as by @text.
+type templateNode struct {
+	format *formatNode
+	parts  []templatePart
+}
+
+// templatePart is literal text or, when query is set, an interpolation
+type templatePart struct {
+	text  string
+	query node
+}
+
+// arrayNode is "[body]", which collects the results of body into an
+// array; a nil body is the empty array
+type arrayNode struct {
+	body node
+}
+
+// objectNode is "{key: value, ...}", which builds a table from every
+// combination of the results of its keys and values
+type objectNode struct {
+	entries []objectEntry
+}
+
+// objectEntry is one "key: value" of an objectNode. A computed key is a
+// parenthesized expression rather than a name or string.
+type objectEntry struct {
+	key      node
+	value    node
+	computed bool
+}
+
+// varNode is a reference to the variable "$name"
+type varNode struct {
+	name string
+}
+
+// bindNode is "source as $name | body"
+type bindNode struct {
+	source node
+	name   string
+	body   node
+}
+
+// reduceNode is "reduce source as $name (init; update)", which folds the
+// results of source into a single value
+type reduceNode struct {
+	source node
+	name   string
+	init   node
+	update node
+}
+
+// foreachNode is "foreach source as $name (init; update; extract)", which
+// emits every intermediate state of a reduction. extract may be nil.
+type foreachNode struct {
+	source  node
+	name    string
+	init    node
+	update  node
+	extract node
+}
+
+// funcDef is a function defined with "def name(params): body;" in the
+// query or in a module. Calls refer to their definition directly.
+type funcDef struct {
+	name   string
+	params []*funcParam
+	body   node
+}
+
+// funcParam is a parameter of a funcDef: a filter "f", run by the body
+// as often as it likes, or a value "$f", which also defines the filter f
+type funcParam struct {
+	name     string
+	variable bool
+}
+
+// defNode is "def ...; rest", which defines a function for rest
+type defNode struct {
+	def  *funcDef
+	rest node
+}
+
+// funcCallNode is a call of a function defined with def
+type funcCallNode struct {
+	name string // as written, including any module prefix
+	def  *funcDef
+	args []node
+}
+
+// paramCallNode is a call of a filter parameter in the body of its
+// function
+type paramCallNode struct {
+	param *funcParam
+}
+
+// moduleNode is a query starting with import and include directives. The
+// definitions of the modules are bound before body runs.
+type moduleNode struct {
+	directives []string
+	defs       []*funcDef
+	body       node
+}
+
+// callNode is a call of a builtin function
+type callNode struct {
+	name string
+	args []node
+	fn   builtinFunc
+}
+
+func (identityNode) String() string {
+	return "."
+}
+
+func (recurseNode) String() string {
+	return ".."
+}
+
+func (n *fieldNode) String() string {
+	return pathPrefix(n.term) + "." + converter.FormatTOMLKey(n.key)
+}
+
+func (n *indexNode) String() string {
+	return termPrefix(n.term) + "[" + n.index.String() + "]"
+}
+
+func (n *sliceNode) String() string {
+	var b strings.Builder
+	b.WriteString(termPrefix(n.term) + "[")
+	if n.start != nil {
+		b.WriteString(n.start.String())
+	}
+	b.WriteByte(':')
+	if n.end != nil {
+		b.WriteString(n.end.String())
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+func (n *iterateNode) String() string {
+	return termPrefix(n.term) + "[]"
+}
+
+func (n *pipeNode) String() string {
+	left := n.left.String()
+	switch n.left.(type) {
+	case *bindNode, *defNode:
+		// The body of a binding or definition would otherwise take in the
+		// right side
+		left = "(" + left + ")"
+	}
+	return left + " | " + n.right.String()
+}
+
+func (n *binaryNode) String() string {
+	prec := binaryPrecedence[n.op]
+	// An operand of equal precedence needs parentheses on the side the
+	// operator does not group from, and on both sides when it cannot chain
+	leftPrec, rightPrec := prec, prec+1
+	switch {
+	case nonAssociative[prec]:
+		leftPrec++
+	case rightAssociative[prec]:
+		leftPrec, rightPrec = prec+1, prec
+	}
+	separator := " " + n.op + " "
+	if n.op == "," {
+		separator = ", "
+	}
+	return operand(n.left, leftPrec) + separator + operand(n.right, rightPrec)
+}
+
+func (n *tryNode) String() string {
+	if n.handler == nil {
+		return termPrefix(n.body) + "?"
+	}
+	return "try " + termPrefix(n.body) + " catch " + termPrefix(n.handler)
+}
+
+func (n *ifNode) String() string {
+	var b strings.Builder
+	b.WriteString("if " + n.cond.String() + " then " + n.then.String())
+	els := n.els
+	for {
+		elif, ok := els.(*ifNode)
+		if !ok {
+			break
+		}
+		b.WriteString(" elif " + elif.cond.String() + " then " + elif.then.String())
+		els = elif.els
+	}
+	if els != nil {
+		b.WriteString(" else " + els.String())
+	}
+	b.WriteString(" end")
+	return b.String()
+}
+
+func (n *negateNode) String() string {
+	return "-" + termPrefix(n.term)
+}
+
+func (n *literalNode) String() string {
+	switch v := n.value.(type) {
+	case bool:
+		return strconv.FormatBool(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case string:
+		return converter.QuoteTOMLString(v)
+	default:
+		return "null"
+	}
+}
+
+func (n *formatNode) String() string {
+	return "@" + n.name
+}
+
+func (n *templateNode) String() string {
+	var b strings.Builder
+	if n.format != nil {
+		b.WriteString(n.format.String() + " ")
+	}
+	b.WriteByte('"')
+	for _, part := range n.parts {
+		if part.query != nil {
+			b.WriteString("\\(" + part.query.String() + ")")
+			continue
+		}
+		quoted := converter.QuoteTOMLString(part.text)
+		b.WriteString(quoted[1 : len(quoted)-1])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func (n *arrayNode) String() string {
+	if n.body == nil {
+		return "[]"
+	}
+	return "[" + n.body.String() + "]"
+}
+
+func (n *objectNode) String() string {
+	entries := make([]string, len(n.entries))
+	for i, e := range n.entries {
+		entries[i] = e.String()
+	}
+	return "{" + strings.Join(entries, ", ") + "}"
+}
+
+// String renders the entry, using the shorthand "key" for "key: .key"
+func (e objectEntry) String() string {
+	if e.computed {
+		return "(" + e.key.String() + "): " + operand(e.value, commaPrecedence+1)
+	}
+	name := e.key.(*literalNode).value.(string)
+	key := name
+	if !isIdentifier(name) {
+		key = converter.QuoteTOMLString(name)
+	}
+	if f, ok := e.value.(*fieldNode); ok && f.key == name && f.term == node(identityNode{}) {
+		return key
+	}
+	if v, ok := e.value.(*varNode); ok && v.name == name {
+		return "$" + name
+	}
+	return key + ": " + operand(e.value, commaPrecedence+1)
+}
+
+func (n *varNode) String() string {
+	return "$" + n.name
+}
+
+func (n *bindNode) String() string {
+	return termPrefix(n.source) + " as $" + n.name + " | " + n.body.String()
+}
+
+func (n *reduceNode) String() string {
+	return "reduce " + termPrefix(n.source) + " as $" + n.name + " (" + n.init.String() + "; " + n.update.String() + ")"
+}
+
+func (n *foreachNode) String() string {
+	s := "foreach " + termPrefix(n.source) + " as $" + n.name + " (" + n.init.String() + "; " + n.update.String()
+	if n.extract != nil {
+		s += "; " + n.extract.String()
+	}
+	return s + ")"
+}
+
+func (n *callNode) String() string {
+	return formatCall(n.name, n.args)
+}
+
+func (n *funcCallNode) String() string {
+	return formatCall(n.name, n.args)
+}
+
+func (n *paramCallNode) String() string {
+	return n.param.name
+}
+
+// formatCall renders a call of name with args
+func formatCall(name string, args []node) string {
+	if len(args) == 0 {
+		return name
+	}
+	rendered := make([]string, len(args))
+	for i, arg := range args {
+		rendered[i] = arg.String()
+	}
+	return name + "(" + strings.Join(rendered, "; ") + ")"
+}
+
+// String renders the definition, including the closing ";"
+func (d *funcDef) String() string {
+	var b strings.Builder
+	b.WriteString("def " + d.name)
+	if len(d.params) > 0 {
+		params := make([]string, len(d.params))
+		for i, param := range d.params {
+			params[i] = param.name
+			if param.variable {
+				params[i] = "$" + param.name
+			}
+		}
+		b.WriteString("(" + strings.Join(params, "; ") + ")")
+	}
+	b.WriteString(": " + d.body.String() + ";")
+	return b.String()
+}
+
+func (n *defNode) String() string {
+	return n.def.String() + " " + n.rest.String()
+}
+
+func (n *moduleNode) String() string {
+	return strings.Join(n.directives, " ") + " " + n.body.String()
+}
+
+// pathPrefix renders the term a ".key" suffix is attached to; the identity
+// contributes nothing because the suffix brings its own dot
+func pathPrefix(term node) string {
+	if _, ok := term.(identityNode); ok {
+		return ""
+	}
+	return termPrefix(term)
+}
+
+// termPrefix renders the term a bracket suffix is attached to, wrapping
+// terms that would otherwise bind differently in parentheses
+func termPrefix(term node) string {
+	return operand(term, termPrecedence)
+}
+
+// operand renders n as the operand of an operator of precedence prec,
+// wrapping it in parentheses when it binds more loosely
+func operand(n node, prec int) string {
+	if precedence(n) < prec {
+		return "(" + n.String() + ")"
+	}
+	return n.String()
+}
+
+// precedence returns how tightly the top-level operator of n binds
+func precedence(n node) int {
+	switch n := n.(type) {
+	case *pipeNode, *bindNode, *defNode, *moduleNode:
+		return 0
+	case *binaryNode:
+		return binaryPrecedence[n.op]
+	case *tryNode:
+		if n.handler != nil {
+			// A suffix after the handler would belong to the handler
+			return termPrecedence - 1
+		}
+		return termPrecedence
+	default:
+		return termPrecedence
+	}
+}
+
+// staticPath returns the path parts of n when n is a plain path made of
+// constant keys, indexes, slices and iterations
+func staticPath(n node) ([]interface{}, bool) {
+	switch n := n.(type) {
+	case identityNode:
+		return []interface{}{}, true
+	case *fieldNode:
+		return appendStatic(n.term, n.key)
+	case *indexNode:
+		lit, ok := n.index.(*literalNode)
+		if !ok {
+			return nil, false
+		}
+		switch v := lit.value.(type) {
+		case string:
+			return appendStatic(n.term, v)
+		case int64:
+			return appendStatic(n.term, int(v))
+		}
+		return nil, false
+	case *sliceNode:
+		var s Slice
+		var ok bool
+		if s.Start, ok = staticBound(n.start); !ok {
+			return nil, false
+		}
+		if s.End, ok = staticBound(n.end); !ok {
+			return nil, false
+		}
+		return appendStatic(n.term, s)
+	case *iterateNode:
+		return appendStatic(n.term, Iterate{})
+	default:
+		return nil, false
+	}
+}
+
+// appendStatic extends the static path of term with part
+func appendStatic(term node, part interface{}) ([]interface{}, bool) {
+	parts, ok := staticPath(term)
+	if !ok {
+		return nil, false
+	}
+	return append(parts, part), true
+}
+
+// staticBound returns the constant value of a slice bound
+func staticBound(n node) (*int, bool) {
+	if n == nil {
+		return nil, true
+	}
+	lit, ok := n.(*literalNode)
+	if !ok {
+		return nil, false
+	}
+	i, ok := lit.value.(int64)
+	if !ok {
+		return nil, false
+	}
+	bound := int(i)
+	return &bound, true
+}
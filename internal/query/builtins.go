@@ -0,0 +1,200 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+// builtinFunc implements a builtin function. Arguments are passed
+// unevaluated so that each builtin decides how to run them.
+type builtinFunc func(env *environment, in interface{}, args []node, emit emitFunc) error
+
+// builtins maps "name/arity" to the implementation of each builtin
+var builtins = map[string]builtinFunc{
+	"keys/0":           valueFunc(funcKeys),
+	"keys_unsorted/0":  valueFunc(funcKeys),
+	"values/0":         funcValues,
+	"length/0":         valueFunc(funcLength),
+	"type/0":           valueFunc(funcType),
+	"toml_type/0":      valueFunc(funcTomlType),
+	"not/0":            valueFunc(funcNot),
+	"select/1":         funcSelect,
+	"has/1":            funcHas,
+	"in/1":             funcIn,
+	"map/1":            funcMap,
+	"map_values/1":     funcMapValues,
+	"to_entries/0":     valueFunc(funcToEntries),
+	"from_entries/0":   valueFunc(funcFromEntries),
+	"with_entries/1":   funcWithEntries,
+	"sort/0":           valueFunc(funcSort),
+	"sort_by/1":        funcSortBy,
+	"group_by/1":       funcGroupBy,
+	"unique/0":         valueFunc(funcUnique),
+	"unique_by/1":      funcUniqueBy,
+	"min_by/1":         extremeBy("min_by", -1),
+	"max_by/1":         extremeBy("max_by", 1),
+	"add/0":            valueFunc(funcAdd),
+	"append/1":         argsFunc(funcAppend),
+	"insert/2":         argsFunc(funcInsert),
+	"any/0":            quantifier(true),
+	"any/1":            quantifier(true),
+	"all/0":            quantifier(false),
+	"all/1":            quantifier(false),
+	"split/1":          argsFunc(funcSplit),
+	"split/2":          argsFunc(funcSplitRegex),
+	"join/1":           argsFunc(funcJoin),
+	"ascii_downcase/0": valueFunc(asciiCase("ascii_downcase", 'A', 'a')),
+	"ascii_upcase/0":   valueFunc(asciiCase("ascii_upcase", 'a', 'A')),
+	"startswith/1":     argsFunc(affixTest("startswith", strings.HasPrefix)),
+	"endswith/1":       argsFunc(affixTest("endswith", strings.HasSuffix)),
+	"ltrimstr/1":       argsFunc(affixTrim(strings.TrimPrefix)),
+	"rtrimstr/1":       argsFunc(affixTrim(strings.TrimSuffix)),
+	"test/1":           funcTest,
+	"test/2":           funcTest,
+	"match/1":          funcMatch,
+	"match/2":          funcMatch,
+	"capture/1":        funcCapture,
+	"capture/2":        funcCapture,
+	"sub/2":            substitute("sub", false),
+	"sub/3":            substitute("sub", false),
+	"gsub/2":           substitute("gsub", true),
+	"gsub/3":           substitute("gsub", true),
+	"floor/0":          valueFunc(roundingFunc("floor", math.Floor)),
+	"ceil/0":           valueFunc(roundingFunc("ceil", math.Ceil)),
+	"round/0":          valueFunc(roundingFunc("round", math.Round)),
+	"sqrt/0":           valueFunc(floatFunc("sqrt", math.Sqrt)),
+	"log/0":            valueFunc(floatFunc("log", math.Log)),
+	"fabs/0":           valueFunc(funcFabs),
+	"pow/2":            argsFunc(funcPow),
+	"limit/2":          funcLimit,
+	"first/0":          valueFunc(funcFirstElement),
+	"first/1":          funcFirst,
+	"last/0":           valueFunc(funcLastElement),
+	"last/1":           funcLast,
+	"range/1":          funcRange,
+	"range/2":          funcRange,
+	"range/3":          funcRange,
+	"until/2":          funcUntil,
+	"while/2":          funcWhile,
+	"path/1":           funcPath,
+	"paths/0":          funcPaths,
+	"paths/1":          funcPaths,
+	"leaf_paths/0":     funcLeafPaths,
+	"recurse/0":        funcRecurse,
+	"recurse/1":        funcRecurse,
+	"recurse/2":        funcRecurse,
+	"walk/1":           funcWalk,
+	"tostream/0":       funcTostream,
+	"fromstream/1":     funcFromstream,
+	"getpath/1":        argsFunc(funcGetpath),
+	"setpath/2":        argsFunc(funcSetpath),
+	"delpaths/1":       argsFunc(funcDelpaths),
+	"del/1":            funcDel,
+	"error/0":          funcError,
+	"error/1":          funcError,
+	"now/0":            valueFunc(funcNow),
+	"todate/0":         valueFunc(funcTodate),
+	"fromdate/0":       valueFunc(funcFromdate),
+	"strftime/1":       argsFunc(funcStrftime),
+	"strptime/1":       argsFunc(funcStrptime),
+	"dateadd/2":        argsFunc(dateArithmetic("dateadd", 1)),
+	"datesub/2":        argsFunc(dateArithmetic("datesub", -1)),
+	"fromjson/0":       valueFunc(fromDocument("fromjson", converter.ParseJSONValue)),
+	"tojson/0":         valueFunc(toDocument("tojson", converter.ConvertToJSON)),
+	"fromtoml/0":       valueFunc(fromDocument("fromtoml", parseTOML)),
+	"totoml/0":         valueFunc(toDocument("totoml", converter.ConvertToTOML)),
+	"fromyaml/0":       valueFunc(fromDocument("fromyaml", converter.ParseYAMLValue)),
+	"toyaml/0":         valueFunc(toDocument("toyaml", converter.ConvertToYAML)),
+}
+
+// lookupBuiltin returns the builtin called name taking arity arguments
+func lookupBuiltin(name string, arity int) (builtinFunc, bool) {
+	fn, ok := builtins[fmt.Sprintf("%s/%d", name, arity)]
+	return fn, ok
+}
+
+// valueFunc adapts a function of the input value alone to a builtin
+func valueFunc(f func(interface{}) (interface{}, error)) builtinFunc {
+	return func(_ *environment, in interface{}, _ []node, emit emitFunc) error {
+		value, err := f(in)
+		if err != nil {
+			return err
+		}
+		return emit(value)
+	}
+}
+
+// argsFunc adapts a function of the input and the values of its arguments
+// to a builtin. The arguments run against the input, and the function runs
+// once for every combination of their results.
+func argsFunc(f func(in interface{}, args []interface{}) (interface{}, error)) builtinFunc {
+	return func(env *environment, in interface{}, args []node, emit emitFunc) error {
+		return evalArgs(env, in, args, nil, func(values []interface{}) error {
+			value, err := f(in, values)
+			if err != nil {
+				return err
+			}
+			return emit(value)
+		})
+	}
+}
+
+// evalArgs calls emit with every combination of the results of args,
+// having already chosen values for the first len(values) of them
+func evalArgs(env *environment, in interface{}, args []node, values []interface{}, emit func([]interface{}) error) error {
+	if len(values) == len(args) {
+		return emit(values)
+	}
+	return args[len(values)].eval(env, in, func(v interface{}) error {
+		// Copy so that sibling combinations do not share a backing array
+		return evalArgs(env, in, args, append(values[:len(values):len(values)], v), emit)
+	})
+}
+
+// funcKeys returns the sorted keys of a table or the indexes of an array.
+// It also implements keys_unsorted: decoded TOML tables do not remember
+// the order of their keys.
+func funcKeys(in interface{}) (interface{}, error) {
+	switch v := in.(type) {
+	case map[string]interface{}:
+		return stringsToValues(sortedKeys(v)), nil
+	case map[interface{}]interface{}:
+		keys := make([]interface{}, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		return keys, nil
+	case []interface{}, []map[string]interface{}:
+		values, _ := iterate(v)
+		result := make([]interface{}, len(values))
+		for i := range values {
+			result[i] = int64(i)
+		}
+		return result, nil
+	default:
+		return nil, fmt.Errorf("%s has no keys", typeName(in))
+	}
+}
+
+// funcNot negates the truthiness of its input
+func funcNot(in interface{}) (interface{}, error) {
+	return !isTruthy(in), nil
+}
+
+// funcSelect passes its input through once for every truthy result of
+// the condition
+func funcSelect(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return args[0].eval(env, in, func(cond interface{}) error {
+		if !isTruthy(cond) {
+			return nil
+		}
+		return emit(in)
+	})
+}
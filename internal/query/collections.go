@@ -0,0 +1,522 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+// collect runs n against in and returns all of its results
+func collect(env *environment, n node, in interface{}) ([]interface{}, error) {
+	var results []interface{}
+	err := n.eval(env, in, func(v interface{}) error {
+		results = append(results, v)
+		return nil
+	})
+	return results, err
+}
+
+// elements returns the elements of an array input for builtins that only
+// accept arrays
+func elements(name string, in interface{}) ([]interface{}, error) {
+	switch in.(type) {
+	case []interface{}, []map[string]interface{}:
+		return iterate(in)
+	default:
+		return nil, fmt.Errorf("%s cannot be applied to %s, expected an array", name, typeName(in))
+	}
+}
+
+// funcLength returns the number of elements of an array, entries of a
+// table or characters of a string, the absolute value of a number and 0
+// for null
+func funcLength(in interface{}) (interface{}, error) {
+	switch v := in.(type) {
+	case nil:
+		return int64(0), nil
+	case string:
+		return int64(utf8.RuneCountInString(v)), nil
+	case []interface{}:
+		return int64(len(v)), nil
+	case []map[string]interface{}:
+		return int64(len(v)), nil
+	case map[string]interface{}:
+		return int64(len(v)), nil
+	case map[interface{}]interface{}:
+		return int64(len(v)), nil
+	case int64:
+		switch {
+		case v >= 0:
+			return v, nil
+		case v == math.MinInt64:
+			// The absolute value does not fit in an int64
+			return -float64(v), nil
+		default:
+			return -v, nil
+		}
+	case int:
+		return funcLength(int64(v))
+	case float64:
+		return math.Abs(v), nil
+	default:
+		return nil, fmt.Errorf("%s has no length", typeName(in))
+	}
+}
+
+// funcType names the type of its input the way jq does; tables are
+// "object" and TOML datetimes, which jq has no type for, are "datetime"
+func funcType(in interface{}) (interface{}, error) {
+	switch name := typeName(in); name {
+	case "table":
+		return "object", nil
+	default:
+		return name, nil
+	}
+}
+
+// funcTomlType names the TOML type of its input from the Go type the
+// decoder produces. [[table]] arrays decode to []map[string]interface{}
+// and other arrays, including arrays of inline tables, to []interface{}.
+// Inline and standard tables decode alike, so both are "table".
+func funcTomlType(in interface{}) (interface{}, error) {
+	switch v := in.(type) {
+	case nil:
+		return "null", nil
+	case int64, int:
+		return "integer", nil
+	case float64:
+		return "float", nil
+	case string:
+		return "string", nil
+	case bool:
+		return "boolean", nil
+	case time.Time:
+		switch converter.KindOf(v) {
+		case localDatetime:
+			return "local-datetime", nil
+		case localDate:
+			return "local-date", nil
+		case localTime:
+			return "local-time", nil
+		default:
+			return "offset-datetime", nil
+		}
+	case []map[string]interface{}:
+		return "array-of-tables", nil
+	case []interface{}:
+		return "array", nil
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "table", nil
+	default:
+		return nil, fmt.Errorf("%s has no TOML type", typeName(in))
+	}
+}
+
+// funcValues keeps its input unless it is null
+func funcValues(_ *environment, in interface{}, _ []node, emit emitFunc) error {
+	if in == nil {
+		return nil
+	}
+	return emit(in)
+}
+
+// hasKey reports whether a table has a string key or an array has an index
+func hasKey(container, key interface{}) (bool, error) {
+	switch c := container.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		k, ok := key.(string)
+		if !ok {
+			return false, fmt.Errorf("cannot check whether a table has a %s key", typeName(key))
+		}
+		_, found := asTable(c)[k]
+		return found, nil
+	case []interface{}, []map[string]interface{}:
+		i, ok := toInt(key)
+		if !ok {
+			return false, fmt.Errorf("cannot check whether an array has a %s key", typeName(key))
+		}
+		values, _ := iterate(c)
+		return i >= 0 && i < len(values), nil
+	default:
+		return false, fmt.Errorf("cannot check whether %s has a key", typeName(container))
+	}
+}
+
+// funcHas reports whether its input has each key
+func funcHas(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return args[0].eval(env, in, func(key interface{}) error {
+		found, err := hasKey(in, key)
+		if err != nil {
+			return err
+		}
+		return emit(found)
+	})
+}
+
+// funcIn reports whether each container has its input as a key
+func funcIn(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return args[0].eval(env, in, func(container interface{}) error {
+		found, err := hasKey(container, in)
+		if err != nil {
+			return err
+		}
+		return emit(found)
+	})
+}
+
+// funcMap collects the results of f for every element or value into an
+// array
+func funcMap(env *environment, in interface{}, args []node, emit emitFunc) error {
+	values, err := iterate(in)
+	if err != nil {
+		return err
+	}
+	result := []interface{}{}
+	for _, value := range values {
+		outputs, err := collect(env, args[0], value)
+		if err != nil {
+			return err
+		}
+		result = append(result, outputs...)
+	}
+	return emit(result)
+}
+
+// funcMapValues replaces every element or value with the first result of
+// f, dropping those for which f produces nothing
+func funcMapValues(env *environment, in interface{}, args []node, emit emitFunc) error {
+	first := func(value interface{}) (interface{}, bool, error) {
+		outputs, err := collect(env, args[0], value)
+		if err != nil || len(outputs) == 0 {
+			return nil, false, err
+		}
+		return outputs[0], true, nil
+	}
+
+	switch v := in.(type) {
+	case map[string]interface{}, map[interface{}]interface{}:
+		result := make(map[string]interface{})
+		for key, value := range asTable(v) {
+			mapped, ok, err := first(value)
+			if err != nil {
+				return err
+			}
+			if ok {
+				result[key] = mapped
+			}
+		}
+		return emit(result)
+	default:
+		values, err := iterate(in)
+		if err != nil {
+			return err
+		}
+		result := []interface{}{}
+		for _, value := range values {
+			mapped, ok, err := first(value)
+			if err != nil {
+				return err
+			}
+			if ok {
+				result = append(result, mapped)
+			}
+		}
+		return emit(result)
+	}
+}
+
+// funcToEntries converts a table to an array of {key, value} tables
+func funcToEntries(in interface{}) (interface{}, error) {
+	keys, err := funcKeys(in)
+	if err != nil {
+		return nil, err
+	}
+	entries := []interface{}{}
+	for _, key := range keys.([]interface{}) {
+		var value interface{}
+		if k, ok := key.(string); ok {
+			value = asTable(in)[k]
+		} else {
+			value, _ = lookupIndex(in, int(key.(int64)))
+		}
+		entries = append(entries, map[string]interface{}{"key": key, "value": value})
+	}
+	return entries, nil
+}
+
+// funcFromEntries builds a table from an array of entries. As in jq, the
+// key may be named key, k, name, Name, K or Key and the value value or v.
+func funcFromEntries(in interface{}) (interface{}, error) {
+	entries, err := elements("from_entries", in)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	for _, entry := range entries {
+		table := asTable(entry)
+		if table == nil {
+			return nil, fmt.Errorf("from_entries expects tables, got %s", typeName(entry))
+		}
+
+		var key interface{}
+		for _, name := range []string{"key", "k", "name", "Name", "K", "Key"} {
+			if key = table[name]; isTruthy(key) {
+				break
+			}
+		}
+		keyString, ok := key.(string)
+		if !ok {
+			if key == nil {
+				return nil, fmt.Errorf("from_entries: entry has no key")
+			}
+			encoded, err := json.Marshal(key)
+			if err != nil {
+				return nil, fmt.Errorf("from_entries: invalid key: %v", err)
+			}
+			keyString = string(encoded)
+		}
+
+		value, found := table["value"]
+		if !found {
+			value = table["v"]
+		}
+		result[keyString] = value
+	}
+	return result, nil
+}
+
+// funcWithEntries applies f to every entry of a table
+func funcWithEntries(env *environment, in interface{}, args []node, emit emitFunc) error {
+	entries, err := funcToEntries(in)
+	if err != nil {
+		return err
+	}
+	return funcMap(env, entries, args, func(mapped interface{}) error {
+		table, err := funcFromEntries(mapped)
+		if err != nil {
+			return err
+		}
+		return emit(table)
+	})
+}
+
+// sortKeyed sorts values by their keys, keeping the order of equal keys
+func sortKeyed(values, keys []interface{}) {
+	indexes := make([]int, len(values))
+	for i := range indexes {
+		indexes[i] = i
+	}
+	sort.SliceStable(indexes, func(i, j int) bool {
+		return compareValues(keys[indexes[i]], keys[indexes[j]]) < 0
+	})
+
+	orderedValues := make([]interface{}, len(values))
+	orderedKeys := make([]interface{}, len(keys))
+	for i, index := range indexes {
+		orderedValues[i], orderedKeys[i] = values[index], keys[index]
+	}
+	copy(values, orderedValues)
+	copy(keys, orderedKeys)
+}
+
+// sortedBy returns a sorted copy of the elements of an array and the sort
+// key of each element; the key is the array of all results of f
+func sortedBy(env *environment, name string, in interface{}, f node) ([]interface{}, []interface{}, error) {
+	items, err := elements(name, in)
+	if err != nil {
+		return nil, nil, err
+	}
+	values := append([]interface{}{}, items...)
+	keys := make([]interface{}, len(values))
+	for i, value := range values {
+		if f == nil {
+			keys[i] = value
+			continue
+		}
+		outputs, err := collect(env, f, value)
+		if err != nil {
+			return nil, nil, err
+		}
+		keys[i] = append([]interface{}{}, outputs...)
+	}
+	sortKeyed(values, keys)
+	return values, keys, nil
+}
+
+// groups splits sorted values into runs of equal keys
+func groups(values, keys []interface{}) [][]interface{} {
+	var result [][]interface{}
+	for i, value := range values {
+		if i == 0 || compareValues(keys[i-1], keys[i]) != 0 {
+			result = append(result, nil)
+		}
+		result[len(result)-1] = append(result[len(result)-1], value)
+	}
+	return result
+}
+
+// funcSort sorts an array in the order of [compareValues]
+func funcSort(in interface{}) (interface{}, error) {
+	values, _, err := sortedBy(nil, "sort", in, nil)
+	return values, err
+}
+
+// funcSortBy sorts an array by the results of f
+func funcSortBy(env *environment, in interface{}, args []node, emit emitFunc) error {
+	values, _, err := sortedBy(env, "sort_by", in, args[0])
+	if err != nil {
+		return err
+	}
+	return emit(values)
+}
+
+// funcGroupBy groups the elements of an array with equal results of f,
+// ordered by those results
+func funcGroupBy(env *environment, in interface{}, args []node, emit emitFunc) error {
+	values, keys, err := sortedBy(env, "group_by", in, args[0])
+	if err != nil {
+		return err
+	}
+	result := []interface{}{}
+	for _, group := range groups(values, keys) {
+		result = append(result, group)
+	}
+	return emit(result)
+}
+
+// funcUnique sorts an array and removes duplicates
+func funcUnique(in interface{}) (interface{}, error) {
+	values, keys, err := sortedBy(nil, "unique", in, nil)
+	if err != nil {
+		return nil, err
+	}
+	result := []interface{}{}
+	for _, group := range groups(values, keys) {
+		result = append(result, group[0])
+	}
+	return result, nil
+}
+
+// funcUniqueBy keeps the first element for every distinct result of f
+func funcUniqueBy(env *environment, in interface{}, args []node, emit emitFunc) error {
+	values, keys, err := sortedBy(env, "unique_by", in, args[0])
+	if err != nil {
+		return err
+	}
+	result := []interface{}{}
+	for _, group := range groups(values, keys) {
+		result = append(result, group[0])
+	}
+	return emit(result)
+}
+
+// extremeBy returns a builtin emitting the element with the smallest
+// (want < 0) or largest (want > 0) result of f, or null for an empty array
+func extremeBy(name string, want int) builtinFunc {
+	return func(env *environment, in interface{}, args []node, emit emitFunc) error {
+		values, _, err := sortedBy(env, name, in, args[0])
+		if err != nil {
+			return err
+		}
+		if len(values) == 0 {
+			return emit(nil)
+		}
+		if want < 0 {
+			return emit(values[0])
+		}
+		// The last of several equal maximums wins, as in jq
+		return emit(values[len(values)-1])
+	}
+}
+
+// funcAdd adds up the elements of an array or the values of a table
+func funcAdd(in interface{}) (interface{}, error) {
+	values, err := iterate(in)
+	if err != nil {
+		return nil, err
+	}
+	var sum interface{}
+	for _, value := range values {
+		if sum, err = addValues(sum, value); err != nil {
+			return nil, err
+		}
+	}
+	return sum, nil
+}
+
+// funcAppend returns its input array with $v added at the end. null is
+// taken as an empty array, so a missing array is created, and an array of
+// tables stays one while v is a table.
+func funcAppend(in interface{}, args []interface{}) (interface{}, error) {
+	items, err := arrayInput("append", in)
+	if err != nil {
+		return nil, err
+	}
+	return fromArray(in, append(items, args[0])), nil
+}
+
+// funcInsert returns its input array with $v inserted before index $i.
+// Negative indexes count from the end, and the length of the array
+// appends; null is taken as an empty array.
+func funcInsert(in interface{}, args []interface{}) (interface{}, error) {
+	items, err := arrayInput("insert", in)
+	if err != nil {
+		return nil, err
+	}
+	i, ok := toInt(args[0])
+	if !ok {
+		return nil, fmt.Errorf("insert index must be a number, got %s", typeName(args[0]))
+	}
+	idx := i
+	if idx < 0 {
+		idx += len(items)
+	}
+	if idx < 0 || idx > len(items) {
+		return nil, fmt.Errorf("insert index %d is out of range for an array of length %d", i, len(items))
+	}
+	items = append(items[:idx], append([]interface{}{args[1]}, items[idx:]...)...)
+	return fromArray(in, items), nil
+}
+
+// arrayInput returns a fresh copy of the elements of the array the
+// builtin name changes, with null as an empty array
+func arrayInput(name string, in interface{}) ([]interface{}, error) {
+	if in == nil {
+		return nil, nil
+	}
+	if _, err := elements(name, in); err != nil {
+		return nil, err
+	}
+	items, _ := toArray(in)
+	return items, nil
+}
+
+// quantifier returns the builtin for any (want true) or all (want false),
+// with an optional condition applied to each element
+func quantifier(want bool) builtinFunc {
+	return func(env *environment, in interface{}, args []node, emit emitFunc) error {
+		values, err := iterate(in)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			conditions := []interface{}{value}
+			if len(args) > 0 {
+				if conditions, err = collect(env, args[0], value); err != nil {
+					return err
+				}
+			}
+			for _, cond := range conditions {
+				if isTruthy(cond) == want {
+					return emit(want)
+				}
+			}
+		}
+		return emit(!want)
+	}
+}
@@ -0,0 +1,184 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// typeOrder ranks the types for ordering values of different types. As in
+// jq, null sorts first and tables last; datetimes sort between numbers and
+// strings.
+func typeOrder(v interface{}) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case bool:
+		if !v {
+			return 1
+		}
+		return 2
+	case int64, int, float64:
+		return 3
+	case time.Time:
+		return 4
+	case string:
+		return 5
+	case []interface{}, []map[string]interface{}:
+		return 6
+	case map[string]interface{}, map[interface{}]interface{}:
+		return 7
+	default:
+		return 8
+	}
+}
+
+// compareValues orders any two query values, returning -1, 0 or 1.
+// Integers and floats compare by numeric value, datetimes as described by
+// compareDatetimes, arrays element by element and tables by their sorted
+// keys first, then by their values.
+func compareValues(a, b interface{}) int {
+	if oa, ob := typeOrder(a), typeOrder(b); oa != ob {
+		return compareInts(int64(oa), int64(ob))
+	}
+
+	switch a := a.(type) {
+	case int64, int, float64:
+		return compareNumbers(a, b)
+	case time.Time:
+		return compareDatetimes(a, b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	case []interface{}, []map[string]interface{}:
+		left, _ := iterate(a)
+		right, _ := iterate(b)
+		for i := 0; i < len(left) && i < len(right); i++ {
+			if c := compareValues(left[i], right[i]); c != 0 {
+				return c
+			}
+		}
+		return compareInts(int64(len(left)), int64(len(right)))
+	case map[string]interface{}, map[interface{}]interface{}:
+		left, right := asTable(a), asTable(b)
+		leftKeys, rightKeys := sortedKeys(left), sortedKeys(right)
+		if c := compareValues(stringsToValues(leftKeys), stringsToValues(rightKeys)); c != 0 {
+			return c
+		}
+		for _, key := range leftKeys {
+			if c := compareValues(left[key], right[key]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	case nil, bool:
+		// The type order already tells null, false and true apart
+		return 0
+	default:
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+}
+
+// compareNumbers compares two numbers, exactly when both are integers
+func compareNumbers(a, b interface{}) int {
+	ia, aInt := toInt64(a)
+	ib, bInt := toInt64(b)
+	if aInt && bInt {
+		return compareInts(ia, ib)
+	}
+
+	fa, fb := toFloat(a), toFloat(b)
+	switch {
+	case math.IsNaN(fa) || math.IsNaN(fb):
+		// NaN sorts below every number, as in jq
+		if math.IsNaN(fa) && math.IsNaN(fb) {
+			return 0
+		}
+		if math.IsNaN(fa) {
+			return -1
+		}
+		return 1
+	case fa < fb:
+		return -1
+	case fa > fb:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func compareInts(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// toInt64 returns the value of an integer
+func toInt64(v interface{}) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// toFloat returns the value of a number as a float
+func toFloat(v interface{}) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	default:
+		return math.NaN()
+	}
+}
+
+// asTable returns a table with string keys, converting the keys of
+// map[interface{}]interface{} tables
+func asTable(v interface{}) map[string]interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		return t
+	case map[interface{}]interface{}:
+		table := make(map[string]interface{}, len(t))
+		for key, value := range t {
+			table[fmt.Sprint(key)] = value
+		}
+		return table
+	default:
+		return nil
+	}
+}
+
+// stringsToValues converts a list of strings to a query array
+func stringsToValues(items []string) []interface{} {
+	values := make([]interface{}, len(items))
+	for i, item := range items {
+		values[i] = item
+	}
+	return values
+}
+
+// isTruthy reports whether a value counts as true in conditions: every
+// value except false and null
+func isTruthy(v interface{}) bool {
+	switch v := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return v
+	default:
+		return true
+	}
+}
@@ -0,0 +1,679 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+// datetimeKind is one of the four TOML datetime types, as told apart by
+// the converter package
+type datetimeKind = converter.DatetimeKind
+
+const (
+	offsetDatetime = converter.OffsetDatetime
+	localDatetime  = converter.LocalDatetime
+	localDate      = converter.LocalDate
+	localTime      = converter.LocalTime
+)
+
+// The TOML decoder marks local datetimes, dates and times with locations of
+// its own, and the encoder recognizes them by identity rather than by
+// name. Decoding a sample document is the only way to get hold of them, so
+// that values built by queries are written back with the right type. The
+// sample decodes into interface values because time.Time fields go through
+// UnmarshalText, which loses the markers.
+var localDatetimeZone, localDateZone, localTimeZone = tomlLocalZones()
+
+func tomlLocalZones() (datetime, date, clock *time.Location) {
+	var sample map[string]interface{}
+	if _, err := toml.Decode("datetime = 2000-01-01T00:00:00\ndate = 2000-01-01\ntime = 00:00:00", &sample); err != nil {
+		panic(err)
+	}
+	zone := func(key string) *time.Location { return sample[key].(time.Time).Location() }
+	return zone("datetime"), zone("date"), zone("time")
+}
+
+// makeDatetime builds a datetime of the given kind from its wall clock.
+// offset is only used for offset datetimes.
+func makeDatetime(kind datetimeKind, year int, month time.Month, day, hour, min, sec, nsec int, offset *time.Location) time.Time {
+	switch kind {
+	case localDatetime:
+		return time.Date(year, month, day, hour, min, sec, nsec, localDatetimeZone)
+	case localDate:
+		return time.Date(year, month, day, 0, 0, 0, 0, localDateZone)
+	case localTime:
+		// The decoder puts local times on the first day of year 0
+		return time.Date(0, time.January, 1, hour, min, sec, nsec, localTimeZone)
+	default:
+		return time.Date(year, month, day, hour, min, sec, nsec, offset)
+	}
+}
+
+// wallClock returns the date and time of day of t, dropping its location
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// compareDatetimes orders two datetimes. Offset datetimes compare as
+// instants and local values by their wall clock. Datetimes of different
+// kinds have no meaningful order, so they sort by kind: offset datetimes,
+// local datetimes, local dates, then local times.
+func compareDatetimes(a, b time.Time) int {
+	ka, kb := converter.KindOf(a), converter.KindOf(b)
+	if ka != kb {
+		return compareInts(int64(ka), int64(kb))
+	}
+	if ka == offsetDatetime {
+		return a.Compare(b)
+	}
+	return wallClock(a).Compare(wallClock(b))
+}
+
+// checkOrderable rejects ordering datetimes of different kinds with the
+// comparison operators, where sorting by kind would silently give a
+// meaningless answer
+func checkOrderable(left, right interface{}) error {
+	a, ok := left.(time.Time)
+	if !ok {
+		return nil
+	}
+	b, ok := right.(time.Time)
+	if !ok {
+		return nil
+	}
+	if ka, kb := converter.KindOf(a), converter.KindOf(b); ka != kb {
+		return fmt.Errorf("cannot compare %s with %s", ka, kb)
+	}
+	return nil
+}
+
+// datetimeInput returns the input of a datetime builtin
+func datetimeInput(name string, in interface{}) (time.Time, error) {
+	t, ok := in.(time.Time)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%s cannot be applied to %s, expected a datetime", name, typeName(in))
+	}
+	return t, nil
+}
+
+// unixTime converts Unix seconds to an offset datetime in UTC
+func unixTime(v interface{}) time.Time {
+	if secs, ok := toInt64(v); ok {
+		return time.Unix(secs, 0).UTC()
+	}
+	whole, frac := math.Modf(toFloat(v))
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
+
+// funcNow returns the current time, to the second, as an offset datetime
+// in UTC
+func funcNow(interface{}) (interface{}, error) {
+	return time.Now().UTC().Truncate(time.Second), nil
+}
+
+// funcTodate converts Unix seconds and strings in TOML datetime syntax to
+// datetimes. Datetimes pass through unchanged.
+func funcTodate(in interface{}) (interface{}, error) {
+	switch v := in.(type) {
+	case time.Time:
+		return v, nil
+	case string:
+		return parseDatetime(v)
+	default:
+		if !isNumber(in) {
+			return nil, fmt.Errorf("todate cannot be applied to %s, expected a number, string or datetime", typeName(in))
+		}
+		return unixTime(in), nil
+	}
+}
+
+// datetimeLayouts are the datetime syntaxes of TOML, each with the kind
+// of value it denotes
+var datetimeLayouts = []struct {
+	layout string
+	kind   datetimeKind
+}{
+	{"2006-01-02T15:04:05.999999999Z07:00", offsetDatetime},
+	{"2006-01-02T15:04Z07:00", offsetDatetime},
+	{"2006-01-02T15:04:05.999999999", localDatetime},
+	{"2006-01-02T15:04", localDatetime},
+	{"2006-01-02", localDate},
+	{"15:04:05.999999999", localTime},
+	{"15:04", localTime},
+}
+
+// parseDatetime parses a string in TOML datetime syntax, which allows a
+// space in place of the "T" and lower case "t" and "z"
+func parseDatetime(s string) (time.Time, error) {
+	normalized := strings.ToUpper(s)
+	if len(normalized) > 10 && normalized[10] == ' ' {
+		normalized = normalized[:10] + "T" + normalized[11:]
+	}
+	for _, l := range datetimeLayouts {
+		t, err := time.Parse(l.layout, normalized)
+		if err != nil {
+			continue
+		}
+		if l.kind == offsetDatetime {
+			return t, nil
+		}
+		return makeDatetime(l.kind, t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), nil), nil
+	}
+	return time.Time{}, fmt.Errorf("cannot parse %q as a TOML datetime", s)
+}
+
+// funcFromdate converts an offset datetime, or a string in TOML datetime
+// syntax, to Unix seconds. Local values have no offset and so no Unix time.
+func funcFromdate(in interface{}) (interface{}, error) {
+	var t time.Time
+	switch v := in.(type) {
+	case time.Time:
+		t = v
+	case string:
+		var err error
+		if t, err = parseDatetime(v); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("fromdate cannot be applied to %s, expected a string or datetime", typeName(in))
+	}
+	if kind := converter.KindOf(t); kind != offsetDatetime {
+		return nil, fmt.Errorf("fromdate cannot convert a %s to Unix time, it has no offset", kind)
+	}
+	if t.Nanosecond() == 0 {
+		return t.Unix(), nil
+	}
+	return float64(t.UnixNano()) / 1e9, nil
+}
+
+// dateUnits are the units dateadd and datesub accept, by singular name.
+// Calendar units move the date and keep the time of day.
+var dateUnits = map[string]struct {
+	duration     time.Duration
+	months, days int
+	calendar     bool
+}{
+	"second": {duration: time.Second},
+	"minute": {duration: time.Minute},
+	"hour":   {duration: time.Hour},
+	"day":    {days: 1, calendar: true},
+	"week":   {days: 7, calendar: true},
+	"month":  {months: 1, calendar: true},
+	"year":   {months: 12, calendar: true},
+}
+
+// dateArithmetic builds dateadd (sign 1) and datesub (sign -1), which move
+// a datetime by a number of units without changing its kind
+func dateArithmetic(name string, sign int) func(interface{}, []interface{}) (interface{}, error) {
+	return func(in interface{}, args []interface{}) (interface{}, error) {
+		t, err := datetimeInput(name, in)
+		if err != nil {
+			return nil, err
+		}
+		unitName, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		unit, ok := dateUnits[strings.TrimSuffix(unitName, "s")]
+		if !ok {
+			return nil, fmt.Errorf("%s: unknown unit %q, expected seconds, minutes, hours, days, weeks, months or years", name, unitName)
+		}
+		if !isNumber(args[1]) {
+			return nil, fmt.Errorf("%s expects a number of %s, got %s", name, unitName, typeName(args[1]))
+		}
+		kind := converter.KindOf(t)
+
+		if !unit.calendar {
+			if !kind.HasTime() {
+				return nil, fmt.Errorf("%s cannot add %s to a %s", name, unitName, kind)
+			}
+			moved := t.Add(time.Duration(float64(sign) * toFloat(args[1]) * float64(unit.duration)))
+			if kind == localTime {
+				// Wrap around midnight rather than leave the day of local times
+				return makeDatetime(kind, 0, 0, 0, moved.Hour(), moved.Minute(), moved.Second(), moved.Nanosecond(), nil), nil
+			}
+			return moved, nil
+		}
+
+		if !kind.HasDate() {
+			return nil, fmt.Errorf("%s cannot add %s to a %s", name, unitName, kind)
+		}
+		n, ok := toInt64(args[1])
+		if !ok {
+			if f := toFloat(args[1]); f != math.Trunc(f) {
+				return nil, fmt.Errorf("%s expects a whole number of %s, got %v", name, unitName, f)
+			}
+			n = int64(toFloat(args[1]))
+		}
+		n *= int64(sign)
+		return addCalendar(t, int(n)*unit.months, int(n)*unit.days), nil
+	}
+}
+
+// addCalendar moves t by months and days. Unlike time.AddDate, a day that
+// does not exist in the target month becomes its last day, so January 31
+// plus a month is the end of February rather than early March.
+func addCalendar(t time.Time, months, days int) time.Time {
+	if months != 0 {
+		first := time.Date(t.Year(), t.Month(), 1, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+		first = first.AddDate(0, months, 0)
+		last := first.AddDate(0, 1, -1).Day()
+		t = first.AddDate(0, 0, min(t.Day(), last)-1)
+	}
+	return t.AddDate(0, 0, days)
+}
+
+// funcStrftime formats a datetime, or Unix seconds, with C strftime
+// conversions
+func funcStrftime(in interface{}, args []interface{}) (interface{}, error) {
+	format, err := stringArg("strftime", args[0])
+	if err != nil {
+		return nil, err
+	}
+	t, ok := in.(time.Time)
+	switch {
+	case ok:
+	case isNumber(in):
+		t = unixTime(in)
+	default:
+		return nil, fmt.Errorf("strftime cannot be applied to %s, expected a datetime or number", typeName(in))
+	}
+	var b strings.Builder
+	if err := formatStrftime(&b, t, format); err != nil {
+		return nil, err
+	}
+	return b.String(), nil
+}
+
+// datetimePart is what a conversion needs from a datetime
+type datetimePart int
+
+const (
+	noPart datetimePart = iota
+	datePart
+	timePart
+	offsetPart
+)
+
+// strftimeConversions format one field of a datetime each
+var strftimeConversions = map[byte]struct {
+	part   datetimePart
+	format func(t time.Time) string
+}{
+	'Y': {datePart, func(t time.Time) string { return fmt.Sprintf("%04d", t.Year()) }},
+	'C': {datePart, func(t time.Time) string { return fmt.Sprintf("%02d", t.Year()/100) }},
+	'y': {datePart, func(t time.Time) string { return fmt.Sprintf("%02d", t.Year()%100) }},
+	'm': {datePart, func(t time.Time) string { return fmt.Sprintf("%02d", int(t.Month())) }},
+	'd': {datePart, func(t time.Time) string { return fmt.Sprintf("%02d", t.Day()) }},
+	'e': {datePart, func(t time.Time) string { return fmt.Sprintf("%2d", t.Day()) }},
+	'j': {datePart, func(t time.Time) string { return fmt.Sprintf("%03d", t.YearDay()) }},
+	'a': {datePart, func(t time.Time) string { return t.Weekday().String()[:3] }},
+	'A': {datePart, func(t time.Time) string { return t.Weekday().String() }},
+	'b': {datePart, func(t time.Time) string { return t.Month().String()[:3] }},
+	'h': {datePart, func(t time.Time) string { return t.Month().String()[:3] }},
+	'B': {datePart, func(t time.Time) string { return t.Month().String() }},
+	'u': {datePart, func(t time.Time) string { return strconv.Itoa((int(t.Weekday())+6)%7 + 1) }},
+	'w': {datePart, func(t time.Time) string { return strconv.Itoa(int(t.Weekday())) }},
+	'H': {timePart, func(t time.Time) string { return fmt.Sprintf("%02d", t.Hour()) }},
+	'I': {timePart, func(t time.Time) string { return fmt.Sprintf("%02d", (t.Hour()+11)%12+1) }},
+	'M': {timePart, func(t time.Time) string { return fmt.Sprintf("%02d", t.Minute()) }},
+	'S': {timePart, func(t time.Time) string { return fmt.Sprintf("%02d", t.Second()) }},
+	'p': {timePart, func(t time.Time) string { return t.Format("PM") }},
+	'z': {offsetPart, func(t time.Time) string { return t.Format("-0700") }},
+	'Z': {offsetPart, func(t time.Time) string { return t.Format("MST") }},
+	's': {offsetPart, func(t time.Time) string { return strconv.FormatInt(t.Unix(), 10) }},
+	'n': {noPart, func(time.Time) string { return "\n" }},
+	't': {noPart, func(time.Time) string { return "\t" }},
+	'%': {noPart, func(time.Time) string { return "%" }},
+}
+
+// dateShorthands are conversions that stand for a sequence of others
+var dateShorthands = map[byte]string{
+	'F': "%Y-%m-%d",
+	'D': "%m/%d/%y",
+	'T': "%H:%M:%S",
+	'R': "%H:%M",
+	'c': "%a %b %e %H:%M:%S %Y",
+}
+
+// formatStrftime writes t formatted with format to b. A conversion needs
+// the part of the datetime it formats, so local dates have no hours and
+// local values no offset.
+func formatStrftime(b *strings.Builder, t time.Time, format string) error {
+	kind := converter.KindOf(t)
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		if i == len(format) {
+			return fmt.Errorf("strftime format %q ends with a lone %%", format)
+		}
+		c := format[i]
+		if shorthand, ok := dateShorthands[c]; ok {
+			if err := formatStrftime(b, t, shorthand); err != nil {
+				return err
+			}
+			continue
+		}
+		conv, ok := strftimeConversions[c]
+		if !ok {
+			return fmt.Errorf("strftime format %q uses unsupported conversion %%%c", format, c)
+		}
+		if err := checkPart(kind, conv.part, c); err != nil {
+			return err
+		}
+		b.WriteString(conv.format(t))
+	}
+	return nil
+}
+
+// checkPart reports an error when a datetime of the given kind lacks part
+func checkPart(kind datetimeKind, part datetimePart, conv byte) error {
+	switch {
+	case part == datePart && !kind.HasDate():
+		return fmt.Errorf("strftime: %%%c needs a date, got a %s", conv, kind)
+	case part == timePart && !kind.HasTime():
+		return fmt.Errorf("strftime: %%%c needs a time of day, got a %s", conv, kind)
+	case part == offsetPart && kind != offsetDatetime:
+		return fmt.Errorf("strftime: %%%c needs an offset, got a %s", conv, kind)
+	}
+	return nil
+}
+
+// funcStrptime parses a string with C strptime conversions. The kind of
+// the result follows from the conversions: a zone makes an offset
+// datetime, and a date, a time of day or both make the matching local
+// value.
+func funcStrptime(in interface{}, args []interface{}) (interface{}, error) {
+	s, err := stringInput("strptime", in)
+	if err != nil {
+		return nil, err
+	}
+	format, err := stringArg("strptime", args[0])
+	if err != nil {
+		return nil, err
+	}
+	p := &timeParser{input: s, month: 1, day: 1}
+	if err := p.parse(format); err != nil {
+		return nil, err
+	}
+	if p.pos < len(s) {
+		return nil, fmt.Errorf("date %q does not match format %q", s, format)
+	}
+	return p.result(s, format)
+}
+
+// timeParser holds the fields strptime has read so far
+type timeParser struct {
+	input string
+	pos   int
+
+	year, month, day     int
+	hour, minute, second int
+	pm                   *bool
+	offset               *int
+	unix                 *int64
+	hasDate, hasTime     bool
+}
+
+// parse reads the input at the current position against format
+func (p *timeParser) parse(format string) error {
+	mismatch := fmt.Errorf("date %q does not match format %q", p.input, format)
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		switch {
+		case isSpace(c):
+			p.skipSpace()
+			continue
+		case c != '%':
+			if p.pos == len(p.input) || p.input[p.pos] != c {
+				return mismatch
+			}
+			p.pos++
+			if c == 'Z' && p.offset == nil {
+				// A literal Z, as in "%Y-%m-%dT%H:%M:%SZ", marks UTC
+				p.offset = new(int)
+			}
+			continue
+		}
+
+		i++
+		if i == len(format) {
+			return fmt.Errorf("strptime format %q ends with a lone %%", format)
+		}
+		c = format[i]
+		if shorthand, ok := dateShorthands[c]; ok {
+			if err := p.parse(shorthand); err != nil {
+				return mismatch
+			}
+			continue
+		}
+		if err := p.convert(c); err != nil {
+			if err == errMismatch {
+				return mismatch
+			}
+			return fmt.Errorf("strptime format %q %v", format, err)
+		}
+	}
+	return nil
+}
+
+// errMismatch is returned by convert when the input does not fit
+var errMismatch = errors.New("input does not match")
+
+// convert reads the field of one conversion
+func (p *timeParser) convert(c byte) error {
+	var ok bool
+	switch c {
+	case 'Y':
+		p.year, ok = p.number(4, 0, 9999)
+		p.hasDate = true
+	case 'y':
+		p.year, ok = p.number(2, 0, 99)
+		// POSIX puts 69-99 in the 1900s and 00-68 in the 2000s
+		if p.year < 69 {
+			p.year += 2000
+		} else {
+			p.year += 1900
+		}
+		p.hasDate = true
+	case 'm':
+		p.month, ok = p.number(2, 1, 12)
+		p.hasDate = true
+	case 'd', 'e':
+		p.skipSpace()
+		p.day, ok = p.number(2, 1, 31)
+		p.hasDate = true
+	case 'b', 'h', 'B':
+		var month int
+		month, ok = p.name(func(i int) string { return time.Month(i + 1).String() }, 12)
+		p.month = month + 1
+		p.hasDate = true
+	case 'a', 'A':
+		_, ok = p.name(func(i int) string { return time.Weekday(i).String() }, 7)
+	case 'H':
+		p.hour, ok = p.number(2, 0, 23)
+		p.hasTime = true
+	case 'I':
+		p.hour, ok = p.number(2, 1, 12)
+		p.hasTime = true
+	case 'M':
+		p.minute, ok = p.number(2, 0, 59)
+		p.hasTime = true
+	case 'S':
+		p.second, ok = p.number(2, 0, 60)
+		p.hasTime = true
+	case 'p':
+		var i int
+		i, ok = p.name(func(i int) string { return [...]string{"AM", "PM"}[i] }, 2)
+		pm := i == 1
+		p.pm = &pm
+	case 'z':
+		ok = p.zoneOffset()
+	case 'Z':
+		ok = p.zoneName()
+	case 's':
+		var secs int64
+		secs, ok = p.unixSeconds()
+		p.unix = &secs
+	case 'n', 't':
+		p.skipSpace()
+		ok = true
+	case '%':
+		ok = p.pos < len(p.input) && p.input[p.pos] == '%'
+		p.pos++
+	default:
+		return fmt.Errorf("uses unsupported conversion %%%c", c)
+	}
+	if !ok {
+		return errMismatch
+	}
+	return nil
+}
+
+// number reads up to width digits with a value between lo and hi
+func (p *timeParser) number(width, lo, hi int) (int, bool) {
+	start := p.pos
+	for p.pos < len(p.input) && p.pos-start < width && isDigit(p.input[p.pos]) {
+		p.pos++
+	}
+	if p.pos == start {
+		return 0, false
+	}
+	n, _ := strconv.Atoi(p.input[start:p.pos])
+	return n, n >= lo && n <= hi
+}
+
+// name reads one of count names, full or abbreviated to three letters,
+// ignoring case, and returns its index
+func (p *timeParser) name(names func(int) string, count int) (int, bool) {
+	rest := strings.ToLower(p.input[p.pos:])
+	for i := 0; i < count; i++ {
+		full := strings.ToLower(names(i))
+		for _, candidate := range []string{full, full[:min(3, len(full))]} {
+			if strings.HasPrefix(rest, candidate) {
+				p.pos += len(candidate)
+				return i, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// zoneOffset reads "Z" or an offset of the form +hhmm or +hh:mm
+func (p *timeParser) zoneOffset() bool {
+	rest := p.input[p.pos:]
+	if strings.HasPrefix(rest, "Z") || strings.HasPrefix(rest, "z") {
+		p.pos++
+		p.offset = new(int)
+		return true
+	}
+	if rest == "" || (rest[0] != '+' && rest[0] != '-') {
+		return false
+	}
+	sign := 1
+	if rest[0] == '-' {
+		sign = -1
+	}
+	p.pos++
+	hours, ok := p.number(2, 0, 23)
+	if !ok {
+		return false
+	}
+	if p.pos < len(p.input) && p.input[p.pos] == ':' {
+		p.pos++
+	}
+	minutes, ok := p.number(2, 0, 59)
+	if !ok {
+		return false
+	}
+	offset := sign * (hours*3600 + minutes*60)
+	p.offset = &offset
+	return true
+}
+
+// zoneName reads the name of a zone. Only the names of UTC are known;
+// other abbreviations are ambiguous.
+func (p *timeParser) zoneName() bool {
+	for _, name := range []string{"UTC", "GMT", "Z"} {
+		if strings.HasPrefix(strings.ToUpper(p.input[p.pos:]), name) {
+			p.pos += len(name)
+			p.offset = new(int)
+			return true
+		}
+	}
+	return false
+}
+
+// unixSeconds reads a signed number of seconds since the Unix epoch
+func (p *timeParser) unixSeconds() (int64, bool) {
+	start := p.pos
+	if p.pos < len(p.input) && p.input[p.pos] == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.input) && isDigit(p.input[p.pos]) {
+		p.pos++
+	}
+	secs, err := strconv.ParseInt(p.input[start:p.pos], 10, 64)
+	return secs, err == nil
+}
+
+func (p *timeParser) skipSpace() {
+	for p.pos < len(p.input) && isSpace(p.input[p.pos]) {
+		p.pos++
+	}
+}
+
+// result builds the datetime the parsed fields describe
+func (p *timeParser) result(s, format string) (interface{}, error) {
+	if p.unix != nil {
+		return time.Unix(*p.unix, 0).UTC(), nil
+	}
+	if p.pm != nil {
+		p.hour %= 12
+		if *p.pm {
+			p.hour += 12
+		}
+	}
+
+	var kind datetimeKind
+	switch {
+	case p.offset != nil && !p.hasDate:
+		return nil, fmt.Errorf("strptime format %q has a zone but no date", format)
+	case p.offset != nil:
+		kind = offsetDatetime
+	case p.hasDate && p.hasTime:
+		kind = localDatetime
+	case p.hasDate:
+		kind = localDate
+	case p.hasTime:
+		kind = localTime
+	default:
+		return nil, fmt.Errorf("strptime format %q has no date or time conversions", format)
+	}
+
+	zone := time.UTC
+	if p.offset != nil && *p.offset != 0 {
+		zone = time.FixedZone("", *p.offset)
+	}
+	t := makeDatetime(kind, p.year, time.Month(p.month), p.day, p.hour, p.minute, p.second, 0, zone)
+	if kind.HasDate() && t.Day() != p.day {
+		// time.Date moved an impossible date such as February 30 into the
+		// next month
+		return nil, fmt.Errorf("date %q is not a valid date", s)
+	}
+	return t, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
@@ -1,7 +1,7 @@
 // Package query provides TOML query functionality for tmq.
 //
-// This package handles parsing and executing dot-separated path queries
-// to extract values from TOML data structures.
+// This package compiles jq-style queries into an expression tree and runs
+// them against TOML data structures.
 //
 // # Basic Usage
 //
@@ -11,11 +11,11 @@
 //	if err != nil {
 //		log.Fatal(err)
 //	}
-//	result, err := q.Execute(tomlData)
+//	results, err := q.Execute(tomlData)
 //	if err != nil {
 //		log.Fatal(err)
 //	}
-//	fmt.Println(result)
+//	fmt.Println(results[0])
 //
 // # Query Syntax
 //
@@ -24,6 +24,186 @@
 //   - ".key" - access top-level key
 //   - ".nested.key" - access nested key
 //   - ".array" - access array values
+//   - ".array[0]" - access an array element by index
+//   - ".array[-1]" - negative indexes count from the end
+//   - ".array[1:3]" - slice an array (end exclusive, bounds optional)
+//   - ".array[]" - every element of an array
+//   - ".table.*" - every value of a table, in key order
+//
+// Keys follow the TOML key grammar: bare keys (A-Za-z0-9_-), basic quoted
+// keys with escapes and literal quoted keys, e.g. ."example.com".port or
+// .tool.'black config'.
+//
+// Indexing works on plain arrays and on arrays of tables ([[table]]) alike.
+// Because of iteration a query produces a stream of results, so
+// [Query.Execute] returns a slice:
+//
+//	q, _ := query.New(".servers[].host")
+//	hosts, err := q.Execute(tomlData)
+//
+// # Pipes and Functions
+//
+// "|" runs the filter on its right once for every result of the filter on
+// its left, and parentheses group filters:
+//
+//   - ".tool.poetry | .dependencies | keys" - sorted dependency names
+//   - "(.tool | .poetry).name" - a path applied to a group
+//   - ".ports[.index]" - an index computed from the input
+//
+// Builtin functions are called by name, with arguments separated by ";".
+// The collection builtins follow jq: keys, keys_unsorted, values, length,
+// type, has(k), in(o), map(f), map_values(f), to_entries, from_entries,
+// with_entries(f), sort, sort_by(f), group_by(f), unique, unique_by(f),
+// min_by(f), max_by(f), add, any and all. Decoded TOML tables do not keep
+// their key order, so keys_unsorted returns sorted keys too, and type names
+// tables "object" and datetimes "datetime". toml_type names the exact TOML
+// type instead: integer, float, string, boolean, offset-datetime,
+// local-datetime, local-date, local-time, array, array-of-tables or table.
+// append(v) and insert(i; v) add an element to an array, keeping arrays of
+// tables intact, and treat null as an empty array.
+//
+// The string builtins are split, join, ascii_downcase, ascii_upcase,
+// startswith, endswith, ltrimstr and rtrimstr. The regex builtins test,
+// match, capture, sub, gsub and split/2 use RE2 syntax and accept the jq
+// flags g, i, s, m, n and l:
+//
+//	.project.version | split(".") | .[0]
+//	.dependencies | keys[] | select(test("^py"))
+//
+// "\(q)" inside a double-quoted string is replaced by each result of q:
+// strings as they are, datetimes in TOML form and other values as JSON. The
+// formats @text, @json, @html, @uri, @csv, @tsv, @sh, @base64 and @base64d
+// write their input as a string, and before a string they format its
+// interpolations:
+//
+//	"\(.host):\(.port)"
+//	@sh "echo \(.message)"
+//
+// fromjson, fromtoml and fromyaml parse a string embedded in the data, and
+// tojson, totoml and toyaml serialize a value as the matching output format
+// of the command line does:
+//
+//	.settings | fromjson | .retries
+//
+// # Building Tables and Arrays
+//
+// "," produces the results of the filter on its left and then those of the
+// filter on its right. "[f]" collects every result of f into an array and
+// "{key: f, ...}" builds a table:
+//
+//	[.servers[].host]
+//	{name: .project.name, version: .project.version}
+//
+// Keys are names, quoted strings or parenthesized filters producing
+// strings, as in {(.k): .v}. A name or string on its own is short for
+// "key: .key", so .project | {name, version} keeps two keys. When a value
+// produces several results, the table is built once for each of them.
+//
+// # Variables
+//
+// "expr as $name | body" runs body once for every result of expr, with
+// $name bound to it. The body receives the original input and extends to
+// the end of the enclosing pipe:
+//
+//	.limits.min as $min | .servers[] | select(.port > $min)
+//
+// [NewWithVariables] defines variables from outside the query, which is
+// how the tmq --arg flags pass values without quoting them into the query.
+// Undefined variables are syntax errors.
+//
+// # Reductions and Generators
+//
+// "reduce source as $x (init; update)" folds the results of source into a
+// single value, and foreach emits every intermediate state:
+//
+//	reduce .items[] as $i (0; . + $i.size)
+//
+// limit(n; f), first(f) and last(f) pick results of f, stopping f early
+// where they can. range, until and while generate sequences of values.
+//
+// # Paths
+//
+// path(f) returns where the results of f are in the input, as an array of
+// keys and indexes such as ["servers", 0, "host"]. paths, leaf_paths,
+// getpath, setpath, delpaths and del work with such paths. [Query.Paths]
+// runs a whole query this way and returns parts like those of
+// [Query.Parts], which [SetPath] and [DeletePaths] apply to data; the
+// modifier package is built on them.
+//
+// Assignments change the values a path expression selects and return the
+// updated input. "lhs = v" stores v, "lhs |= f" runs f against each
+// value, and "+=", "-=", "*=", "/=", "%=" and "//=" combine each value
+// with the right operand. [AssignmentOperator] tells assignments from
+// other queries without parsing their right-hand side:
+//
+//	.servers[].port |= . + 1
+//	.timeout //= 30
+//
+// ".." and recurse produce the input and every value inside it, so that a
+// query reaches keys at any depth; recurse(f) and recurse(f; cond) follow
+// f instead. walk(f) rewrites a value bottom-up, and tostream and
+// fromstream convert between values and [path, leaf] events:
+//
+//	walk(if type == "string" then ascii_downcase else . end)
+//	[.. | select(type == "object" and has("password")) | .password]
+//
+// # Functions and Modules
+//
+// "def name(params): body;" defines a function for the rest of the pipe.
+// A parameter f is a filter the body may run any number of times; $f is a
+// value the body runs once for every result of the argument:
+//
+//	def above($n): select(.port > $n); .servers[] | above(1024)
+//
+// Functions see the definitions and variables in scope where they are
+// defined and may call themselves. Shared definitions live in module files
+// ending in .tmq, loaded by "import "path" as name;", which makes them
+// callable as name::f, or by "include "path";". [NewWithOptions] sets the
+// directories modules are searched in.
+//
+// # Arithmetic
+//
+// +, -, *, / and % work on numbers. When both operands are TOML integers
+// the result stays an int64, unless it overflows or a division is inexact,
+// so that it can be written back as an integer. + also concatenates strings
+// and arrays and merges tables, - removes array elements, * deep-merges
+// tables and / splits strings. The math builtins are floor, ceil, round
+// (which return integers), sqrt, log, fabs and pow(x; y).
+//
+// # Dates and Times
+//
+// The four TOML datetime types all decode to time.Time; the builtins tell
+// them apart by location and keep them apart. now is an offset datetime,
+// todate makes datetimes from Unix seconds and TOML datetime strings,
+// fromdate turns offset datetimes into Unix seconds, dateadd(unit; n) and
+// datesub(unit; n) move a datetime without changing its type, and
+// strftime(fmt) and strptime(fmt) use C conversions:
+//
+//	.certs[] | select(.expires < (now | dateadd("days"; 30))) | .name
+//
+// Offset datetimes compare as instants and local values by their wall
+// clock. The ordering operators reject datetimes of different types.
+//
+// # Conditions
+//
+// select(cond) passes its input through when cond is true. Conditions are
+// built from ==, !=, <, <=, >, >=, and, or and not; false and null are
+// false and every other value is true:
+//
+//	.servers[] | select(.port > 1024 and .enabled) | .name
+//
+// "if cond then a elif cond2 then b else c end" chooses between queries;
+// without else the input passes through.
+//
+// error(value) stops the query with a [UserError] carrying value, so that
+// callers can tell failures the query reports from its own. "try body catch
+// handler" runs handler against the error that stops body: the raised
+// value, or the message of any other error. "try body" and "body?" drop
+// the error.
+//
+// Values of different types are ordered null, false, true, numbers,
+// datetimes, strings, arrays, tables. Integers compare exactly, mixed
+// integers and floats by value.
 //
 // # Supported Data Types
 //
@@ -39,7 +219,17 @@
 // Query execution may fail if:
 //   - The path doesn't exist in the data
 //   - The data structure is incompatible with the query
-//   - The path syntax is invalid
 //
-// Use query.New() to validate query syntax before execution.
+// Queries can handle such failures themselves. A "?" suffix drops the
+// errors of the term before it, and "a // b" produces b when a fails or
+// produces nothing but false and null:
+//
+//	.tool.black.line-length?
+//	.server.timeout // 30
+//
+// Errors raised by later stages of the query still propagate.
+//
+// query.New() rejects invalid syntax and undefined functions before
+// execution with a *SyntaxError holding the line and column of the
+// offending token.
 package query
@@ -0,0 +1,48 @@
+package query
+
+import (
+	"fmt"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+// parseTOML parses a TOML document, or a single value as totoml writes
+// everything but a table, so that totoml and fromtoml round-trip
+func parseTOML(s string) (interface{}, error) {
+	doc, err := converter.ParseTOMLDocument(s)
+	if err == nil {
+		return doc, nil
+	}
+	if value, valueErr := converter.ParseTOMLValue(s); valueErr == nil {
+		return value, nil
+	}
+	return nil, err
+}
+
+// fromDocument returns a builtin parsing a string embedded in the data,
+// such as a JSON blob in a multi-line string
+func fromDocument(name string, parse func(string) (interface{}, error)) func(interface{}) (interface{}, error) {
+	return func(in interface{}) (interface{}, error) {
+		s, err := stringInput(name, in)
+		if err != nil {
+			return nil, err
+		}
+		value, err := parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return value, nil
+	}
+}
+
+// toDocument returns a builtin serializing its input to a string the way
+// the matching output format of the command line does
+func toDocument(name string, convert func(interface{}) (string, error)) func(interface{}) (interface{}, error) {
+	return func(in interface{}) (interface{}, error) {
+		s, err := convert(in)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return s, nil
+	}
+}
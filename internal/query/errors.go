@@ -0,0 +1,38 @@
+package query
+
+import "errors"
+
+// UserError is an error raised by the query itself with error(value), as
+// opposed to a failure of one of its operations
+type UserError struct {
+	Value interface{} // the value passed to error
+}
+
+// Error returns a string value as it is and other values as JSON
+func (e *UserError) Error() string {
+	if s, ok := e.Value.(string); ok {
+		return s
+	}
+	return formatValue(e.Value) + " (not a string)"
+}
+
+// errorValue returns the value a catch handler receives for err: the value
+// of a UserError and the message of any other error
+func errorValue(err error) interface{} {
+	var userErr *UserError
+	if errors.As(err, &userErr) {
+		return userErr.Value
+	}
+	return err.Error()
+}
+
+// funcError raises its argument, or its input when it has none, as a
+// UserError
+func funcError(env *environment, in interface{}, args []node, emit emitFunc) error {
+	if len(args) == 0 {
+		return &UserError{Value: in}
+	}
+	return args[0].eval(env, in, func(v interface{}) error {
+		return &UserError{Value: v}
+	})
+}
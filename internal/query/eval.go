@@ -0,0 +1,376 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+)
+
+func (identityNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return emit(in)
+}
+
+func (n *fieldNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.term.eval(env, in, func(v interface{}) error {
+		value, err := lookupKey(v, n.key)
+		if err != nil {
+			return err
+		}
+		return emit(value)
+	})
+}
+
+func (n *indexNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.term.eval(env, in, func(v interface{}) error {
+		return n.index.eval(env, in, func(index interface{}) error {
+			var value interface{}
+			var err error
+			if key, ok := index.(string); ok {
+				value, err = lookupKey(v, key)
+			} else if i, ok := toInt(index); ok {
+				value, err = lookupIndex(v, i)
+			} else {
+				err = fmt.Errorf("cannot index %s with %s", typeName(v), typeName(index))
+			}
+			if err != nil {
+				return err
+			}
+			return emit(value)
+		})
+	})
+}
+
+func (n *sliceNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.term.eval(env, in, func(v interface{}) error {
+		return evalBound(env, n.start, in, func(start *int) error {
+			return evalBound(env, n.end, in, func(end *int) error {
+				value, err := lookupSlice(v, Slice{Start: start, End: end})
+				if err != nil {
+					return err
+				}
+				return emit(value)
+			})
+		})
+	})
+}
+
+// evalBound evaluates an optional slice bound, passing nil for a missing one
+func evalBound(env *environment, n node, in interface{}, emit func(*int) error) error {
+	if n == nil {
+		return emit(nil)
+	}
+	return n.eval(env, in, func(v interface{}) error {
+		i, ok := toInt(v)
+		if !ok {
+			return fmt.Errorf("slice bound must be a number, got %s", typeName(v))
+		}
+		return emit(&i)
+	})
+}
+
+func (n *iterateNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.term.eval(env, in, func(v interface{}) error {
+		values, err := iterate(v)
+		if err != nil {
+			return err
+		}
+		for _, value := range values {
+			if err := emit(value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (n *pipeNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.left.eval(env, in, func(v interface{}) error {
+		return n.right.eval(env, v, emit)
+	})
+}
+
+func (n *tryNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	caught, err := evalTry(env, n.body, in, emit)
+	if caught == nil || err != nil || n.handler == nil {
+		return err
+	}
+	return n.handler.eval(env, errorValue(caught), emit)
+}
+
+// evalCatching runs n and stops quietly at its first error
+func evalCatching(env *environment, n node, in interface{}, emit emitFunc) error {
+	_, err := evalTry(env, n, in, emit)
+	return err
+}
+
+// evalTry runs n and stops at its first error, which it returns as
+// caught. Errors returned by emit come from later stages of the query
+// rather than from n, so they still propagate as err.
+func evalTry(env *environment, n node, in interface{}, emit emitFunc) (caught, err error) {
+	var emitErr error
+	caught = n.eval(env, in, func(v interface{}) error {
+		emitErr = emit(v)
+		return emitErr
+	})
+	if emitErr != nil {
+		return nil, emitErr
+	}
+	return caught, nil
+}
+
+func (n *ifNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.cond.eval(env, in, func(cond interface{}) error {
+		switch {
+		case isTruthy(cond):
+			return n.then.eval(env, in, emit)
+		case n.els != nil:
+			return n.els.eval(env, in, emit)
+		default:
+			return emit(in)
+		}
+	})
+}
+
+func (n *literalNode) eval(_ *environment, _ interface{}, emit emitFunc) error {
+	return emit(n.value)
+}
+
+func (n *arrayNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	items := []interface{}{}
+	if n.body != nil {
+		err := n.body.eval(env, in, func(v interface{}) error {
+			items = append(items, v)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return emit(items)
+}
+
+func (n *formatNode) eval(_ *environment, in interface{}, emit emitFunc) error {
+	s, err := n.format(in)
+	if err != nil {
+		return err
+	}
+	return emit(s)
+}
+
+func (n *templateNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.build(env, in, len(n.parts), "", emit)
+}
+
+// build prepends the parts before end to suffix, emitting a string for
+// every combination of the results of the interpolations. As in jq, the
+// last interpolation varies slowest.
+func (n *templateNode) build(env *environment, in interface{}, end int, suffix string, emit emitFunc) error {
+	if end == 0 {
+		return emit(suffix)
+	}
+	part := n.parts[end-1]
+	if part.query == nil {
+		return n.build(env, in, end-1, part.text+suffix, emit)
+	}
+	return part.query.eval(env, in, func(v interface{}) error {
+		format := formatText
+		if n.format != nil {
+			format = n.format.format
+		}
+		s, err := format(v)
+		if err != nil {
+			return err
+		}
+		return n.build(env, in, end-1, s+suffix, emit)
+	})
+}
+
+func (n *objectNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.build(env, in, 0, map[string]interface{}{}, emit)
+}
+
+// build adds the entries from i onwards to table, emitting a copy for
+// every combination of their keys and values
+func (n *objectNode) build(env *environment, in interface{}, i int, table map[string]interface{}, emit emitFunc) error {
+	if i == len(n.entries) {
+		result := make(map[string]interface{}, len(table))
+		for k, v := range table {
+			result[k] = v
+		}
+		return emit(result)
+	}
+	entry := n.entries[i]
+	return entry.key.eval(env, in, func(k interface{}) error {
+		key, ok := k.(string)
+		if !ok {
+			return fmt.Errorf("object key must be a string, got %s", typeName(k))
+		}
+		return entry.value.eval(env, in, func(v interface{}) error {
+			previous, had := table[key]
+			table[key] = v
+			err := n.build(env, in, i+1, table, emit)
+			if had {
+				table[key] = previous
+			} else {
+				delete(table, key)
+			}
+			return err
+		})
+	})
+}
+
+func (n *callNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.fn(env, in, n.args, emit)
+}
+
+// iterate returns the elements of an array or the values of a table
+func iterate(current interface{}) ([]interface{}, error) {
+	switch v := current.(type) {
+	case []interface{}:
+		return v, nil
+	case []map[string]interface{}:
+		values := make([]interface{}, len(v))
+		for i, item := range v {
+			values[i] = item
+		}
+		return values, nil
+	case map[string]interface{}:
+		keys := sortedKeys(v)
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = v[key]
+		}
+		return values, nil
+	case map[interface{}]interface{}:
+		keys := make([]interface{}, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+
+		values := make([]interface{}, len(keys))
+		for i, key := range keys {
+			values[i] = v[key]
+		}
+		return values, nil
+	default:
+		return nil, fmt.Errorf("cannot iterate over %T", current)
+	}
+}
+
+// sortedKeys returns the keys of a table in order
+func sortedKeys(table map[string]interface{}) []string {
+	keys := make([]string, 0, len(table))
+	for key := range table {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// lookupKey returns the value stored under key in a table
+func lookupKey(current interface{}, key string) (interface{}, error) {
+	var value interface{}
+	var ok bool
+
+	switch v := current.(type) {
+	case map[string]interface{}:
+		value, ok = v[key]
+	case map[interface{}]interface{}:
+		// Handle cases where TOML parser returns map[interface{}]interface{}
+		value, ok = v[key]
+	case []interface{}, []map[string]interface{}:
+		return nil, fmt.Errorf("cannot navigate into array at '%s'", key)
+	default:
+		return nil, fmt.Errorf("cannot navigate into %T at '%s'", current, key)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("key '%s' not found", key)
+	}
+	return value, nil
+}
+
+// lookupIndex returns the element at index i of an array; negative
+// indexes count from the end
+func lookupIndex(current interface{}, i int) (interface{}, error) {
+	var length int
+	switch v := current.(type) {
+	case []interface{}:
+		length = len(v)
+	case []map[string]interface{}:
+		// Arrays of tables ([[table]]) are decoded with a concrete element type
+		length = len(v)
+	default:
+		return nil, fmt.Errorf("cannot navigate into %T at '[%d]'", current, i)
+	}
+
+	idx := i
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		return nil, fmt.Errorf("index %d out of range (length %d)", i, length)
+	}
+
+	if arr, ok := current.([]map[string]interface{}); ok {
+		return arr[idx], nil
+	}
+	return current.([]interface{})[idx], nil
+}
+
+// lookupSlice returns a sub-array, keeping the array's element type
+func lookupSlice(current interface{}, s Slice) (interface{}, error) {
+	switch v := current.(type) {
+	case []interface{}:
+		start, end := s.Bounds(len(v))
+		return v[start:end], nil
+	case []map[string]interface{}:
+		start, end := s.Bounds(len(v))
+		return v[start:end], nil
+	default:
+		return nil, fmt.Errorf("cannot navigate into %T at '%s'", current, s)
+	}
+}
+
+// toInt converts a numeric query value to an int, truncating floats
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int64:
+		return int(n), true
+	case int:
+		return n, true
+	case float64:
+		if math.IsNaN(n) || math.IsInf(n, 0) {
+			return 0, false
+		}
+		return int(math.Floor(n)), true
+	default:
+		return 0, false
+	}
+}
+
+// typeName names the TOML type of a value for error messages
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case map[string]interface{}, map[interface{}]interface{}:
+		return "table"
+	case []interface{}, []map[string]interface{}:
+		return "array"
+	case string:
+		return "string"
+	case int64, int, float64:
+		return "number"
+	case bool:
+		return "boolean"
+	case time.Time:
+		return "datetime"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
@@ -0,0 +1,193 @@
+package query
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+// formatFunc writes a value as a string for "@name" and for the
+// interpolations of a string that follows it
+type formatFunc func(v interface{}) (string, error)
+
+// formats are the encoders available as "@name"
+var formats = map[string]formatFunc{
+	"text":    formatText,
+	"json":    formatJSON,
+	"html":    textFormat(htmlEscaper.Replace),
+	"uri":     textFormat(escapeURI),
+	"csv":     formatCSV,
+	"tsv":     formatTSV,
+	"sh":      formatShell,
+	"base64":  textFormat(encodeBase64),
+	"base64d": formatBase64Decode,
+}
+
+// toText writes a value the way interpolation does: strings as they are,
+// datetimes in their TOML form and everything else as JSON
+func toText(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case time.Time:
+		return converter.FormatTOMLDatetime(v), nil
+	}
+	return formatJSONValue(v)
+}
+
+// formatText is @text, which writes its input as interpolation does
+func formatText(v interface{}) (string, error) {
+	return toText(v)
+}
+
+// formatJSON is @json, which writes its input as JSON, strings included
+func formatJSON(v interface{}) (string, error) {
+	return formatJSONValue(v)
+}
+
+// formatJSONValue writes v as compact JSON, with the non-finite float
+// policy of -o json
+func formatJSONValue(v interface{}) (string, error) {
+	encoded, err := json.Marshal(converter.JSONValue(v, converter.JSONOptions{}))
+	if err != nil {
+		return "", fmt.Errorf("cannot encode %s as JSON: %w", typeName(v), err)
+	}
+	return string(encoded), nil
+}
+
+// textFormat returns a format that escapes the text of its input
+func textFormat(escape func(string) string) formatFunc {
+	return func(v interface{}) (string, error) {
+		s, err := toText(v)
+		if err != nil {
+			return "", err
+		}
+		return escape(s), nil
+	}
+}
+
+var htmlEscaper = strings.NewReplacer(
+	"<", "&lt;",
+	">", "&gt;",
+	"&", "&amp;",
+	"'", "&#39;",
+	`"`, "&quot;",
+)
+
+// escapeURI percent-encodes every byte but the unreserved characters of
+// RFC 3986
+func escapeURI(s string) string {
+	const hex = "0123456789ABCDEF"
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if isIdentChar(c) || strings.IndexByte("-.~", c) >= 0 {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteByte('%')
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&15])
+	}
+	return b.String()
+}
+
+func encodeBase64(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// formatBase64Decode is @base64d, which accepts data with or without
+// padding
+func formatBase64Decode(v interface{}) (string, error) {
+	s, err := toText(v)
+	if err != nil {
+		return "", err
+	}
+	decoded, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		decoded, err = base64.RawStdEncoding.DecodeString(s)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s is not valid base64 data", formatValue(s))
+	}
+	return string(decoded), nil
+}
+
+// formatCSV is @csv, which writes an array as a CSV row with its strings
+// and datetimes quoted
+func formatCSV(v interface{}) (string, error) {
+	return formatRow("csv", ",", v, func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+	})
+}
+
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+// formatTSV is @tsv, which writes an array as a TSV row with tabs,
+// newlines and backslashes in its strings escaped
+func formatTSV(v interface{}) (string, error) {
+	return formatRow("tsv", "\t", v, tsvEscaper.Replace)
+}
+
+// formatRow writes the elements of an array separated by sep, quoting
+// strings and datetimes with quote. Numbers and booleans are written
+// out and null is left empty.
+func formatRow(name, sep string, v interface{}, quote func(string) string) (string, error) {
+	items, err := elements("@"+name, v)
+	if err != nil {
+		return "", err
+	}
+	fields := make([]string, len(items))
+	for i, item := range items {
+		switch item := item.(type) {
+		case nil:
+		case string:
+			fields[i] = quote(item)
+		case time.Time:
+			fields[i] = quote(converter.FormatTOMLDatetime(item))
+		case bool:
+			fields[i] = strconv.FormatBool(item)
+		case int64, int, float64:
+			fields[i] = formatNumber(item)
+		default:
+			return "", fmt.Errorf("%s is not valid in a %s row", typeName(item), name)
+		}
+	}
+	return strings.Join(fields, sep), nil
+}
+
+// formatShell is @sh, which quotes strings and datetimes for a POSIX
+// shell. The elements of an array are quoted separately and joined with
+// spaces, so that they expand to separate words.
+func formatShell(v interface{}) (string, error) {
+	items := []interface{}{v}
+	if isArray(v) {
+		var err error
+		if items, err = iterate(v); err != nil {
+			return "", err
+		}
+	}
+	words := make([]string, len(items))
+	for i, item := range items {
+		switch item := item.(type) {
+		case string:
+			words[i] = quoteShell(item)
+		case time.Time:
+			words[i] = quoteShell(converter.FormatTOMLDatetime(item))
+		case nil, bool, int64, int, float64:
+			words[i], _ = formatJSONValue(item)
+		default:
+			return "", fmt.Errorf("%s cannot be escaped for shell", typeName(item))
+		}
+	}
+	return strings.Join(words, " "), nil
+}
+
+func quoteShell(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,77 @@
+package query
+
+import (
+	"fmt"
+)
+
+// closure is an argument of a function call, run in the environment of
+// the caller whenever the function calls the parameter
+type closure struct {
+	body node
+	env  *environment
+}
+
+func (n *defNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.rest.eval(env.bind(n.def, nil), in, emit)
+}
+
+func (n *moduleNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	for _, def := range n.defs {
+		env = env.bind(def, nil)
+	}
+	return n.body.eval(env, in, emit)
+}
+
+// eval runs the body of the function in the environment it was defined
+// in, extended with the arguments
+func (n *funcCallNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.call(env, in, func(scope *environment) error {
+		return n.def.body.eval(scope, in, emit)
+	})
+}
+
+// call binds the arguments in the environment of the definition and passes
+// it to run. Value parameters run their argument against the input, and
+// run is called once for every combination of their results.
+func (n *funcCallNode) call(env *environment, in interface{}, run func(scope *environment) error) error {
+	scope := env.find(n.def)
+	if scope == nil {
+		// The parser only resolves calls to definitions in scope
+		return fmt.Errorf("%s/%d is not defined", n.name, len(n.args))
+	}
+	for i, param := range n.def.params {
+		scope = scope.bind(param, closure{body: n.args[i], env: env})
+	}
+	return n.bindValues(env, scope, 0, in, run)
+}
+
+// bindValues binds the value parameters from i onwards and calls run
+func (n *funcCallNode) bindValues(env, scope *environment, i int, in interface{}, run func(scope *environment) error) error {
+	for i < len(n.def.params) && !n.def.params[i].variable {
+		i++
+	}
+	if i == len(n.def.params) {
+		return run(scope)
+	}
+	param := n.def.params[i]
+	return n.args[i].eval(env, in, func(v interface{}) error {
+		return n.bindValues(env, scope.bind(param.name, v), i+1, in, run)
+	})
+}
+
+func (n *paramCallNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	arg, err := n.closure(env)
+	if err != nil {
+		return err
+	}
+	return arg.body.eval(arg.env, in, emit)
+}
+
+// closure returns the argument bound to the parameter
+func (n *paramCallNode) closure(env *environment) (closure, error) {
+	value, ok := env.lookup(n.param)
+	if !ok {
+		return closure{}, fmt.Errorf("%s/0 is not defined", n.param.name)
+	}
+	return value.(closure), nil
+}
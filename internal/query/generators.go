@@ -0,0 +1,189 @@
+package query
+
+import (
+	"fmt"
+	"math"
+)
+
+// eval folds the results of source into the state, starting from each
+// result of init. The state becomes the last result of update, or null
+// when update produces nothing, as in jq.
+func (n *reduceNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.init.eval(env, in, func(state interface{}) error {
+		err := n.source.eval(env, in, func(v interface{}) error {
+			var last interface{}
+			err := n.update.eval(env.bind(n.name, v), state, func(u interface{}) error {
+				last = u
+				return nil
+			})
+			state = last
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		return emit(state)
+	})
+}
+
+// eval runs update like reduce, emitting extract of every state update
+// produces. The state is left alone when update produces nothing.
+func (n *foreachNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.init.eval(env, in, func(state interface{}) error {
+		return n.source.eval(env, in, func(v interface{}) error {
+			scope := env.bind(n.name, v)
+			return n.update.eval(scope, state, func(u interface{}) error {
+				state = u
+				if n.extract == nil {
+					return emit(u)
+				}
+				return n.extract.eval(scope, u, emit)
+			})
+		})
+	})
+}
+
+// stopSignal is returned by an emit function to end a generator early.
+// Each use creates its own signal so that nested generators stop
+// independently.
+type stopSignal struct{}
+
+func (*stopSignal) Error() string {
+	return "generator stopped"
+}
+
+// evalLimit emits the first count results of n and stops it
+func evalLimit(env *environment, n node, in interface{}, count int, emit emitFunc) error {
+	if count <= 0 {
+		return nil
+	}
+	stop := &stopSignal{}
+	emitted := 0
+	err := n.eval(env, in, func(v interface{}) error {
+		if err := emit(v); err != nil {
+			return err
+		}
+		if emitted++; emitted == count {
+			return stop
+		}
+		return nil
+	})
+	if err == stop {
+		return nil
+	}
+	return err
+}
+
+// funcLimit emits the first n results of f
+func funcLimit(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return args[0].eval(env, in, func(n interface{}) error {
+		count, ok := toInt(n)
+		if !ok {
+			return fmt.Errorf("limit count must be a number, got %s", typeName(n))
+		}
+		return evalLimit(env, args[1], in, count, emit)
+	})
+}
+
+// funcFirst emits the first result of f, without running f further
+func funcFirst(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return evalLimit(env, args[0], in, 1, emit)
+}
+
+// funcLast emits the last result of f
+func funcLast(env *environment, in interface{}, args []node, emit emitFunc) error {
+	results, err := collect(env, args[0], in)
+	if err != nil || len(results) == 0 {
+		return err
+	}
+	return emit(results[len(results)-1])
+}
+
+// funcFirstElement returns the first element of an array, null when it is
+// empty
+func funcFirstElement(in interface{}) (interface{}, error) {
+	values, err := elements("first", in)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	return values[0], nil
+}
+
+// funcLastElement returns the last element of an array, null when it is
+// empty
+func funcLastElement(in interface{}) (interface{}, error) {
+	values, err := elements("last", in)
+	if err != nil || len(values) == 0 {
+		return nil, err
+	}
+	return values[len(values)-1], nil
+}
+
+// funcRange emits the numbers from $from up to but excluding $upto in
+// steps of $by: range($upto), range($from; $upto) or
+// range($from; $upto; $by). The arguments default to 0 and 1.
+func funcRange(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return evalArgs(env, in, args, nil, func(values []interface{}) error {
+		bounds := []interface{}{int64(0), nil, int64(1)}
+		switch len(values) {
+		case 1:
+			bounds[1] = values[0]
+		default:
+			copy(bounds, values)
+		}
+		for _, bound := range bounds {
+			if !isNumber(bound) {
+				return fmt.Errorf("range bounds must be numbers, got %s", typeName(bound))
+			}
+		}
+		return emitRange(bounds[0], bounds[1], bounds[2], emit)
+	})
+}
+
+// emitRange emits from, from+by and so on while they are on the same side
+// of upto as from. Integers stay integers.
+func emitRange(from, upto, by interface{}, emit emitFunc) error {
+	direction := compareNumbers(by, int64(0))
+	if direction == 0 || math.IsNaN(toFloat(by)) {
+		return nil
+	}
+	for v := from; !math.IsNaN(toFloat(v)) && compareNumbers(v, upto) == -direction; {
+		if err := emit(v); err != nil {
+			return err
+		}
+		var err error
+		if v, err = addValues(v, by); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcUntil applies update to its input until cond is true, emitting the
+// value that satisfies it
+func funcUntil(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return args[0].eval(env, in, func(cond interface{}) error {
+		if isTruthy(cond) {
+			return emit(in)
+		}
+		return args[1].eval(env, in, func(next interface{}) error {
+			return funcUntil(env, next, args, emit)
+		})
+	})
+}
+
+// funcWhile emits its input and repeatedly applies update while cond is
+// true
+func funcWhile(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return args[0].eval(env, in, func(cond interface{}) error {
+		if !isTruthy(cond) {
+			return nil
+		}
+		if err := emit(in); err != nil {
+			return err
+		}
+		return args[1].eval(env, in, func(next interface{}) error {
+			return funcWhile(env, next, args, emit)
+		})
+	})
+}
@@ -0,0 +1,393 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+// tokenKind identifies the kind of a lexical token
+type tokenKind int
+
+const (
+	tokEOF      tokenKind = iota
+	tokInvalid            // a character that starts no token
+	tokDot                // .
+	tokField              // .key, with the key in text
+	tokWildcard           // .*
+	tokRecurse            // ..
+	tokIdent              // function names and keywords
+	tokVariable           // $name, with the name in text
+	tokNumber             // 42, 3.14, 1e3
+	tokString             // "basic" or 'literal', with the decoded value in text
+	tokTemplate           // "basic \(query)", with its []stringPart in value
+	tokFormat             // @name, with "@name" in text
+	tokPunct              // operators and punctuation, with the operator in text
+)
+
+// token is a lexical token and its byte offsets in the query
+type token struct {
+	kind  tokenKind
+	text  string
+	value interface{} // decoded number for tokNumber, parts for tokTemplate
+	pos   int
+	end   int
+	err   error // lexing error for tokInvalid
+}
+
+// String describes the token for error messages
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokField:
+		return "'." + t.text + "'"
+	case tokString:
+		return "string " + converter.QuoteTOMLString(t.text)
+	case tokVariable:
+		return "'$" + t.text + "'"
+	default:
+		return "'" + t.text + "'"
+	}
+}
+
+// punctuation lists the operators and punctuation, longest first so that
+// the lexer always takes the longest match
+var punctuation = []string{
+	"//=",
+	"==", "!=", "<=", ">=", "//", "|=", "+=", "-=", "*=", "/=", "%=",
+	"|", ",", "(", ")", "[", "]", "{", "}", ":", ";", "<", ">", "?",
+	"+", "-", "*", "/", "%", "=",
+}
+
+// lexer splits a query into tokens on demand
+type lexer struct {
+	src string
+	pos int
+}
+
+// next returns the next token. Characters that start no token produce a
+// tokInvalid token rather than an error, so that a parser which stops early
+// (see [ParsePrefix]) never trips over text it does not need.
+func (l *lexer) next() token {
+	tok := l.scan()
+	tok.end = l.pos
+	return tok
+}
+
+// scan reads the token starting at the next non-blank character
+func (l *lexer) scan() token {
+	l.skipSpaceAndComments()
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, pos: start}
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c == '.':
+		return l.lexDot()
+	case isIdentStart(c):
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		// Functions of imported modules are called as "module::name"
+		for strings.HasPrefix(l.src[l.pos:], "::") && l.pos+2 < len(l.src) && isIdentStart(l.src[l.pos+2]) {
+			l.pos += 2
+			for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+				l.pos++
+			}
+		}
+		return token{kind: tokIdent, text: l.src[start:l.pos], pos: start}
+	case c == '$' && l.pos+1 < len(l.src) && isIdentStart(l.src[l.pos+1]):
+		l.pos++
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokVariable, text: l.src[start+1 : l.pos], pos: start}
+	case c >= '0' && c <= '9':
+		return l.lexNumber()
+	case c == '@' && l.pos+1 < len(l.src) && isIdentStart(l.src[l.pos+1]):
+		l.pos++
+		for l.pos < len(l.src) && isIdentChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokFormat, text: l.src[start:l.pos], pos: start}
+	case c == '"':
+		value, parts, end, err := parseBasicString(l.src, l.pos)
+		if parts != nil {
+			l.pos = end
+			return token{kind: tokTemplate, text: l.src[start:end], value: parts, pos: start}
+		}
+		return l.stringToken(value, end, err)
+	case c == '\'':
+		value, end, err := parseLiteralString(l.src, l.pos)
+		return l.stringToken(value, end, err)
+	}
+
+	for _, p := range punctuation {
+		if strings.HasPrefix(l.src[l.pos:], p) {
+			l.pos += len(p)
+			return token{kind: tokPunct, text: p, pos: start}
+		}
+	}
+
+	_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+	l.pos += size
+	return token{kind: tokInvalid, text: l.src[start:l.pos], pos: start}
+}
+
+// skipSpaceAndComments skips whitespace and "#" comments
+func (l *lexer) skipSpaceAndComments() {
+	for l.pos < len(l.src) {
+		switch l.src[l.pos] {
+		case ' ', '\t', '\n', '\r':
+			l.pos++
+		case '#':
+			for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// lexDot lexes ".", "..", ".*" and ".key"
+func (l *lexer) lexDot() token {
+	start := l.pos
+	l.pos++
+	if l.pos >= len(l.src) {
+		return token{kind: tokDot, text: ".", pos: start}
+	}
+
+	switch c := l.src[l.pos]; {
+	case c == '.':
+		l.pos++
+		return token{kind: tokRecurse, text: "..", pos: start}
+	case c == '*':
+		l.pos++
+		return token{kind: tokWildcard, text: ".*", pos: start}
+	case isBareKeyChar(c):
+		for l.pos < len(l.src) && isBareKeyChar(l.src[l.pos]) {
+			l.pos++
+		}
+		return token{kind: tokField, text: l.src[start+1 : l.pos], pos: start}
+	default:
+		return token{kind: tokDot, text: ".", pos: start}
+	}
+}
+
+// lexNumber lexes an integer or a float. Integers that do not fit in an
+// int64 become floats.
+func (l *lexer) lexNumber() token {
+	start := l.pos
+	isFloat := false
+	l.skipDigits()
+	if l.pos+1 < len(l.src) && l.src[l.pos] == '.' && isDigit(l.src[l.pos+1]) {
+		isFloat = true
+		l.pos++
+		l.skipDigits()
+	}
+	if l.pos < len(l.src) && (l.src[l.pos] == 'e' || l.src[l.pos] == 'E') {
+		exp := l.pos + 1
+		if exp < len(l.src) && (l.src[exp] == '+' || l.src[exp] == '-') {
+			exp++
+		}
+		if exp < len(l.src) && isDigit(l.src[exp]) {
+			isFloat = true
+			l.pos = exp
+			l.skipDigits()
+		}
+	}
+
+	text := l.src[start:l.pos]
+	if !isFloat {
+		if i, err := strconv.ParseInt(text, 10, 64); err == nil {
+			return token{kind: tokNumber, text: text, value: i, pos: start}
+		}
+	}
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return token{kind: tokInvalid, text: text, pos: start, err: fmt.Errorf("invalid number '%s'", text)}
+	}
+	return token{kind: tokNumber, text: text, value: f, pos: start}
+}
+
+func (l *lexer) skipDigits() {
+	for l.pos < len(l.src) && isDigit(l.src[l.pos]) {
+		l.pos++
+	}
+}
+
+// stringToken builds the token for a string literal ending at end
+func (l *lexer) stringToken(value string, end int, err error) token {
+	start := l.pos
+	if err != nil {
+		l.pos = len(l.src)
+		return token{kind: tokInvalid, text: l.src[start:], pos: start, err: err}
+	}
+	l.pos = end
+	return token{kind: tokString, text: value, pos: start}
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// isIdentifier reports whether s lexes as a single name
+func isIdentifier(s string) bool {
+	if s == "" || !isIdentStart(s[0]) {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if !isIdentChar(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func isIdentStart(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c == '_'
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+// isBareKeyChar reports whether c may appear in a TOML bare key
+func isBareKeyChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || isDigit(c) || c == '_' || c == '-'
+}
+
+// stringPart is a piece of a string with interpolations: literal text, or
+// the byte offsets of the query in "\(query)" when end is set
+type stringPart struct {
+	text       string
+	start, end int
+}
+
+// parseBasicString parses a TOML basic string ("...") starting at the
+// opening quote and returns its unescaped value and the position after the
+// closing quote. A string with interpolations returns its parts instead of
+// the value.
+func parseBasicString(src string, pos int) (string, []stringPart, int, error) {
+	var b strings.Builder
+	var parts []stringPart
+	i := pos + 1
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == '"':
+			if parts != nil {
+				return "", append(parts, stringPart{text: b.String()}), i + 1, nil
+			}
+			return b.String(), nil, i + 1, nil
+		case c == '\\' && i+1 < len(src) && src[i+1] == '(':
+			end, err := interpolationEnd(src, i+2)
+			if err != nil {
+				return "", nil, 0, err
+			}
+			parts = append(parts, stringPart{text: b.String()}, stringPart{start: i + 2, end: end})
+			b.Reset()
+			i = end + 1
+		case c == '\\':
+			if i+1 >= len(src) {
+				return "", nil, 0, fmt.Errorf("unterminated string")
+			}
+			r, n, err := parseEscape(src[i+1:])
+			if err != nil {
+				return "", nil, 0, err
+			}
+			b.WriteRune(r)
+			i += 1 + n
+		case c == '\n' || c < 0x20 && c != '\t' || c == 0x7f:
+			return "", nil, 0, fmt.Errorf("control character %U in string", rune(c))
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return "", nil, 0, fmt.Errorf("unterminated string")
+}
+
+// interpolationEnd returns the offset of the ")" closing the interpolation
+// whose query starts at pos. The query is lexed rather than scanned for
+// parentheses, so that strings inside it may hold any of them.
+func interpolationEnd(src string, pos int) (int, error) {
+	l := lexer{src: src, pos: pos}
+	depth := 0
+	for {
+		tok := l.next()
+		switch {
+		case tok.kind == tokEOF:
+			return 0, fmt.Errorf("unterminated interpolation")
+		case tok.kind == tokInvalid && tok.err != nil:
+			return 0, tok.err
+		case isPunct(tok, "("):
+			depth++
+		case isPunct(tok, ")"):
+			if depth == 0 {
+				return tok.pos, nil
+			}
+			depth--
+		}
+	}
+}
+
+// parseEscape decodes the escape sequence following a backslash and returns
+// the rune and the number of bytes consumed
+func parseEscape(s string) (rune, int, error) {
+	switch s[0] {
+	case 'b':
+		return '\b', 1, nil
+	case 't':
+		return '\t', 1, nil
+	case 'n':
+		return '\n', 1, nil
+	case 'f':
+		return '\f', 1, nil
+	case 'r':
+		return '\r', 1, nil
+	case '"':
+		return '"', 1, nil
+	case '\\':
+		return '\\', 1, nil
+	case '/':
+		// Not a TOML escape, but accepted for JSON compatibility
+		return '/', 1, nil
+	case 'u', 'U':
+		digits := 4
+		if s[0] == 'U' {
+			digits = 8
+		}
+		if len(s) < 1+digits {
+			return 0, 0, fmt.Errorf("invalid escape '\\%s'", s)
+		}
+		code, err := strconv.ParseUint(s[1:1+digits], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(code)) {
+			return 0, 0, fmt.Errorf("invalid escape '\\%s'", s[:1+digits])
+		}
+		return rune(code), 1 + digits, nil
+	default:
+		return 0, 0, fmt.Errorf("invalid escape '\\%c'", s[0])
+	}
+}
+
+// parseLiteralString parses a TOML literal string ('...'), which has no
+// escapes, starting at the opening quote
+func parseLiteralString(src string, pos int) (string, int, error) {
+	end := strings.IndexByte(src[pos+1:], '\'')
+	if end < 0 {
+		return "", 0, fmt.Errorf("unterminated string")
+	}
+	value := src[pos+1 : pos+1+end]
+	if strings.ContainsAny(value, "\n\r") {
+		return "", 0, fmt.Errorf("newline in literal string")
+	}
+	return value, pos + end + 2, nil
+}
@@ -0,0 +1,91 @@
+package query
+
+import (
+	"fmt"
+	"math"
+)
+
+// numberInput returns the input of a math builtin
+func numberInput(name string, in interface{}) (float64, error) {
+	if !isNumber(in) {
+		return 0, fmt.Errorf("%s cannot be applied to %s, expected a number", name, typeName(in))
+	}
+	return toFloat(in), nil
+}
+
+// roundingFunc returns floor, ceil or round. Integers pass through, and
+// results that fit in an int64 become integers so that they can be written
+// back as TOML integers.
+func roundingFunc(name string, round func(float64) float64) func(interface{}) (interface{}, error) {
+	return func(in interface{}) (interface{}, error) {
+		if i, ok := toInt64(in); ok {
+			return i, nil
+		}
+		f, err := numberInput(name, in)
+		if err != nil {
+			return nil, err
+		}
+		return floatToNumber(round(f)), nil
+	}
+}
+
+// floatToNumber converts a whole float to an int64 when it is in range
+func floatToNumber(f float64) interface{} {
+	if f >= math.MinInt64 && f < math.MaxInt64 && f == math.Trunc(f) {
+		return int64(f)
+	}
+	return f
+}
+
+// floatFunc returns a math builtin computed on floats
+func floatFunc(name string, f func(float64) float64) func(interface{}) (interface{}, error) {
+	return func(in interface{}) (interface{}, error) {
+		x, err := numberInput(name, in)
+		if err != nil {
+			return nil, err
+		}
+		return f(x), nil
+	}
+}
+
+// funcFabs returns the absolute value of a number, keeping integers
+func funcFabs(in interface{}) (interface{}, error) {
+	if _, err := numberInput("fabs", in); err != nil {
+		return nil, err
+	}
+	return funcLength(in)
+}
+
+// funcPow raises the first argument to the power of the second. Integers
+// raised to a non-negative integer power stay integers unless the result
+// overflows.
+func funcPow(_ interface{}, args []interface{}) (interface{}, error) {
+	base, err := numberInput("pow", args[0])
+	if err != nil {
+		return nil, err
+	}
+	exp, err := numberInput("pow", args[1])
+	if err != nil {
+		return nil, err
+	}
+
+	b, bInt := toInt64(args[0])
+	e, eInt := toInt64(args[1])
+	if bInt && eInt && e >= 0 {
+		// Exponentiation by squaring; once a step overflows, so does the
+		// result
+		result, ok := int64(1), true
+		for ; e > 0 && ok; e >>= 1 {
+			if e&1 == 1 {
+				result, ok = multiplyInts(result, b)
+			}
+			if e > 1 && ok {
+				b, ok = multiplyInts(b, b)
+			}
+		}
+		if ok {
+			return result, nil
+		}
+	}
+	return math.Pow(base, exp), nil
+}
@@ -0,0 +1,93 @@
+package query
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// moduleExtension is the file extension of query modules
+const moduleExtension = ".tmq"
+
+// module is a parsed module file
+type module struct {
+	exported []*funcDef // functions importers can call, includes first
+}
+
+// moduleLoader finds, parses and caches the modules a query imports
+type moduleLoader struct {
+	searchPath []string
+	loaded     map[string]*module // by file; nil while the module is parsed
+	defs       []*funcDef         // definitions of all modules, dependencies first
+}
+
+// newModuleLoader returns a loader searching the directories in order
+func newModuleLoader(searchPath []string) *moduleLoader {
+	return &moduleLoader{searchPath: searchPath, loaded: make(map[string]*module)}
+}
+
+// load returns the module name, which is looked up relative to dir, the
+// directory of the importing module, and then the search path
+func (l *moduleLoader) load(name, dir string) (*module, error) {
+	file, err := l.resolve(name, dir)
+	if err != nil {
+		return nil, err
+	}
+	if mod, ok := l.loaded[file]; ok {
+		if mod == nil {
+			return nil, fmt.Errorf("module %q imports itself", name)
+		}
+		return mod, nil
+	}
+
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read module %q: %v", name, err)
+	}
+	l.loaded[file] = nil
+	p := newParser(string(src))
+	p.file, p.dir, p.modules = file, filepath.Dir(file), l
+	exported, defs, err := p.parseModule()
+	if err != nil {
+		return nil, err
+	}
+	mod := &module{exported: exported}
+	l.loaded[file] = mod
+	l.defs = append(l.defs, defs...)
+	return mod, nil
+}
+
+// resolve returns the file of the module name: "name.tmq" or
+// "name/base.tmq", where base is the last element of name
+func (l *moduleLoader) resolve(name, dir string) (string, error) {
+	if name == "" || filepath.IsAbs(name) || strings.HasPrefix(name, "/") {
+		return "", fmt.Errorf("invalid module path %q", name)
+	}
+	for _, part := range strings.Split(name, "/") {
+		if part == ".." || part == "." || part == "" {
+			return "", fmt.Errorf("invalid module path %q", name)
+		}
+	}
+
+	dirs := l.searchPath
+	if dir != "" {
+		dirs = append([]string{dir}, dirs...)
+	}
+	local := filepath.FromSlash(name)
+	for _, d := range dirs {
+		candidates := []string{
+			filepath.Join(d, local+moduleExtension),
+			filepath.Join(d, local, filepath.Base(local)+moduleExtension),
+		}
+		for _, file := range candidates {
+			if info, err := os.Stat(file); err == nil && info.Mode().IsRegular() {
+				return file, nil
+			}
+		}
+	}
+	if len(l.searchPath) == 0 {
+		return "", fmt.Errorf("module %q not found: no library path is set", name)
+	}
+	return "", fmt.Errorf("module %q not found in %s", name, strings.Join(l.searchPath, string(filepath.ListSeparator)))
+}
@@ -0,0 +1,325 @@
+package query
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// binaryOps implements the binary operators that evaluate both operands
+var binaryOps = map[string]func(left, right interface{}) (interface{}, error){
+	"==": compareOp(func(c int) bool { return c == 0 }),
+	"!=": compareOp(func(c int) bool { return c != 0 }),
+	"<":  orderOp(func(c int) bool { return c < 0 }),
+	"<=": orderOp(func(c int) bool { return c <= 0 }),
+	">":  orderOp(func(c int) bool { return c > 0 }),
+	">=": orderOp(func(c int) bool { return c >= 0 }),
+	"+":  addValues,
+	"-":  subtractValues,
+	"*":  multiplyValues,
+	"/":  divideValues,
+	"%":  moduloValues,
+}
+
+// compareOp builds a comparison operator from a test of the ordering of
+// its operands
+func compareOp(test func(int) bool) func(left, right interface{}) (interface{}, error) {
+	return func(left, right interface{}) (interface{}, error) {
+		return test(compareValues(left, right)), nil
+	}
+}
+
+// orderOp builds an ordering operator. Unlike sorting, which needs an order
+// between any two values, it refuses to order datetimes of different TOML
+// types.
+func orderOp(test func(int) bool) func(left, right interface{}) (interface{}, error) {
+	compare := compareOp(test)
+	return func(left, right interface{}) (interface{}, error) {
+		if err := checkOrderable(left, right); err != nil {
+			return nil, err
+		}
+		return compare(left, right)
+	}
+}
+
+// eval runs both operands against the input and applies the operator to
+// every pair of results. As in jq, the right operand is the outer loop.
+// "and" and "or" only evaluate the right operand when the left one does
+// not decide the result, and "//" only when the left one produces no
+// value other than false and null. "," emits the results of the left
+// operand followed by those of the right one. Assignments are evaluated
+// by evalAssign.
+func (n *binaryNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	switch n.op {
+	case ",":
+		if err := n.left.eval(env, in, emit); err != nil {
+			return err
+		}
+		return n.right.eval(env, in, emit)
+	case "//":
+		found := false
+		err := evalCatching(env, n.left, in, func(left interface{}) error {
+			if !isTruthy(left) {
+				return nil
+			}
+			found = true
+			return emit(left)
+		})
+		if err != nil || found {
+			return err
+		}
+		return n.right.eval(env, in, emit)
+	case "and", "or":
+		return n.left.eval(env, in, func(left interface{}) error {
+			if isTruthy(left) == (n.op == "or") {
+				return emit(n.op == "or")
+			}
+			return n.right.eval(env, in, func(right interface{}) error {
+				return emit(isTruthy(right))
+			})
+		})
+	}
+	if binaryPrecedence[n.op] == assignPrecedence {
+		return n.evalAssign(env, in, emit)
+	}
+
+	op := binaryOps[n.op]
+	return n.right.eval(env, in, func(right interface{}) error {
+		return n.left.eval(env, in, func(left interface{}) error {
+			value, err := op(left, right)
+			if err != nil {
+				return err
+			}
+			return emit(value)
+		})
+	})
+}
+
+func (n *negateNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.term.eval(env, in, func(v interface{}) error {
+		if !isNumber(v) {
+			return fmt.Errorf("%s cannot be negated", typeName(v))
+		}
+		return emit(numberOp(int64(0), v, subtractInts, func(a, b float64) float64 { return a - b }))
+	})
+}
+
+// addValues implements addition: numbers add, strings and arrays
+// concatenate and tables merge, with the right table winning on shared
+// keys. null is the identity on either side.
+func addValues(left, right interface{}) (interface{}, error) {
+	switch {
+	case left == nil:
+		return right, nil
+	case right == nil:
+		return left, nil
+	}
+
+	switch l := left.(type) {
+	case int64, int, float64:
+		if isNumber(right) {
+			return numberOp(l, right, addInts, func(a, b float64) float64 { return a + b }), nil
+		}
+	case string:
+		if r, ok := right.(string); ok {
+			return l + r, nil
+		}
+	case []interface{}, []map[string]interface{}:
+		if typeOrder(right) != typeOrder(left) {
+			break
+		}
+		a, _ := iterate(l)
+		b, _ := iterate(right)
+		return append(append(make([]interface{}, 0, len(a)+len(b)), a...), b...), nil
+	case map[string]interface{}, map[interface{}]interface{}:
+		if typeOrder(right) != typeOrder(left) {
+			break
+		}
+		merged := make(map[string]interface{})
+		for key, value := range asTable(l) {
+			merged[key] = value
+		}
+		for key, value := range asTable(right) {
+			merged[key] = value
+		}
+		return merged, nil
+	}
+	return nil, fmt.Errorf("%s and %s cannot be added", typeName(left), typeName(right))
+}
+
+// subtractValues implements subtraction of numbers, and of arrays, which
+// removes every element of the left array that occurs in the right one
+func subtractValues(left, right interface{}) (interface{}, error) {
+	switch {
+	case isNumber(left) && isNumber(right):
+		return numberOp(left, right, subtractInts, func(a, b float64) float64 { return a - b }), nil
+	case typeName(left) == "array" && typeName(right) == "array":
+		items, _ := iterate(left)
+		removed, _ := iterate(right)
+		result := []interface{}{}
+		for _, item := range items {
+			keep := true
+			for _, r := range removed {
+				if compareValues(item, r) == 0 {
+					keep = false
+					break
+				}
+			}
+			if keep {
+				result = append(result, item)
+			}
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("%s and %s cannot be subtracted", typeName(left), typeName(right))
+}
+
+// multiplyValues implements multiplication of numbers, the deep merge of
+// tables and the repetition of a string, which gives null for a count
+// below one
+func multiplyValues(left, right interface{}) (interface{}, error) {
+	switch {
+	case isNumber(left) && isNumber(right):
+		return numberOp(left, right, multiplyInts, func(a, b float64) float64 { return a * b }), nil
+	case typeName(left) == "table" && typeName(right) == "table":
+		return deepMerge(asTable(left), asTable(right)), nil
+	case typeName(left) == "string" && isNumber(right):
+		return repeatString(left.(string), right)
+	case isNumber(left) && typeName(right) == "string":
+		return repeatString(right.(string), left)
+	}
+	return nil, fmt.Errorf("%s and %s cannot be multiplied", typeName(left), typeName(right))
+}
+
+// divideValues implements division of numbers, which stays an integer
+// when both operands are integers and the division is exact, and the
+// splitting of a string on a separator
+func divideValues(left, right interface{}) (interface{}, error) {
+	switch {
+	case isNumber(left) && isNumber(right):
+		if toFloat(right) == 0 {
+			return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", formatNumber(left), formatNumber(right))
+		}
+		return numberOp(left, right, divideInts, func(a, b float64) float64 { return a / b }), nil
+	case typeName(left) == "string" && typeName(right) == "string":
+		return funcSplit(left, []interface{}{right})
+	}
+	return nil, fmt.Errorf("%s and %s cannot be divided", typeName(left), typeName(right))
+}
+
+// moduloValues implements the remainder of numbers, truncated to integers
+// first as in jq
+func moduloValues(left, right interface{}) (interface{}, error) {
+	a, aOK := truncateInt(left)
+	b, bOK := truncateInt(right)
+	if !aOK || !bOK {
+		return nil, fmt.Errorf("%s and %s cannot be divided", typeName(left), typeName(right))
+	}
+	if b == 0 {
+		return nil, fmt.Errorf("%s and %s cannot be divided because the divisor is zero", formatNumber(left), formatNumber(right))
+	}
+	if b == -1 {
+		// Avoids overflowing on the smallest integer
+		return int64(0), nil
+	}
+	return a % b, nil
+}
+
+// truncateInt converts a number to an integer, dropping any fraction
+func truncateInt(v interface{}) (int64, bool) {
+	if i, ok := toInt64(v); ok {
+		return i, true
+	}
+	f, ok := v.(float64)
+	if !ok || math.IsNaN(f) {
+		return 0, false
+	}
+	return int64(math.Trunc(f)), true
+}
+
+// isNumber reports whether v is an integer or a float
+func isNumber(v interface{}) bool {
+	switch v.(type) {
+	case int64, int, float64:
+		return true
+	default:
+		return false
+	}
+}
+
+// numberOp applies intOp when both operands are integers, so that TOML
+// integers stay integers, and floatOp when either is a float or intOp
+// cannot represent the result
+func numberOp(left, right interface{}, intOp func(a, b int64) (int64, bool), floatOp func(a, b float64) float64) interface{} {
+	a, aInt := toInt64(left)
+	b, bInt := toInt64(right)
+	if aInt && bInt {
+		if result, ok := intOp(a, b); ok {
+			return result
+		}
+	}
+	return floatOp(toFloat(left), toFloat(right))
+}
+
+func addInts(a, b int64) (int64, bool) {
+	sum := a + b
+	return sum, (sum > a) == (b > 0)
+}
+
+func subtractInts(a, b int64) (int64, bool) {
+	diff := a - b
+	return diff, (diff < a) == (b > 0)
+}
+
+func multiplyInts(a, b int64) (int64, bool) {
+	if a == 0 || b == 0 {
+		return 0, true
+	}
+	product := a * b
+	overflow := product/b != a || (a == -1 && b == math.MinInt64) || (b == -1 && a == math.MinInt64)
+	return product, !overflow
+}
+
+func divideInts(a, b int64) (int64, bool) {
+	if b == -1 && a == math.MinInt64 {
+		return 0, false
+	}
+	return a / b, a%b == 0
+}
+
+// deepMerge merges right into a copy of left, merging nested tables
+// rather than replacing them
+func deepMerge(left, right map[string]interface{}) map[string]interface{} {
+	merged := make(map[string]interface{}, len(left))
+	for key, value := range left {
+		merged[key] = value
+	}
+	for key, value := range right {
+		if typeName(merged[key]) == "table" && typeName(value) == "table" {
+			merged[key] = deepMerge(asTable(merged[key]), asTable(value))
+		} else {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// maxRepeatLength is the longest string repetition may build, in bytes
+const maxRepeatLength = 1 << 30
+
+// repeatString repeats s count times, rounding count down; counts below
+// one give null, as in jq. Results longer than maxRepeatLength, including
+// those of an infinite count, are errors.
+func repeatString(s string, count interface{}) (interface{}, error) {
+	n := math.Floor(toFloat(count))
+	if n < 1 || math.IsNaN(n) {
+		return nil, nil
+	}
+	if s == "" {
+		return "", nil
+	}
+	if n > float64(maxRepeatLength/len(s)) {
+		return nil, fmt.Errorf("string repeated %s times is too long", formatNumber(count))
+	}
+	return strings.Repeat(s, int(n)), nil
+}
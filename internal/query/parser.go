@@ -0,0 +1,940 @@
+package query
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+// SyntaxError describes a query that cannot be compiled. Line and Column
+// locate the offending token, counting from 1. File names the module the
+// error is in and is empty for errors in the query itself.
+type SyntaxError struct {
+	File   string
+	Line   int
+	Column int
+	Msg    string
+}
+
+// Error returns the message with the position of the offending token
+func (e *SyntaxError) Error() string {
+	where := "syntax error"
+	if e.File != "" {
+		where += " in " + e.File
+	}
+	if e.Line > 1 || e.File != "" {
+		return fmt.Sprintf("%s at line %d, column %d: %s", where, e.Line, e.Column, e.Msg)
+	}
+	return fmt.Sprintf("%s at column %d: %s", where, e.Column, e.Msg)
+}
+
+// parser is a recursive descent parser producing a node tree.
+//
+// Grammar, loosest binding first:
+//
+//	program = { import } pipe
+//	module  = { import } { def }
+//	import  = "import" string "as" name ";" | "include" string ";"
+//	def     = "def" name [ "(" param { ";" param } ")" ] ":" pipe ";"
+//	param   = name | variable
+//	pipe    = def pipe | comma { "|" comma } | term "as" variable "|" pipe
+//	comma   = alt { "," alt }
+//	alt     = assign [ "//" alt ]
+//	assign  = or [ ( "=" | "|=" | "+=" | "-=" | "*=" | "/=" | "%=" | "//=" ) or ]
+//	or      = and { "or" and }
+//	and     = compare { "and" compare }
+//	compare = sum [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" ) sum ]
+//	sum     = product { ( "+" | "-" ) product }
+//	product = term { ( "*" | "/" | "%" ) term }
+//	term    = primary { suffix }
+//	primary = "." | ".." | ".key" | ".*" | "." string | number | "-" term
+//	        | string | template | format [ string | template ]
+//	        | "true" | "false" | "null" | "(" pipe ")"
+//	        | variable | "[" [pipe] "]" | "{" [entry { "," entry }] "}"
+//	        | "reduce" term "as" variable "(" pipe ";" pipe ")"
+//	        | "foreach" term "as" variable "(" pipe ";" pipe [ ";" pipe ] ")"
+//	        | "if" pipe "then" pipe { "elif" pipe "then" pipe }
+//	          [ "else" pipe ] "end"
+//	        | "try" term [ "catch" term ]
+//	        | name [ "(" pipe { ";" pipe } ")" ]
+//	entry   = ( name | string | template | "(" pipe ")" ) ":" value
+//	        | name | string
+//	        | variable
+//	value   = alt { "|" alt }
+//	suffix  = ".key" | ".*" | "." string | "." name | "[" "]"
+//	        | "[" pipe "]" | "[" [pipe] ":" [pipe] "]" | "?"
+//
+// A template is a basic string with interpolations "\(" pipe ")", and a
+// format is "@" followed by a name.
+//
+// A binding "term as $x | body" may start wherever a term may: its body
+// extends as far as the pipe the term is part of, and "$x" is defined
+// only inside the body. Likewise a definition is in scope in its own body
+// and in the rest of the pipe it starts.
+type parser struct {
+	src     string
+	lex     lexer
+	ahead   []token       // lookahead buffer
+	lastEnd int           // end offset of the last consumed token
+	pipeMin int           // minimum precedence of the pipe being parsed
+	vars    []string      // variables in scope, innermost last
+	funcs   []scopedFunc  // functions in scope, innermost last
+	file    string        // module being parsed, empty for the query
+	dir     string        // directory of the module being parsed
+	modules *moduleLoader // loads imported modules
+}
+
+// scopedFunc is a function callable by name: a definition or a filter
+// parameter of the definition being parsed
+type scopedFunc struct {
+	name  string
+	arity int
+	def   *funcDef
+	param *funcParam
+}
+
+// binaryPrecedence ranks the binary operators, higher binding tighter
+var binaryPrecedence = map[string]int{
+	",":  1,
+	"//": 2,
+	"=":  assignPrecedence, "|=": assignPrecedence, "+=": assignPrecedence, "-=": assignPrecedence,
+	"*=": assignPrecedence, "/=": assignPrecedence, "%=": assignPrecedence, "//=": assignPrecedence,
+	"or":  4,
+	"and": 5,
+	"==":  6, "!=": 6, "<": 6, "<=": 6, ">": 6, ">=": 6,
+	"+": 7, "-": 7,
+	"*": 8, "/": 8, "%": 8,
+}
+
+// assignPrecedence is the precedence of the assignment operators. As in
+// jq, they bind more tightly than "//", so ".a |= . // 1" is
+// "(.a |= .) // 1" and defaults are written ".a //= 1".
+const assignPrecedence = 3
+
+// nonAssociative lists the precedence levels whose operators cannot be
+// chained, so that "a < b < c" is an error rather than a surprise
+var nonAssociative = map[int]bool{assignPrecedence: true, 6: true}
+
+// rightAssociative lists the precedence levels whose operators group from
+// the right, so that "a // b // c" is "a // (b // c)"
+var rightAssociative = map[int]bool{2: true}
+
+// commaPrecedence is the precedence of ",", the loosest binary operator.
+// Object values stop at it so that it can separate the entries.
+const commaPrecedence = 1
+
+// termPrecedence is the precedence of terms, above every operator
+const termPrecedence = 100
+
+// keywords are names that cannot be called as functions
+var keywords = map[string]bool{
+	"and": true, "or": true, "as": true, "def": true, "import": true, "include": true,
+	"reduce": true, "foreach": true, "if": true, "then": true, "elif": true,
+	"else": true, "end": true, "try": true, "catch": true,
+}
+
+// newParser returns a parser for src in which vars are defined
+func newParser(src string, vars ...string) *parser {
+	return &parser{src: src, lex: lexer{src: src}, pipeMin: commaPrecedence, vars: vars}
+}
+
+// peekAt returns the token n positions ahead without consuming it
+func (p *parser) peekAt(n int) token {
+	for len(p.ahead) <= n {
+		p.ahead = append(p.ahead, p.lex.next())
+	}
+	return p.ahead[n]
+}
+
+func (p *parser) peek() token {
+	return p.peekAt(0)
+}
+
+// next consumes and returns the next token
+func (p *parser) next() token {
+	tok := p.peek()
+	p.ahead = p.ahead[1:]
+	p.lastEnd = tok.end
+	return tok
+}
+
+// isPunct reports whether tok is the punctuation text
+func isPunct(tok token, text string) bool {
+	return tok.kind == tokPunct && tok.text == text
+}
+
+// expect consumes the punctuation text or fails
+func (p *parser) expect(text string) error {
+	tok := p.peek()
+	if !isPunct(tok, text) {
+		return p.errorAt(tok, "expected '%s', got %s", text, tok)
+	}
+	p.next()
+	return nil
+}
+
+// unexpected reports tok as out of place, preferring the lexer's own
+// message for malformed tokens
+func (p *parser) unexpected(tok token) error {
+	if tok.kind == tokInvalid && tok.err != nil {
+		return p.errorAt(tok, "%v", tok.err)
+	}
+	return p.errorAt(tok, "unexpected %s", tok)
+}
+
+// errorAt builds a SyntaxError located at tok
+func (p *parser) errorAt(tok token, format string, args ...interface{}) error {
+	before := p.src[:tok.pos]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+	return &SyntaxError{File: p.file, Line: line, Column: column, Msg: fmt.Sprintf(format, args...)}
+}
+
+// parseProgram parses a complete query
+func (p *parser) parseProgram() (node, error) {
+	directives, err := p.parseDirectives()
+	if err != nil {
+		return nil, err
+	}
+	n, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, p.unexpected(tok)
+	}
+	if len(directives) > 0 {
+		n = &moduleNode{directives: directives, defs: p.modules.defs, body: n}
+	}
+	return n, nil
+}
+
+// isKeyword reports whether tok is the keyword name
+func isKeyword(tok token, name string) bool {
+	return tok.kind == tokIdent && tok.text == name
+}
+
+// parseDirectives parses the import and include directives at the start
+// of a query or module, bringing the functions of the modules into scope,
+// and returns the directives in canonical syntax
+func (p *parser) parseDirectives() ([]string, error) {
+	var directives []string
+	for isKeyword(p.peek(), "import") || isKeyword(p.peek(), "include") {
+		kind := p.next()
+		path := p.next()
+		if path.kind != tokString {
+			return nil, p.errorAt(path, "expected module path after '%s', got %s", kind.text, path)
+		}
+		var alias string
+		if kind.text == "import" {
+			if tok := p.next(); !isKeyword(tok, "as") {
+				return nil, p.errorAt(tok, "expected 'as', got %s", tok)
+			}
+			name := p.next()
+			if name.kind != tokIdent || keywords[name.text] || strings.Contains(name.text, "::") {
+				return nil, p.errorAt(name, "expected module name after 'as', got %s", name)
+			}
+			alias = name.text
+		}
+		if err := p.expect(";"); err != nil {
+			return nil, err
+		}
+
+		mod, err := p.modules.load(path.text, p.dir)
+		if err != nil {
+			if _, ok := err.(*SyntaxError); ok {
+				return nil, err
+			}
+			return nil, p.errorAt(path, "%v", err)
+		}
+		for _, def := range mod.exported {
+			name := def.name
+			if alias != "" {
+				name = alias + "::" + name
+			}
+			p.funcs = append(p.funcs, scopedFunc{name: name, arity: len(def.params), def: def})
+		}
+
+		directive := kind.text + " " + converter.QuoteTOMLString(path.text)
+		if alias != "" {
+			directive += " as " + alias
+		}
+		directives = append(directives, directive+";")
+	}
+	return directives, nil
+}
+
+// parseModule parses a module file, which holds directives and
+// definitions only. It returns the functions the module exports, those of
+// its includes first, and the definitions it adds.
+func (p *parser) parseModule() (exported, defs []*funcDef, err error) {
+	first := len(p.funcs)
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, nil, err
+	}
+	for _, fn := range p.funcs[first:] {
+		if !strings.Contains(fn.name, "::") {
+			exported = append(exported, fn.def)
+		}
+	}
+	for isKeyword(p.peek(), "def") {
+		def, err := p.parseDefinition()
+		if err != nil {
+			return nil, nil, err
+		}
+		p.funcs = append(p.funcs, scopedFunc{name: def.name, arity: len(def.params), def: def})
+		defs = append(defs, def)
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, nil, p.errorAt(tok, "expected 'def', got %s", tok)
+	}
+	return append(exported, defs...), defs, nil
+}
+
+func (p *parser) parsePipe() (node, error) {
+	return p.parsePipeOf(commaPrecedence)
+}
+
+// parsePipeOf parses a pipe of binary expressions binding at least as
+// tightly as minPrec
+func (p *parser) parsePipeOf(minPrec int) (node, error) {
+	outer := p.pipeMin
+	p.pipeMin = minPrec
+	defer func() { p.pipeMin = outer }()
+
+	if isKeyword(p.peek(), "def") {
+		return p.parseDefinitions(minPrec)
+	}
+	left, err := p.parseBinary(minPrec)
+	if err != nil {
+		return nil, err
+	}
+	for isPunct(p.peek(), "|") {
+		p.next()
+		if isKeyword(p.peek(), "def") {
+			right, err := p.parseDefinitions(minPrec)
+			if err != nil {
+				return nil, err
+			}
+			return &pipeNode{left: left, right: right}, nil
+		}
+		right, err := p.parseBinary(minPrec)
+		if err != nil {
+			return nil, err
+		}
+		left = &pipeNode{left: left, right: right}
+	}
+	return left, nil
+}
+
+// parseDefinitions parses a definition and the rest of the pipe, in which
+// the function is in scope
+func (p *parser) parseDefinitions(minPrec int) (node, error) {
+	def, err := p.parseDefinition()
+	if err != nil {
+		return nil, err
+	}
+	p.funcs = append(p.funcs, scopedFunc{name: def.name, arity: len(def.params), def: def})
+	rest, err := p.parsePipeOf(minPrec)
+	p.funcs = p.funcs[:len(p.funcs)-1]
+	if err != nil {
+		return nil, err
+	}
+	return &defNode{def: def, rest: rest}, nil
+}
+
+// parseDefinition parses "def name(params): body;". The function is in
+// scope in its own body, so it may call itself.
+func (p *parser) parseDefinition() (*funcDef, error) {
+	p.next() // "def"
+	name := p.next()
+	if name.kind != tokIdent || keywords[name.text] || strings.Contains(name.text, "::") {
+		return nil, p.errorAt(name, "expected function name after 'def', got %s", name)
+	}
+	def := &funcDef{name: name.text}
+	if isPunct(p.peek(), "(") {
+		p.next()
+		for {
+			tok := p.next()
+			switch {
+			case tok.kind == tokVariable:
+				def.params = append(def.params, &funcParam{name: tok.text, variable: true})
+			case tok.kind == tokIdent && !keywords[tok.text] && !strings.Contains(tok.text, "::"):
+				def.params = append(def.params, &funcParam{name: tok.text})
+			default:
+				return nil, p.errorAt(tok, "expected parameter name, got %s", tok)
+			}
+			if !isPunct(p.peek(), ";") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+
+	funcs, vars := len(p.funcs), len(p.vars)
+	p.funcs = append(p.funcs, scopedFunc{name: def.name, arity: len(def.params), def: def})
+	for _, param := range def.params {
+		p.funcs = append(p.funcs, scopedFunc{name: param.name, param: param})
+		if param.variable {
+			p.vars = append(p.vars, param.name)
+		}
+	}
+	body, err := p.parsePipe()
+	p.funcs, p.vars = p.funcs[:funcs], p.vars[:vars]
+	if err != nil {
+		return nil, err
+	}
+	def.body = body
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+	return def, nil
+}
+
+// lookupFunc returns the innermost function in scope with the name and
+// arity
+func (p *parser) lookupFunc(name string, arity int) *scopedFunc {
+	for i := len(p.funcs) - 1; i >= 0; i-- {
+		if fn := &p.funcs[i]; fn.name == name && fn.arity == arity {
+			return fn
+		}
+	}
+	return nil
+}
+
+// binaryOperator returns the binary operator tok stands for and its
+// precedence
+func binaryOperator(tok token) (string, int, bool) {
+	if tok.kind != tokPunct && tok.kind != tokIdent {
+		return "", 0, false
+	}
+	prec, ok := binaryPrecedence[tok.text]
+	return tok.text, prec, ok
+}
+
+// parseBinary parses operators binding at least as tightly as minPrec by
+// precedence climbing
+func (p *parser) parseBinary(minPrec int) (node, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind == tokIdent && tok.text == "as" {
+		return p.parseBinding(left)
+	}
+	for {
+		op, prec, ok := binaryOperator(p.peek())
+		if !ok || prec < minPrec {
+			return left, nil
+		}
+		p.next()
+		rightPrec := prec + 1
+		if rightAssociative[prec] {
+			rightPrec = prec
+		}
+		right, err := p.parseBinary(rightPrec)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+
+		if _, next, ok := binaryOperator(p.peek()); ok && next == prec && nonAssociative[prec] {
+			return nil, p.unexpected(p.peek())
+		}
+	}
+}
+
+// parseBinding parses "as $name | body" after source. The body is the
+// rest of the enclosing pipe.
+func (p *parser) parseBinding(source node) (node, error) {
+	p.next() // "as"
+	tok := p.next()
+	if tok.kind != tokVariable {
+		return nil, p.errorAt(tok, "expected variable after 'as', got %s", tok)
+	}
+	if err := p.expect("|"); err != nil {
+		return nil, err
+	}
+
+	p.vars = append(p.vars, tok.text)
+	body, err := p.parsePipeOf(p.pipeMin)
+	p.vars = p.vars[:len(p.vars)-1]
+	if err != nil {
+		return nil, err
+	}
+	return &bindNode{source: source, name: tok.text, body: body}, nil
+}
+
+// parseVariable returns a reference to the variable tok names
+func (p *parser) parseVariable(tok token) (node, error) {
+	for _, name := range p.vars {
+		if name == tok.text {
+			return &varNode{name: tok.text}, nil
+		}
+	}
+	return nil, p.errorAt(tok, "$%s is not defined", tok.text)
+}
+
+func (p *parser) parseTerm() (node, error) {
+	term, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseSuffixes(term)
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokDot:
+		if p.peek().kind == tokString {
+			return &fieldNode{term: identityNode{}, key: p.next().text}, nil
+		}
+		return identityNode{}, nil
+	case tokField:
+		return &fieldNode{term: identityNode{}, key: tok.text}, nil
+	case tokWildcard:
+		return &iterateNode{term: identityNode{}}, nil
+	case tokRecurse:
+		return recurseNode{}, nil
+	case tokNumber:
+		return &literalNode{value: tok.value}, nil
+	case tokString:
+		return &literalNode{value: tok.text}, nil
+	case tokTemplate:
+		return p.parseTemplate(tok, nil)
+	case tokFormat:
+		return p.parseFormat(tok)
+	case tokVariable:
+		return p.parseVariable(tok)
+	case tokIdent:
+		switch tok.text {
+		case "true", "false":
+			return &literalNode{value: tok.text == "true"}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		case "reduce", "foreach":
+			return p.parseReduction(tok)
+		case "if":
+			return p.parseIf()
+		case "try":
+			return p.parseTry()
+		}
+		if keywords[tok.text] {
+			break
+		}
+		return p.parseCall(tok)
+	case tokPunct:
+		switch tok.text {
+		case "(":
+			n, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return n, nil
+		case "[":
+			return p.parseArray()
+		case "{":
+			return p.parseObject()
+		case "-":
+			if num := p.peek(); num.kind == tokNumber {
+				p.next()
+				return &literalNode{value: negate(num.value)}, nil
+			}
+			term, err := p.parseTerm()
+			if err != nil {
+				return nil, err
+			}
+			return &negateNode{term: term}, nil
+		}
+	}
+	return nil, p.unexpected(tok)
+}
+
+// parseReduction parses "reduce source as $name (init; update)" or
+// "foreach source as $name (init; update; extract)" after the keyword.
+// $name is defined in update and extract.
+func (p *parser) parseReduction(keyword token) (node, error) {
+	source, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); !isKeyword(tok, "as") {
+		return nil, p.errorAt(tok, "expected 'as' after %s source, got %s", keyword.text, tok)
+	}
+	name := p.next()
+	if name.kind != tokVariable {
+		return nil, p.errorAt(name, "expected variable after 'as', got %s", name)
+	}
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	init, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(";"); err != nil {
+		return nil, err
+	}
+
+	p.vars = append(p.vars, name.text)
+	defer func() { p.vars = p.vars[:len(p.vars)-1] }()
+	update, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	var extract node
+	if keyword.text == "foreach" && isPunct(p.peek(), ";") {
+		p.next()
+		if extract, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+
+	if keyword.text == "reduce" {
+		return &reduceNode{source: source, name: name.text, init: init, update: update}, nil
+	}
+	return &foreachNode{source: source, name: name.text, init: init, update: update, extract: extract}, nil
+}
+
+// parseFormat parses "@name", optionally followed by a string whose
+// interpolations it formats
+func (p *parser) parseFormat(tok token) (node, error) {
+	name := tok.text[1:]
+	fn, ok := formats[name]
+	if !ok {
+		return nil, p.errorAt(tok, "%s is not a valid format", tok.text)
+	}
+	format := &formatNode{name: name, format: fn}
+	switch next := p.peek(); next.kind {
+	case tokTemplate:
+		return p.parseTemplate(p.next(), format)
+	case tokString:
+		p.next()
+		return &templateNode{format: format, parts: []templatePart{{text: next.text}}}, nil
+	}
+	return format, nil
+}
+
+// parseTemplate parses the interpolations of a template token. Their
+// queries are parsed in the scope of the string.
+func (p *parser) parseTemplate(tok token, format *formatNode) (node, error) {
+	n := &templateNode{format: format}
+	for _, part := range tok.value.([]stringPart) {
+		if part.end == 0 {
+			if part.text != "" {
+				n.parts = append(n.parts, templatePart{text: part.text})
+			}
+			continue
+		}
+		sub := &parser{
+			src:     p.src,
+			lex:     lexer{src: p.src[:part.end+1], pos: part.start},
+			pipeMin: commaPrecedence,
+			vars:    p.vars,
+			funcs:   p.funcs,
+			file:    p.file,
+			dir:     p.dir,
+			modules: p.modules,
+		}
+		query, err := sub.parsePipe()
+		if err != nil {
+			return nil, err
+		}
+		if err := sub.expect(")"); err != nil {
+			return nil, err
+		}
+		n.parts = append(n.parts, templatePart{query: query})
+	}
+	return n, nil
+}
+
+// parseIf parses "cond then body { elif cond then body } [ else body ] end"
+// after "if"
+func (p *parser) parseIf() (node, error) {
+	cond, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.next(); !isKeyword(tok, "then") {
+		return nil, p.errorAt(tok, "expected 'then', got %s", tok)
+	}
+	then, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	n := &ifNode{cond: cond, then: then}
+
+	switch tok := p.next(); {
+	case isKeyword(tok, "elif"):
+		if n.els, err = p.parseIf(); err != nil {
+			return nil, err
+		}
+	case isKeyword(tok, "else"):
+		if n.els, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+		if tok := p.next(); !isKeyword(tok, "end") {
+			return nil, p.errorAt(tok, "expected 'end', got %s", tok)
+		}
+	case !isKeyword(tok, "end"):
+		return nil, p.errorAt(tok, "expected 'elif', 'else' or 'end', got %s", tok)
+	}
+	return n, nil
+}
+
+// parseTry parses "body [ catch handler ]" after "try". Both are terms,
+// so "try .a catch . | length" takes the length of the result.
+func (p *parser) parseTry() (node, error) {
+	body, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	n := &tryNode{body: body}
+	if isKeyword(p.peek(), "catch") {
+		p.next()
+		if n.handler, err = p.parseTerm(); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// parseArray parses the elements of "[...]" after the opening bracket
+func (p *parser) parseArray() (node, error) {
+	if isPunct(p.peek(), "]") {
+		p.next()
+		return &arrayNode{}, nil
+	}
+	body, err := p.parsePipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return &arrayNode{body: body}, nil
+}
+
+// parseObject parses the entries of "{...}" after the opening brace
+func (p *parser) parseObject() (node, error) {
+	obj := &objectNode{}
+	if isPunct(p.peek(), "}") {
+		p.next()
+		return obj, nil
+	}
+	for {
+		entry, err := p.parseEntry()
+		if err != nil {
+			return nil, err
+		}
+		obj.entries = append(obj.entries, entry)
+		if !isPunct(p.peek(), ",") {
+			break
+		}
+		p.next()
+	}
+	if err := p.expect("}"); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// parseEntry parses "key: value" or the shorthand "key", which stands for
+// "key: .key"
+func (p *parser) parseEntry() (objectEntry, error) {
+	var entry objectEntry
+	tok := p.next()
+	switch {
+	case tok.kind == tokVariable:
+		// {$x} is short for {x: $x}
+		value, err := p.parseVariable(tok)
+		if err != nil {
+			return entry, err
+		}
+		return objectEntry{key: &literalNode{value: tok.text}, value: value}, nil
+	case tok.kind == tokIdent, tok.kind == tokString:
+		entry.key = &literalNode{value: tok.text}
+	case tok.kind == tokTemplate:
+		key, err := p.parseTemplate(tok, nil)
+		if err != nil {
+			return entry, err
+		}
+		entry.key = key
+		entry.computed = true
+	case isPunct(tok, "("):
+		key, err := p.parsePipe()
+		if err != nil {
+			return entry, err
+		}
+		if err := p.expect(")"); err != nil {
+			return entry, err
+		}
+		entry.key = key
+		entry.computed = true
+	default:
+		return entry, p.errorAt(tok, "expected object key, got %s", tok)
+	}
+
+	if !isPunct(p.peek(), ":") {
+		if entry.computed {
+			return entry, p.errorAt(p.peek(), "expected ':', got %s", p.peek())
+		}
+		entry.value = &fieldNode{term: identityNode{}, key: tok.text}
+		return entry, nil
+	}
+	p.next()
+	value, err := p.parsePipeOf(commaPrecedence + 1)
+	if err != nil {
+		return entry, err
+	}
+	entry.value = value
+	return entry, nil
+}
+
+// negate returns the negative of a lexed number
+func negate(v interface{}) interface{} {
+	if i, ok := v.(int64); ok {
+		return -i
+	}
+	return -v.(float64)
+}
+
+// parseCall parses a function call whose name has been consumed
+func (p *parser) parseCall(name token) (node, error) {
+	var args []node
+	if isPunct(p.peek(), "(") {
+		p.next()
+		for {
+			arg, err := p.parsePipe()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if !isPunct(p.peek(), ";") {
+				break
+			}
+			p.next()
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+
+	if fn := p.lookupFunc(name.text, len(args)); fn != nil {
+		if fn.param != nil {
+			return &paramCallNode{param: fn.param}, nil
+		}
+		return &funcCallNode{name: name.text, def: fn.def, args: args}, nil
+	}
+	fn, ok := lookupBuiltin(name.text, len(args))
+	if !ok {
+		return nil, p.errorAt(name, "%s/%d is not defined", name.text, len(args))
+	}
+	return &callNode{name: name.text, args: args, fn: fn}, nil
+}
+
+// parseSuffixes parses the keys, indexes, slices and iterations that
+// follow a term
+func (p *parser) parseSuffixes(term node) (node, error) {
+	for {
+		tok := p.peek()
+		switch {
+		case tok.kind == tokField:
+			p.next()
+			term = &fieldNode{term: term, key: tok.text}
+		case tok.kind == tokWildcard:
+			p.next()
+			term = &iterateNode{term: term}
+		case tok.kind == tokDot:
+			// ". key", ."key" and ".[" after a term; as in TOML, whitespace
+			// is allowed around the dot
+			switch key := p.peekAt(1); {
+			case key.kind == tokString || key.kind == tokIdent:
+				p.next()
+				p.next()
+				term = &fieldNode{term: term, key: key.text}
+			case isPunct(key, "["):
+				p.next()
+			default:
+				return nil, p.errorAt(key, "expected key after '.', got %s", key)
+			}
+		case isPunct(tok, "["):
+			var err error
+			if term, err = p.parseBracket(term); err != nil {
+				return nil, err
+			}
+		case isPunct(tok, "?"):
+			p.next()
+			term = &tryNode{body: term}
+		default:
+			return term, nil
+		}
+	}
+}
+
+// parseBracket parses "[]", "[index]" or "[start:end]" after term
+func (p *parser) parseBracket(term node) (node, error) {
+	p.next()
+	if isPunct(p.peek(), "]") {
+		p.next()
+		return &iterateNode{term: term}, nil
+	}
+
+	var start node
+	if !isPunct(p.peek(), ":") {
+		var err error
+		if start, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+		if !isPunct(p.peek(), ":") {
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			return &indexNode{term: term, index: start}, nil
+		}
+	}
+
+	p.next() // ":"
+	var end node
+	if !isPunct(p.peek(), "]") {
+		var err error
+		if end, err = p.parsePipe(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return &sliceNode{term: term, start: start, end: end}, nil
+}
+
+// parsePath parses a path at the start of the query: "." or a key, ".*"
+// or "." string, followed by suffixes
+func (p *parser) parsePath() (node, error) {
+	switch tok := p.peek(); tok.kind {
+	case tokDot, tokField, tokWildcard:
+	default:
+		return nil, fmt.Errorf("query path cannot be empty")
+	}
+
+	term, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	return p.parseSuffixes(term)
+}
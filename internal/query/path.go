@@ -0,0 +1,102 @@
+package query
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/azolfagharj/tmq/internal/converter"
+)
+
+// Slice is a query path part that selects a range of array elements,
+// as in ".servers[1:3]". A nil Start or End means the beginning or the end
+// of the array.
+type Slice struct {
+	Start *int
+	End   *int
+}
+
+// Iterate is a query path part that produces every element of an array or
+// every value of a table, written ".servers[]" or ".dependencies.*".
+// Table values are produced in key order.
+type Iterate struct{}
+
+// Bounds resolves the slice against an array of the given length.
+// Negative bounds count from the end and out-of-range bounds are clamped,
+// so the result always satisfies 0 <= start <= end <= length.
+func (s Slice) Bounds(length int) (start, end int) {
+	start, end = 0, length
+	if s.Start != nil {
+		start = clampIndex(*s.Start, length)
+	}
+	if s.End != nil {
+		end = clampIndex(*s.End, length)
+	}
+	if end < start {
+		end = start
+	}
+	return start, end
+}
+
+// String returns the slice in path syntax, e.g. "[1:3]"
+func (s Slice) String() string {
+	var b strings.Builder
+	b.WriteByte('[')
+	if s.Start != nil {
+		b.WriteString(strconv.Itoa(*s.Start))
+	}
+	b.WriteByte(':')
+	if s.End != nil {
+		b.WriteString(strconv.Itoa(*s.End))
+	}
+	b.WriteByte(']')
+	return b.String()
+}
+
+// clampIndex normalizes a possibly negative slice bound into [0, length]
+func clampIndex(i, length int) int {
+	if i < 0 {
+		i += length
+	}
+	if i < 0 {
+		return 0
+	}
+	if i > length {
+		return length
+	}
+	return i
+}
+
+// FormatPath renders path parts in query syntax, e.g. ".servers[0].name".
+// Keys that are not valid TOML bare keys are quoted.
+func FormatPath(parts []interface{}) string {
+	if len(parts) == 0 {
+		return "."
+	}
+
+	var b strings.Builder
+	for i, part := range parts {
+		switch p := part.(type) {
+		case string:
+			b.WriteString("." + converter.FormatTOMLKey(p))
+		case int:
+			if i == 0 {
+				b.WriteByte('.')
+			}
+			fmt.Fprintf(&b, "[%d]", p)
+		case Slice:
+			if i == 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString(p.String())
+		case Iterate:
+			if i == 0 {
+				b.WriteByte('.')
+			}
+			b.WriteString("[]")
+		default:
+			fmt.Fprintf(&b, ".%v", p)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,532 @@
+package query
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// pathValue is a result of a path expression: a value and where it is in
+// the input, as parts like those of [Query.Parts]
+type pathValue struct {
+	path  []interface{}
+	value interface{}
+}
+
+// pathEmitFunc receives the results of a path expression
+type pathEmitFunc func(pathValue) error
+
+// pathEvaluator is implemented by the nodes that can run as path
+// expressions, which report where their results are in the input
+type pathEvaluator interface {
+	evalPaths(env *environment, in pathValue, emit pathEmitFunc) error
+}
+
+// pathBuiltinFunc implements a builtin as a path expression
+type pathBuiltinFunc func(env *environment, in pathValue, args []node, emit pathEmitFunc) error
+
+// pathBuiltins maps "name/arity" to the path expression form of the
+// builtins that select parts of their input
+var pathBuiltins = map[string]pathBuiltinFunc{
+	"select/1":  pathSelect,
+	"first/0":   pathElement(0),
+	"last/0":    pathElement(-1),
+	"first/1":   pathFirst,
+	"last/1":    pathLast,
+	"getpath/1": pathGetpath,
+	"recurse/0": pathRecurse,
+	"recurse/1": pathRecurse,
+	"recurse/2": pathRecurse,
+}
+
+// evalPaths runs n as a path expression. Nodes that compute new values
+// rather than select parts of the input fail with their first result.
+func evalPaths(env *environment, n node, in pathValue, emit pathEmitFunc) error {
+	if p, ok := n.(pathEvaluator); ok {
+		return p.evalPaths(env, in, emit)
+	}
+	return rejectPaths(env, n, in)
+}
+
+// rejectPaths fails with the first result of n, which is not a path
+func rejectPaths(env *environment, n node, in pathValue) error {
+	return n.eval(env, in.value, func(v interface{}) error {
+		return fmt.Errorf("invalid path expression with result %s", formatValue(v))
+	})
+}
+
+// appendPart returns path extended with part, leaving path alone
+func appendPart(path []interface{}, part interface{}) []interface{} {
+	return append(path[:len(path):len(path)], part)
+}
+
+func (identityNode) evalPaths(_ *environment, in pathValue, emit pathEmitFunc) error {
+	return emit(in)
+}
+
+func (n *fieldNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	return evalPaths(env, n.term, in, func(pv pathValue) error {
+		return emitKeyPath(pv, n.key, emit)
+	})
+}
+
+// emitKeyPath emits the path of key in the value of pv. Missing keys have
+// a path and a null value, so that they can be set.
+func emitKeyPath(pv pathValue, key string, emit pathEmitFunc) error {
+	value, err := getPart(pv.value, key)
+	if err != nil {
+		return err
+	}
+	return emit(pathValue{path: appendPart(pv.path, key), value: value})
+}
+
+func (n *indexNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	return evalPaths(env, n.term, in, func(pv pathValue) error {
+		return n.index.eval(env, in.value, func(index interface{}) error {
+			if key, ok := index.(string); ok {
+				return emitKeyPath(pv, key, emit)
+			}
+			i, ok := toInt(index)
+			if !ok {
+				return fmt.Errorf("cannot index %s with %s", typeName(pv.value), typeName(index))
+			}
+			value, err := getPart(pv.value, i)
+			if err != nil {
+				return err
+			}
+			return emit(pathValue{path: appendPart(pv.path, i), value: value})
+		})
+	})
+}
+
+func (n *sliceNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	return evalPaths(env, n.term, in, func(pv pathValue) error {
+		return evalBound(env, n.start, in.value, func(start *int) error {
+			return evalBound(env, n.end, in.value, func(end *int) error {
+				part := Slice{Start: start, End: end}
+				value, err := getPart(pv.value, part)
+				if err != nil {
+					return err
+				}
+				return emit(pathValue{path: appendPart(pv.path, part), value: value})
+			})
+		})
+	})
+}
+
+func (n *iterateNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	return evalPaths(env, n.term, in, func(pv pathValue) error {
+		return emitChildPaths(pv, emit)
+	})
+}
+
+// emitChildPaths emits the path of every element of an array or value of
+// a table, in the order of [iterate]
+func emitChildPaths(pv pathValue, emit pathEmitFunc) error {
+	if table := asTable(pv.value); table != nil {
+		for _, key := range sortedKeys(table) {
+			if err := emit(pathValue{path: appendPart(pv.path, key), value: table[key]}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	values, err := iterate(pv.value)
+	if err != nil {
+		return err
+	}
+	for i, value := range values {
+		if err := emit(pathValue{path: appendPart(pv.path, i), value: value}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (n *pipeNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	return evalPaths(env, n.left, in, func(pv pathValue) error {
+		return evalPaths(env, n.right, pv, emit)
+	})
+}
+
+func (n *binaryNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	switch n.op {
+	case ",":
+		if err := evalPaths(env, n.left, in, emit); err != nil {
+			return err
+		}
+		return evalPaths(env, n.right, in, emit)
+	case "//":
+		found := false
+		var emitErr error
+		_ = evalPaths(env, n.left, in, func(pv pathValue) error {
+			if !isTruthy(pv.value) {
+				return nil
+			}
+			found = true
+			emitErr = emit(pv)
+			return emitErr
+		})
+		if emitErr != nil || found {
+			return emitErr
+		}
+		return evalPaths(env, n.right, in, emit)
+	default:
+		return rejectPaths(env, n, in)
+	}
+}
+
+func (n *tryNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	var emitErr error
+	caught := evalPaths(env, n.body, in, func(pv pathValue) error {
+		emitErr = emit(pv)
+		return emitErr
+	})
+	if emitErr != nil || caught == nil || n.handler == nil {
+		return emitErr
+	}
+	// The handler runs against the error rather than the input, so it
+	// cannot select parts of the input
+	return rejectPaths(env, n.handler, pathValue{value: errorValue(caught)})
+}
+
+func (n *ifNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	return n.cond.eval(env, in.value, func(cond interface{}) error {
+		switch {
+		case isTruthy(cond):
+			return evalPaths(env, n.then, in, emit)
+		case n.els != nil:
+			return evalPaths(env, n.els, in, emit)
+		default:
+			return emit(in)
+		}
+	})
+}
+
+func (n *callNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	fn, ok := pathBuiltins[fmt.Sprintf("%s/%d", n.name, len(n.args))]
+	if !ok {
+		return rejectPaths(env, n, in)
+	}
+	return fn(env, in, n.args, emit)
+}
+
+func (n *bindNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	return n.source.eval(env, in.value, func(v interface{}) error {
+		return evalPaths(env.bind(n.name, v), n.body, in, emit)
+	})
+}
+
+func (n *defNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	return evalPaths(env.bind(n.def, nil), n.rest, in, emit)
+}
+
+func (n *moduleNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	for _, def := range n.defs {
+		env = env.bind(def, nil)
+	}
+	return evalPaths(env, n.body, in, emit)
+}
+
+func (n *funcCallNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	return n.call(env, in.value, func(scope *environment) error {
+		return evalPaths(scope, n.def.body, in, emit)
+	})
+}
+
+func (n *paramCallNode) evalPaths(env *environment, in pathValue, emit pathEmitFunc) error {
+	arg, err := n.closure(env)
+	if err != nil {
+		return err
+	}
+	return evalPaths(arg.env, arg.body, in, emit)
+}
+
+// pathSelect passes the path of its input through for every truthy result
+// of the condition
+func pathSelect(env *environment, in pathValue, args []node, emit pathEmitFunc) error {
+	return args[0].eval(env, in.value, func(cond interface{}) error {
+		if !isTruthy(cond) {
+			return nil
+		}
+		return emit(in)
+	})
+}
+
+// pathElement returns the path form of first or last, which are .[0] and
+// .[-1]
+func pathElement(index int) pathBuiltinFunc {
+	return func(_ *environment, in pathValue, _ []node, emit pathEmitFunc) error {
+		value, err := getPart(in.value, index)
+		if err != nil {
+			return err
+		}
+		return emit(pathValue{path: appendPart(in.path, index), value: value})
+	}
+}
+
+// pathFirst emits the first path of f
+func pathFirst(env *environment, in pathValue, args []node, emit pathEmitFunc) error {
+	stop := &stopSignal{}
+	err := evalPaths(env, args[0], in, func(pv pathValue) error {
+		if err := emit(pv); err != nil {
+			return err
+		}
+		return stop
+	})
+	if err == stop {
+		return nil
+	}
+	return err
+}
+
+// pathLast emits the last path of f
+func pathLast(env *environment, in pathValue, args []node, emit pathEmitFunc) error {
+	var last *pathValue
+	err := evalPaths(env, args[0], in, func(pv pathValue) error {
+		last = &pv
+		return nil
+	})
+	if err != nil || last == nil {
+		return err
+	}
+	return emit(*last)
+}
+
+// pathGetpath extends the path of its input with each path argument
+func pathGetpath(env *environment, in pathValue, args []node, emit pathEmitFunc) error {
+	return args[0].eval(env, in.value, func(p interface{}) error {
+		parts, err := valueToPath(p)
+		if err != nil {
+			return err
+		}
+		value, err := getPath(in.value, parts)
+		if err != nil {
+			return err
+		}
+		return emit(pathValue{path: append(in.path[:len(in.path):len(in.path)], parts...), value: value})
+	})
+}
+
+// getPart returns the value at one path part, null when the part is
+// missing
+func getPart(container interface{}, part interface{}) (interface{}, error) {
+	if container == nil {
+		return nil, nil
+	}
+	switch p := part.(type) {
+	case string:
+		if table := asTable(container); table != nil {
+			return table[p], nil
+		}
+		return lookupKey(container, p)
+	case int:
+		value, err := lookupIndex(container, p)
+		if err != nil && isArray(container) {
+			// Out of range
+			return nil, nil
+		}
+		return value, err
+	case Slice:
+		return lookupSlice(container, p)
+	default:
+		return nil, fmt.Errorf("unsupported path part %T", part)
+	}
+}
+
+// isArray reports whether v is a TOML array, including arrays of tables
+func isArray(v interface{}) bool {
+	switch v.(type) {
+	case []interface{}, []map[string]interface{}:
+		return true
+	default:
+		return false
+	}
+}
+
+// getPath returns the value at the path, null when part of it is missing
+func getPath(value interface{}, parts []interface{}) (interface{}, error) {
+	for _, part := range parts {
+		var err error
+		if value, err = getPart(value, part); err != nil {
+			return nil, err
+		}
+	}
+	return value, nil
+}
+
+// pathToValue converts path parts to a path array of the query language:
+// keys are strings, indexes integers and slices {"start": s, "end": e}
+// tables, as in jq
+func pathToValue(parts []interface{}) []interface{} {
+	result := make([]interface{}, len(parts))
+	for i, part := range parts {
+		switch p := part.(type) {
+		case int:
+			result[i] = int64(p)
+		case Slice:
+			slice := map[string]interface{}{"start": nil, "end": nil}
+			if p.Start != nil {
+				slice["start"] = int64(*p.Start)
+			}
+			if p.End != nil {
+				slice["end"] = int64(*p.End)
+			}
+			result[i] = slice
+		case Iterate:
+			// Iteration has no jq representation; it only comes from
+			// modifier paths, which are converted for sorting
+			result[i] = nil
+		default:
+			result[i] = part
+		}
+	}
+	return result
+}
+
+// valueToPath converts a path array of the query language to path parts
+func valueToPath(v interface{}) ([]interface{}, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("path must be an array, got %s", typeName(v))
+	}
+	parts := make([]interface{}, len(items))
+	for i, item := range items {
+		switch p := item.(type) {
+		case string:
+			parts[i] = p
+		case map[string]interface{}:
+			var slice Slice
+			for key, bound := range map[string]**int{"start": &slice.Start, "end": &slice.End} {
+				if p[key] == nil {
+					continue
+				}
+				n, ok := toInt(p[key])
+				if !ok {
+					return nil, fmt.Errorf("slice %s must be a number, got %s", key, typeName(p[key]))
+				}
+				*bound = &n
+			}
+			parts[i] = slice
+		default:
+			n, ok := toInt(item)
+			if !ok {
+				return nil, fmt.Errorf("invalid path component %s", formatValue(item))
+			}
+			parts[i] = n
+		}
+	}
+	return parts, nil
+}
+
+// formatValue renders a value for error messages, shortening long ones
+func formatValue(v interface{}) string {
+	const maxLength = 40
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return typeName(v)
+	}
+	if len(encoded) > maxLength {
+		return string(encoded[:maxLength-3]) + "..."
+	}
+	return string(encoded)
+}
+
+// funcPath emits the path of every result of f
+func funcPath(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return evalPaths(env, args[0], pathValue{path: []interface{}{}, value: in}, func(pv pathValue) error {
+		return emit(pathToValue(pv.path))
+	})
+}
+
+// walkPaths emits everything inside the value of pv, each table or array
+// before its contents
+func walkPaths(pv pathValue, emit pathEmitFunc) error {
+	if !isArray(pv.value) && asTable(pv.value) == nil {
+		return nil
+	}
+	return emitChildPaths(pv, func(child pathValue) error {
+		if err := emit(child); err != nil {
+			return err
+		}
+		return walkPaths(child, emit)
+	})
+}
+
+// funcPaths emits the path of everything in its input or, given a filter,
+// of every value for which the filter is true
+func funcPaths(env *environment, in interface{}, args []node, emit emitFunc) error {
+	return walkPaths(pathValue{path: []interface{}{}, value: in}, func(pv pathValue) error {
+		if len(args) == 0 {
+			return emit(pathToValue(pv.path))
+		}
+		return args[0].eval(env, pv.value, func(cond interface{}) error {
+			if !isTruthy(cond) {
+				return nil
+			}
+			return emit(pathToValue(pv.path))
+		})
+	})
+}
+
+// funcLeafPaths emits the paths of the values in its input that are
+// neither tables nor arrays
+func funcLeafPaths(_ *environment, in interface{}, _ []node, emit emitFunc) error {
+	return walkPaths(pathValue{path: []interface{}{}, value: in}, func(pv pathValue) error {
+		if isArray(pv.value) || asTable(pv.value) != nil {
+			return nil
+		}
+		return emit(pathToValue(pv.path))
+	})
+}
+
+// funcGetpath returns the value at a path, null when part of it is missing
+func funcGetpath(in interface{}, args []interface{}) (interface{}, error) {
+	parts, err := valueToPath(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return getPath(in, parts)
+}
+
+// funcSetpath returns its input with a value stored at a path
+func funcSetpath(in interface{}, args []interface{}) (interface{}, error) {
+	parts, err := valueToPath(args[0])
+	if err != nil {
+		return nil, err
+	}
+	return SetPath(in, parts, args[1])
+}
+
+// funcDelpaths returns its input without the values at an array of paths
+func funcDelpaths(in interface{}, args []interface{}) (interface{}, error) {
+	if !isArray(args[0]) {
+		return nil, fmt.Errorf("delpaths expects an array of paths, got %s", typeName(args[0]))
+	}
+	values, _ := iterate(args[0])
+	paths := make([][]interface{}, len(values))
+	for i, value := range values {
+		parts, err := valueToPath(value)
+		if err != nil {
+			return nil, err
+		}
+		paths[i] = parts
+	}
+	return DeletePaths(in, paths)
+}
+
+// funcDel returns its input without the values f selects
+func funcDel(env *environment, in interface{}, args []node, emit emitFunc) error {
+	var paths [][]interface{}
+	err := evalPaths(env, args[0], pathValue{path: []interface{}{}, value: in}, func(pv pathValue) error {
+		paths = append(paths, pv.path)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	result, err := DeletePaths(in, paths)
+	if err != nil {
+		return err
+	}
+	return emit(result)
+}
@@ -5,72 +5,178 @@ import (
 	"strings"
 )
 
-// Query represents a parsed query path
+// Query is a compiled query program
 type Query struct {
-	parts []string
+	root node
+	env  *environment
 }
 
-// New creates a new query from a dot-separated path string
+// New compiles a query such as ".servers[0].name" or
+// ".tool.poetry | .dependencies | keys". Keys follow TOML rules, so quoted
+// keys like .tool."black-config" work. Malformed queries are reported as a
+// [*SyntaxError] locating the offending token.
 func New(path string) (*Query, error) {
+	return NewWithVariables(path, nil)
+}
+
+// NewWithVariables compiles a query in which vars are defined, so that
+// ".version == $v" compares against the value of vars["v"]. Values
+// should have the types the TOML decoder produces.
+func NewWithVariables(path string, vars map[string]interface{}) (*Query, error) {
+	return NewWithOptions(path, Options{Variables: vars})
+}
+
+// Options configures the compilation of a query
+type Options struct {
+	// Variables are defined in the query, as in [NewWithVariables]
+	Variables map[string]interface{}
+	// SearchPath lists the directories searched, in order, for the
+	// modules named by import and include directives
+	SearchPath []string
+}
+
+// NewWithOptions compiles a query with the given options. Modules are read
+// while the query is compiled; errors in them are reported as a
+// [*SyntaxError] naming the module file.
+func NewWithOptions(path string, opts Options) (*Query, error) {
 	if path == "" {
 		return nil, fmt.Errorf("query path cannot be empty")
 	}
 
-	// Remove leading dot if present
-	path = strings.TrimPrefix(path, ".")
+	p := newParser(path, variableNames(opts.Variables)...)
+	p.modules = newModuleLoader(opts.SearchPath)
+	root, err := p.parseProgram()
+	if err != nil {
+		return nil, err
+	}
+	return &Query{root: root, env: newEnvironment(opts.Variables)}, nil
+}
+
+// variableNames returns the names of vars, in no particular order
+func variableNames(vars map[string]interface{}) []string {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	return names
+}
 
-	if path == "" {
-		// Root query (just ".")
-		return &Query{parts: []string{}}, nil
+// ParsePrefix parses the path at the start of s and returns it along with
+// the rest of s, starting right after the path. It lets other packages
+// embed paths in larger expressions, such as the left-hand side of a set
+// expression. Only plain paths with constant keys and indexes are accepted.
+// As in a TOML file, the leading dot may be left out: title = "x" sets
+// .title.
+func ParsePrefix(s string) (*Query, string, error) {
+	if trimmed := strings.TrimLeft(s, " \t"); startsBareKey(trimmed) {
+		return ParsePrefix("." + trimmed)
 	}
 
-	parts := strings.Split(path, ".")
-	return &Query{parts: parts}, nil
+	p := newParser(s)
+	root, err := p.parsePath()
+	if err != nil {
+		return nil, "", err
+	}
+	if _, ok := staticPath(root); !ok {
+		return nil, "", fmt.Errorf("path '%s' must use constant keys and indexes", root)
+	}
+	return &Query{root: root}, s[p.lastEnd:], nil
 }
 
-// Execute runs the query against the provided TOML data
-func (q *Query) Execute(data interface{}) (interface{}, error) {
-	if data == nil {
-		return nil, fmt.Errorf("data cannot be nil")
+// startsBareKey reports whether s starts with a TOML key rather than a
+// path: a letter, an underscore or a quote
+func startsBareKey(s string) bool {
+	if s == "" {
+		return false
 	}
+	c := s[0]
+	return c == '_' || c == '"' || c == '\'' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
 
-	current := data
+// AssignmentOperator returns the operator of s when s is an assignment,
+// such as "=" for ".version = 2.0" or "+=" for ".build += 1", and "" when
+// it is not. s is parsed, so "==" and "=" inside strings are told apart
+// from assignments, and an assignment that is only part of a query, as in
+// ".a |= 1 | .a", does not count. When s is not a valid query, only its
+// left-hand side is parsed: the right-hand side of "=" may be a TOML value
+// rather than a query, and the key of "=" may leave out the leading dot,
+// as in [ParsePrefix]. vars are the variables s may use, as in
+// [NewWithVariables].
+func AssignmentOperator(s string, vars map[string]interface{}) string {
+	whole := newParser(s, variableNames(vars)...)
+	whole.modules = newModuleLoader(nil)
+	if root, err := whole.parseProgram(); err == nil {
+		if n, ok := root.(*binaryNode); ok && binaryPrecedence[n.op] == assignPrecedence {
+			return n.op
+		}
+		return ""
+	}
 
-	// Navigate through the path parts
-	for _, part := range q.parts {
-		switch v := current.(type) {
-		case map[string]interface{}:
-			var ok bool
-			current, ok = v[part]
-			if !ok {
-				return nil, fmt.Errorf("key '%s' not found", part)
-			}
-		case map[interface{}]interface{}:
-			// Handle cases where TOML parser returns map[interface{}]interface{}
-			var ok bool
-			current, ok = v[part]
-			if !ok {
-				return nil, fmt.Errorf("key '%s' not found", part)
+	p := newParser(s, variableNames(vars)...)
+	if _, err := p.parseBinary(assignPrecedence + 1); err != nil {
+		// A set expression may name its key without the leading dot
+		if _, rest, err := ParsePrefix(s); err == nil {
+			rest = strings.TrimSpace(rest)
+			if strings.HasPrefix(rest, "=") && !strings.HasPrefix(rest, "==") {
+				return "="
 			}
-		case []interface{}:
-			return nil, fmt.Errorf("cannot navigate into array at '%s'", part)
-		default:
-			return nil, fmt.Errorf("cannot navigate into %T at '%s'", current, part)
 		}
+		return ""
+	}
+	if op, prec, ok := binaryOperator(p.peek()); ok && prec == assignPrecedence {
+		return op
+	}
+	return ""
+}
+
+// Execute runs the query against the provided TOML data and returns every
+// result it produces, in order. Plain paths produce exactly one result;
+// iteration ("[]" and ".*") produces one result per element.
+func (q *Query) Execute(data interface{}) ([]interface{}, error) {
+	if data == nil {
+		return nil, fmt.Errorf("data cannot be nil")
 	}
 
-	return current, nil
+	var results []interface{}
+	err := q.root.eval(q.env, data, func(v interface{}) error {
+		results = append(results, v)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
 }
 
-// String returns the string representation of the query
-func (q *Query) String() string {
-	if len(q.parts) == 0 {
-		return "."
+// Paths runs the query as a path expression against data and returns
+// where each result is, as parts like those of [Query.Parts]. This is how del
+// finds what to delete. Queries that compute new values rather than
+// select parts of data, such as ".a + 1", fail.
+func (q *Query) Paths(data interface{}) ([][]interface{}, error) {
+	var paths [][]interface{}
+	err := evalPaths(q.env, q.root, pathValue{path: []interface{}{}, value: data}, func(pv pathValue) error {
+		paths = append(paths, pv.path)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
-	return "." + strings.Join(q.parts, ".")
+	return paths, nil
 }
 
-// Parts returns the individual parts of the query path
-func (q *Query) Parts() []string {
-	return q.parts
+// String returns the query in canonical syntax
+func (q *Query) String() string {
+	return q.root.String()
+}
+
+// Parts returns the individual parts of the query when it is a plain path.
+// Each part is a string (table key), an int (array index, negative counting
+// from the end), a [Slice] or an [Iterate]. Parts returns nil for queries
+// that are not plain paths, such as pipelines and function calls.
+func (q *Query) Parts() []interface{} {
+	parts, ok := staticPath(q.root)
+	if !ok {
+		return nil
+	}
+	return parts
 }
@@ -0,0 +1,147 @@
+package query
+
+import (
+	"math"
+	"testing"
+)
+
+func createArithmeticTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"port":    int64(8080),
+		"ratio":   0.5,
+		"name":    "web",
+		"version": "2.31.0",
+		"tags":    []interface{}{"a", "b", "a", "c"},
+		"servers": []map[string]interface{}{
+			{"name": "web1", "port": int64(80)},
+			{"name": "web2", "port": int64(81)},
+		},
+		"base": map[string]interface{}{
+			"db":  map[string]interface{}{"host": "localhost", "port": int64(5432)},
+			"env": "dev",
+		},
+		"override": map[string]interface{}{
+			"db": map[string]interface{}{"host": "db.internal"},
+		},
+	}
+}
+
+func TestExecute_Arithmetic(t *testing.T) {
+	data := createArithmeticTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected interface{}
+		errMsg   string
+	}{
+		// Integers stay integers
+		{name: "add integers", query: ".port + 1", expected: int64(8081)},
+		{name: "subtract integers", query: ".port - 80", expected: int64(8000)},
+		{name: "multiply integers", query: ".port * 2", expected: int64(16160)},
+		{name: "exact integer division", query: ".port / 8", expected: int64(1010)},
+		{name: "inexact integer division", query: "7 / 2", expected: 3.5},
+		{name: "modulo", query: ".port % 1000", expected: int64(80)},
+		{name: "modulo truncates floats", query: "-5.5 % 2", expected: int64(-1)},
+		{name: "mixed integer and float", query: ".port * .ratio", expected: 4040.0},
+		{name: "overflow becomes float", query: "9223372036854775807 + 1", expected: 9223372036854775808.0},
+		{name: "multiplication overflow", query: "4611686018427387904 * 4", expected: 18446744073709551616.0},
+		{name: "negation", query: "-.port", expected: int64(-8080)},
+		{name: "negation of expression", query: "-(.port - 8000)", expected: int64(-80)},
+		{name: "negate string", query: "-.name", errMsg: "string cannot be negated"},
+
+		// Precedence
+		{name: "multiplication binds tighter", query: "1 + 2 * 3", expected: int64(7)},
+		{name: "left associative", query: "10 - 4 - 3", expected: int64(3)},
+		{name: "parentheses", query: "(1 + 2) * 3", expected: int64(9)},
+		{name: "arithmetic before comparison", query: ".port + 1 > 8080", expected: true},
+		{name: "subtracting a negative", query: "1 - -1", expected: int64(2)},
+		{name: "no space before minus", query: ".port -80", expected: int64(8000)},
+
+		// Division errors
+		{name: "division by zero", query: ".port / 0", errMsg: "8080 and 0 cannot be divided because the divisor is zero"},
+		{name: "modulo by zero", query: ".port % 0", errMsg: "because the divisor is zero"},
+		{name: "modulo of string", query: ".name % 2", errMsg: "string and number cannot be divided"},
+
+		// Strings, arrays and tables
+		{name: "string concatenation", query: `.name + "-" + .version`, expected: "web-2.31.0"},
+		{name: "string repetition", query: `"ab" * 3`, expected: "ababab"},
+		{name: "string repetition below one", query: `"ab" * 0`, expected: nil},
+		{name: "empty string repetition", query: `"" * 1e19`, expected: ""},
+		{name: "string repetition too long", query: `"ab" * 1e19`, errMsg: "string repeated 1e+19 times is too long"},
+		{name: "string repetition of a huge float", query: `1e300 * "ab"`, errMsg: "is too long"},
+		{name: "string repetition of infinity", query: `"ab" * ("inf" | fromtoml)`, errMsg: "is too long"},
+		{name: "string repetition of nan", query: `"ab" * ("nan" | fromtoml)`, expected: nil},
+		{name: "string division splits", query: `.version / "."`, expected: []interface{}{"2", "31", "0"}},
+		{name: "array concatenation", query: `.tags + .tags | length`, expected: int64(8)},
+		{name: "array of tables concatenation", query: `.servers + .servers | map(.port)`, expected: []interface{}{int64(80), int64(81), int64(80), int64(81)}},
+		{name: "array subtraction", query: `.tags - (.tags | .[0:1])`, expected: []interface{}{"b", "c"}},
+		{
+			name:     "shallow merge",
+			query:    ".base + .override",
+			expected: map[string]interface{}{"db": map[string]interface{}{"host": "db.internal"}, "env": "dev"},
+		},
+		{
+			name:  "deep merge",
+			query: ".base * .override",
+			expected: map[string]interface{}{
+				"db":  map[string]interface{}{"host": "db.internal", "port": int64(5432)},
+				"env": "dev",
+			},
+		},
+		{name: "null is the identity", query: ".port + null", expected: int64(8080)},
+		{name: "string plus number", query: `.name + 1`, errMsg: "string and number cannot be added"},
+		{name: "table minus table", query: `.base - .override`, errMsg: "table and table cannot be subtracted"},
+		{name: "array times number", query: `.tags * 2`, errMsg: "array and number cannot be multiplied"},
+
+		// Math functions
+		{name: "floor", query: "3.7 | floor", expected: int64(3)},
+		{name: "ceil", query: "3.2 | ceil", expected: int64(4)},
+		{name: "round", query: "-2.5 | round", expected: int64(-3)},
+		{name: "floor of integer", query: ".port | floor", expected: int64(8080)},
+		{name: "floor out of int64 range", query: "1e300 | floor", expected: 1e300},
+		{name: "sqrt", query: "16 | sqrt", expected: 4.0},
+		{name: "log", query: "1 | log", expected: 0.0},
+		{name: "fabs of integer", query: "-3 | fabs", expected: int64(3)},
+		{name: "fabs of float", query: "-2.5 | fabs", expected: 2.5},
+		{name: "pow of integers", query: "pow(2; 10)", expected: int64(1024)},
+		{name: "pow with negative exponent", query: "pow(2; -1)", expected: 0.5},
+		{name: "pow overflow", query: "pow(2; 64)", expected: math.Pow(2, 64)},
+		{name: "pow of floats", query: "pow(.ratio; 2)", expected: 0.25},
+		{name: "sqrt of string", query: ".name | sqrt", errMsg: "sqrt cannot be applied to string, expected a number"},
+		{name: "pow of string", query: `pow("a"; 2)`, errMsg: "pow cannot be applied to string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.errMsg != "" {
+				assertResults(t, tt.query, data, nil, tt.errMsg)
+				return
+			}
+			assertResults(t, tt.query, data, []interface{}{tt.expected}, "")
+		})
+	}
+}
+
+func TestNew_ArithmeticString(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantStr string
+	}{
+		{query: "1+2*3", wantStr: "1 + 2 * 3"},
+		{query: "(1+2)*3", wantStr: "(1 + 2) * 3"},
+		{query: "1-(2-3)", wantStr: "1 - (2 - 3)"},
+		{query: "-(.a + 1)", wantStr: "-(.a + 1)"},
+		{query: "-.a.b", wantStr: "-.a.b"},
+		{query: ".a + 1 == 2 and true", wantStr: ".a + 1 == 2 and true"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, false, "")
+			if q != nil {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package query
+
+import (
+	"testing"
+)
+
+// createArrayTestData mirrors what the TOML parser produces for plain
+// arrays ([]interface{}) and arrays of tables ([]map[string]interface{})
+func createArrayTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"ports": []interface{}{int64(8080), int64(8443), int64(9000)},
+		"servers": []map[string]interface{}{
+			{"name": "web1", "ip": "192.168.1.1"},
+			{"name": "web2", "ip": "192.168.1.2"},
+			{"name": "db1", "ip": "192.168.1.10"},
+		},
+		"matrix": []interface{}{
+			[]interface{}{int64(1), int64(2)},
+			[]interface{}{int64(3), int64(4)},
+		},
+	}
+}
+
+func TestExecute_ArrayIndexing(t *testing.T) {
+	data := createArrayTestData()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected interface{}
+		wantErr  bool
+		errMsg   string
+	}{
+		{name: "first element", path: ".ports[0]", expected: int64(8080)},
+		{name: "last element", path: ".ports[2]", expected: int64(9000)},
+		{name: "negative index", path: ".ports[-1]", expected: int64(9000)},
+		{name: "array of tables", path: ".servers[1].name", expected: "web2"},
+		{name: "array of tables negative", path: ".servers[-1].ip", expected: "192.168.1.10"},
+		{name: "nested arrays", path: ".matrix[1][0]", expected: int64(3)},
+		{name: "dot before bracket", path: ".ports.[1]", expected: int64(8443)},
+		{name: "index out of range", path: ".ports[3]", wantErr: true, errMsg: "index 3 out of range"},
+		{name: "negative out of range", path: ".ports[-4]", wantErr: true, errMsg: "index -4 out of range"},
+		{name: "index into table", path: ".servers[0][0]", wantErr: true, errMsg: "cannot navigate into"},
+		{name: "key into array", path: ".servers.name", wantErr: true, errMsg: "cannot navigate into array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(tt.path)
+			if err != nil {
+				t.Fatalf("failed to create query: %v", err)
+			}
+
+			assertQueryExecution(t, q, data, tt.wantErr, tt.errMsg, tt.expected)
+		})
+	}
+}
+
+func TestExecute_ArraySlicing(t *testing.T) {
+	data := createArrayTestData()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected interface{}
+	}{
+		{name: "middle slice", path: ".ports[1:3]", expected: []interface{}{int64(8443), int64(9000)}},
+		{name: "open start", path: ".ports[:1]", expected: []interface{}{int64(8080)}},
+		{name: "open end", path: ".ports[1:]", expected: []interface{}{int64(8443), int64(9000)}},
+		{name: "negative bounds", path: ".ports[-2:]", expected: []interface{}{int64(8443), int64(9000)}},
+		{name: "clamped bounds", path: ".ports[1:10]", expected: []interface{}{int64(8443), int64(9000)}},
+		{name: "empty slice", path: ".ports[2:1]", expected: []interface{}{}},
+		{
+			name: "array of tables keeps shape",
+			path: ".servers[:2]",
+			expected: []map[string]interface{}{
+				{"name": "web1", "ip": "192.168.1.1"},
+				{"name": "web2", "ip": "192.168.1.2"},
+			},
+		},
+		{name: "index after slice", path: ".servers[1:][0].name", expected: "web2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(tt.path)
+			if err != nil {
+				t.Fatalf("failed to create query: %v", err)
+			}
+
+			assertQueryExecution(t, q, data, false, "", tt.expected)
+		})
+	}
+}
+
+func TestNew_ArrayPaths(t *testing.T) {
+	one, three, minusTwo := 1, 3, -2
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedParts []interface{}
+		wantStr       string
+	}{
+		{name: "index", path: ".servers[0].name", expectedParts: []interface{}{"servers", 0, "name"}, wantStr: ".servers[0].name"},
+		{name: "negative index", path: ".ports[-1]", expectedParts: []interface{}{"ports", -1}, wantStr: ".ports[-1]"},
+		{name: "root index", path: ".[0]", expectedParts: []interface{}{0}, wantStr: ".[0]"},
+		{name: "slice", path: ".ports[1:3]", expectedParts: []interface{}{"ports", Slice{Start: &one, End: &three}}, wantStr: ".ports[1:3]"},
+		{name: "open slice", path: ".ports[-2:]", expectedParts: []interface{}{"ports", Slice{Start: &minusTwo}}, wantStr: ".ports[-2:]"},
+		{name: "numeric key", path: ".a.0", expectedParts: []interface{}{"a", "0"}, wantStr: ".a.0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.path, false, "")
+			if q == nil {
+				return
+			}
+			assertQueryString(t, q, tt.wantStr)
+
+			parts := q.Parts()
+			if len(parts) != len(tt.expectedParts) {
+				t.Fatalf("expected %d parts, got %d (%v)", len(tt.expectedParts), len(parts), parts)
+			}
+			for i, part := range parts {
+				if FormatPath([]interface{}{part}) != FormatPath([]interface{}{tt.expectedParts[i]}) {
+					t.Errorf("expected part[%d] = %v, got %v", i, tt.expectedParts[i], part)
+				}
+			}
+		})
+	}
+}
+
+func TestNew_InvalidArrayPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		errMsg string
+	}{
+		{name: "unterminated bracket", path: ".ports[0", errMsg: "column 9: expected ']'"},
+		{name: "non-numeric index", path: ".ports[x]", errMsg: "column 8: x/0 is not defined"},
+		{name: "non-numeric slice bound", path: ".ports[1:x]", errMsg: "column 10: x/0 is not defined"},
+		{name: "garbage after bracket", path: ".ports[0]x", errMsg: "column 10: unexpected 'x'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertQueryCreation(t, tt.path, true, tt.errMsg)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package query
+
+import (
+	"testing"
+)
+
+func createAssignTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"name":  "API",
+		"build": map[string]interface{}{"number": int64(41), "flags": []interface{}{"-O"}},
+		"servers": []map[string]interface{}{
+			{"host": "a", "port": int64(80)},
+			{"host": "b", "port": int64(8080), "timeout": int64(5)},
+		},
+	}
+}
+
+func TestExecute_Assignment(t *testing.T) {
+	data := createAssignTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// =
+		{name: "set a key", query: "(.build.number = 1) | .build.number", expected: []interface{}{int64(1)}},
+		{name: "set from the input", query: "(.build.number = .servers[1].port) | .build.number", expected: []interface{}{int64(8080)}},
+		{name: "set every element", query: "(.servers[].port = 443) | [.servers[].port]", expected: []interface{}{[]interface{}{int64(443), int64(443)}}},
+		{name: "one result per value", query: "[(.name = (\"x\", \"y\")) | .name]", expected: []interface{}{[]interface{}{"x", "y"}}},
+		{name: "create a key", query: "(.a.b = 1) | .a", expected: []interface{}{map[string]interface{}{"b": int64(1)}}},
+
+		// |=
+		{name: "update a string", query: ".name |= ascii_downcase | .name", expected: []interface{}{"api"}},
+		{name: "update every element", query: ".servers[].port |= . + 1 | [.servers[].port]", expected: []interface{}{[]interface{}{int64(81), int64(8081)}}},
+		{name: "update selected elements", query: "(.servers[] | select(.port > 1024) | .host) |= \"B\" | [.servers[].host]", expected: []interface{}{[]interface{}{"a", "B"}}},
+		{name: "update uses the first result", query: ".name |= (\"x\", \"y\") | .name", expected: []interface{}{"x"}},
+		{name: "update without results deletes", query: "[.servers[] |= select(.port < 1024)] | .[0].servers | length", expected: []interface{}{int64(1)}},
+		{name: "update a missing key", query: ".missing |= 1 | .missing", expected: []interface{}{int64(1)}},
+		{name: "update an array", query: ".build.flags |= . + [\"-g\"] | .build.flags", expected: []interface{}{[]interface{}{"-O", "-g"}}},
+
+		// arithmetic
+		{name: "add", query: ".build.number += 1 | .build.number", expected: []interface{}{int64(42)}},
+		{name: "subtract", query: ".build.number -= 1 | .build.number", expected: []interface{}{int64(40)}},
+		{name: "multiply every element", query: ".servers[].port *= 2 | [.servers[].port]", expected: []interface{}{[]interface{}{int64(160), int64(16160)}}},
+		{name: "divide", query: ".build.number /= 2 | .build.number", expected: []interface{}{20.5}},
+		{name: "modulo", query: ".build.number %= 10 | .build.number", expected: []interface{}{int64(1)}},
+		{name: "right operand runs against the input", query: ".build.number += .servers[0].port | .build.number", expected: []interface{}{int64(121)}},
+		{name: "append to an array", query: ".build.flags += [\"-g\"] | .build.flags", expected: []interface{}{[]interface{}{"-O", "-g"}}},
+		{name: "cannot add", query: ".name += 1", errMsg: "string and number cannot be added"},
+
+		// //=
+		{name: "default a missing key", query: ".servers[].timeout //= 30 | [.servers[].timeout]", expected: []interface{}{[]interface{}{int64(30), int64(5)}}},
+
+		// left-hand side
+		{name: "several paths", query: "(.build.number, .servers[0].port) += 1 | .build.number, .servers[0].port", expected: []interface{}{int64(42), int64(81)}},
+		{name: "input is unchanged", query: "(.name = \"x\") | .name", expected: []interface{}{"x"}},
+		{name: "not a path", query: "(.name | ascii_downcase) = \"x\"", errMsg: "invalid path expression with result \"api\""},
+		{name: "assignment inside map", query: ".servers | map(.port = 1) | map(.port)", expected: []interface{}{[]interface{}{int64(1), int64(1)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+
+	// The input is never changed
+	if data["name"] != "API" || data["build"].(map[string]interface{})["number"] != int64(41) {
+		t.Errorf("input changed to %#v", data)
+	}
+}
+
+func TestNew_AssignmentSyntax(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantStr string
+		errMsg  string
+	}{
+		{query: ".a|=.+1", wantStr: ".a |= . + 1"},
+		{query: ".a//=1", wantStr: ".a //= 1"},
+		{query: ".a += 1 | .b", wantStr: ".a += 1 | .b"},
+		{query: "(.a |= .) // 1", wantStr: ".a |= . // 1"},
+		{query: ".a |= (. // 1)", wantStr: ".a |= (. // 1)"},
+		{query: ".a = .b or .c", wantStr: ".a = .b or .c"},
+		{query: "(.a = 1) += 2", wantStr: "(.a = 1) += 2"},
+		{query: ".a == 1", wantStr: ".a == 1"},
+		{query: ".a += 1 -= 2", errMsg: "column 9: unexpected '-='"},
+		{query: ".a |=", errMsg: "unexpected end of query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, tt.errMsg != "", tt.errMsg)
+			if q != nil {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
+
+func TestAssignmentOperator(t *testing.T) {
+	tests := []struct {
+		expr     string
+		expected string
+	}{
+		{expr: ".version = 2.0", expected: "="},
+		{expr: ".date = 2024-05-01", expected: "="},
+		{expr: ".host = localhost", expected: "="},
+		{expr: `."a=b" = 1`, expected: "="},
+		{expr: ".build.number += 1", expected: "+="},
+		{expr: ".name |= ascii_downcase", expected: "|="},
+		{expr: ".timeout //= 30", expected: "//="},
+		{expr: ".servers[] |= select(.port == 80)", expected: "|="},
+		{expr: ".[$i] -= 1", expected: "-="},
+		{expr: `title = "x"`, expected: "="},
+		{expr: `owner."full name" = "x"`, expected: "="},
+		{expr: `title == "x"`, expected: ""},
+		{expr: ".name == \"a=b\"", expected: ""},
+		{expr: ".a | .b = 1", expected: ""},
+		{expr: ".a |= 1 | .a", expected: ""},
+		{expr: ".nums[0] += 1 | .nums", expected: ""},
+		{expr: ".a = 1 | keys", expected: ""},
+		{expr: ".a = 1, .b = 2", expected: ""},
+		{expr: ".a = (1 | tostring)", expected: "="},
+		{expr: ".a |= (. | ascii_downcase)", expected: "|="},
+		{expr: ".a |= .b |", expected: "|="},
+		{expr: ".a = { host = \"h\" }", expected: "="},
+		{expr: "map(.a = 1)", expected: ""},
+		{expr: "if .a == 1 then .b else .c end", expected: ""},
+		{expr: "del(.a)", expected: ""},
+		{expr: ".a.b", expected: ""},
+	}
+
+	vars := map[string]interface{}{"i": int64(0)}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			if op := AssignmentOperator(tt.expr, vars); op != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, op)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+package query
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+func createCollectionTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"servers": []map[string]interface{}{
+			{"name": "web1", "role": "web", "port": int64(8080)},
+			{"name": "db1", "role": "db", "port": int64(5432)},
+			{"name": "web2", "role": "web", "port": int64(80)},
+		},
+		"dependencies": map[string]interface{}{
+			"requests": "2.31.0",
+			"click":    "8.1.7",
+		},
+		"ports":    []interface{}{int64(443), int64(80), int64(443), 8080.0},
+		"tags":     []interface{}{"web", nil, "api"},
+		"flags":    []interface{}{true, false},
+		"name":     "app",
+		"released": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestExecute_CollectionBuiltins(t *testing.T) {
+	data := createCollectionTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// keys, length and type
+		{name: "keys_unsorted", query: ".dependencies | keys_unsorted", expected: []interface{}{[]interface{}{"click", "requests"}}},
+		{name: "length of table", query: ".dependencies | length", expected: []interface{}{int64(2)}},
+		{name: "length of array of tables", query: ".servers | length", expected: []interface{}{int64(3)}},
+		{name: "length of string", query: `"héllo" | length`, expected: []interface{}{int64(5)}},
+		{name: "length of number", query: "-5 | length", expected: []interface{}{int64(5)}},
+		{name: "length of null", query: "null | length", expected: []interface{}{int64(0)}},
+		{name: "length of boolean", query: "true | length", errMsg: "boolean has no length"},
+		{name: "length of datetime", query: ".released | length", errMsg: "datetime has no length"},
+		{name: "type of table", query: ".dependencies | type", expected: []interface{}{"object"}},
+		{name: "type of array of tables", query: ".servers | type", expected: []interface{}{"array"}},
+		{name: "type of datetime", query: ".released | type", expected: []interface{}{"datetime"}},
+		{name: "values drops null", query: ".tags[] | values", expected: []interface{}{"web", "api"}},
+
+		// has and in
+		{name: "has key", query: `.dependencies | has("click")`, expected: []interface{}{true}},
+		{name: "has missing key", query: `.dependencies | has("flask")`, expected: []interface{}{false}},
+		{name: "has index", query: ".ports | has(3)", expected: []interface{}{true}},
+		{name: "has index out of range", query: ".ports | has(4)", expected: []interface{}{false}},
+		{name: "has wrong key type", query: ".ports | has(\"a\")", errMsg: "cannot check whether an array has a string key"},
+		{name: "in non-container", query: `.dependencies | keys[] | in(.)`, errMsg: "cannot check whether string has a key"},
+
+		// map, map_values and entries
+		{name: "map", query: ".servers | map(.port)", expected: []interface{}{[]interface{}{int64(8080), int64(5432), int64(80)}}},
+		{name: "map over table", query: ".dependencies | map(length)", expected: []interface{}{[]interface{}{int64(5), int64(6)}}},
+		{name: "map with filter", query: ".servers | map(select(.role == \"web\") | .name)", expected: []interface{}{[]interface{}{"web1", "web2"}}},
+		{name: "map scalar", query: ".name | map(.)", errMsg: "cannot iterate over string"},
+		{
+			name:     "map_values on table",
+			query:    ".dependencies | map_values(length)",
+			expected: []interface{}{map[string]interface{}{"requests": int64(6), "click": int64(5)}},
+		},
+		{name: "map_values drops empty", query: ".tags | map_values(values)", expected: []interface{}{[]interface{}{"web", "api"}}},
+		{
+			name:  "to_entries",
+			query: ".dependencies | to_entries",
+			expected: []interface{}{[]interface{}{
+				map[string]interface{}{"key": "click", "value": "8.1.7"},
+				map[string]interface{}{"key": "requests", "value": "2.31.0"},
+			}},
+		},
+		{
+			name:     "from_entries with alternative names",
+			query:    ".servers | map(select(.role == \"web\")) | from_entries",
+			expected: []interface{}{map[string]interface{}{"web1": nil, "web2": nil}},
+		},
+		{
+			name:     "round trip",
+			query:    ".dependencies | (to_entries | from_entries) == .",
+			expected: []interface{}{true},
+		},
+		{
+			name:     "with_entries",
+			query:    `.dependencies | with_entries(select(.key != "click"))`,
+			expected: []interface{}{map[string]interface{}{"requests": "2.31.0"}},
+		},
+		{name: "from_entries without key", query: ".flags | from_entries", errMsg: "from_entries expects tables"},
+
+		// sorting and grouping
+		{name: "sort", query: ".ports | sort", expected: []interface{}{[]interface{}{int64(80), int64(443), int64(443), 8080.0}}},
+		{name: "sort mixed types", query: ".tags | sort", expected: []interface{}{[]interface{}{nil, "api", "web"}}},
+		{name: "sort table", query: ".dependencies | sort", errMsg: "sort cannot be applied to table"},
+		{name: "sort_by", query: ".servers | sort_by(.port) | map(.name)", expected: []interface{}{[]interface{}{"web2", "db1", "web1"}}},
+		{name: "sort_by is stable", query: ".servers | sort_by(.role) | map(.name)", expected: []interface{}{[]interface{}{"db1", "web1", "web2"}}},
+		{
+			name:  "group_by",
+			query: ".servers | group_by(.role) | map(map(.name))",
+			expected: []interface{}{[]interface{}{
+				[]interface{}{"db1"},
+				[]interface{}{"web1", "web2"},
+			}},
+		},
+		{name: "unique", query: ".ports | unique", expected: []interface{}{[]interface{}{int64(80), int64(443), 8080.0}}},
+		{name: "unique_by", query: ".servers | unique_by(.role) | map(.name)", expected: []interface{}{[]interface{}{"db1", "web1"}}},
+		{name: "min_by", query: ".servers | min_by(.port) | .name", expected: []interface{}{"web2"}},
+		{name: "max_by", query: ".servers | max_by(.port) | .name", expected: []interface{}{"web1"}},
+		{name: "min_by empty", query: ".servers | map(select(false)) | min_by(.port)", expected: []interface{}{nil}},
+
+		// add, any and all
+		{name: "add numbers", query: ".ports | add", expected: []interface{}{9046.0}},
+		{name: "add strings", query: ".servers | map(.name) | add", expected: []interface{}{"web1db1web2"}},
+		{name: "add table values", query: ".dependencies | add", expected: []interface{}{"8.1.72.31.0"}},
+		{name: "add empty", query: ".servers | map(select(false)) | add", expected: []interface{}{nil}},
+		{name: "any", query: ".flags | any", expected: []interface{}{true}},
+		{name: "all", query: ".flags | all", expected: []interface{}{false}},
+		{name: "any with condition", query: ".servers | any(.port < 100)", expected: []interface{}{true}},
+		{name: "all with condition", query: ".servers | all(.port < 100)", expected: []interface{}{false}},
+		{name: "all of empty", query: ".servers | map(select(false)) | all", expected: []interface{}{true}},
+
+		// append and insert
+		{name: "append", query: `.tags | append("db")`, expected: []interface{}{[]interface{}{"web", nil, "api", "db"}}},
+		{
+			name:  "append keeps arrays of tables",
+			query: `.servers | append({name: "web3"})`,
+			expected: []interface{}{[]map[string]interface{}{
+				{"name": "web1", "role": "web", "port": int64(8080)},
+				{"name": "db1", "role": "db", "port": int64(5432)},
+				{"name": "web2", "role": "web", "port": int64(80)},
+				{"name": "web3"},
+			}},
+		},
+		{name: "append to null", query: `null | append(1)`, expected: []interface{}{[]interface{}{int64(1)}}},
+		{name: "append an array", query: `[1] | append([2])`, expected: []interface{}{[]interface{}{int64(1), []interface{}{int64(2)}}}},
+		{name: "append with an update", query: `.tags |= append("db") | .tags | length`, expected: []interface{}{int64(4)}},
+		{name: "append to a string", query: `.name | append(1)`, errMsg: "append cannot be applied to string, expected an array"},
+		{name: "insert", query: `.tags | insert(1; "db")`, expected: []interface{}{[]interface{}{"web", "db", nil, "api"}}},
+		{name: "insert at the end", query: `[1] | insert(1; 2)`, expected: []interface{}{[]interface{}{int64(1), int64(2)}}},
+		{name: "insert from the end", query: `[1, 2] | insert(-1; 3)`, expected: []interface{}{[]interface{}{int64(1), int64(3), int64(2)}}},
+		{name: "insert first server", query: `.servers | insert(0; {name: "lb"}) | map(.name)`, expected: []interface{}{[]interface{}{"lb", "web1", "db1", "web2"}}},
+		{name: "insert out of range", query: `[1] | insert(3; 2)`, errMsg: "insert index 3 is out of range for an array of length 1"},
+		{name: "insert index type", query: `[1] | insert("a"; 2)`, errMsg: "insert index must be a number, got string"},
+		{name: "insert leaves the input", query: `(.tags | insert(0; 1) | length), (.tags | length)`, expected: []interface{}{int64(4), int64(3)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestAddValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     interface{}
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "integers stay integers", a: int64(2), b: int64(3), expected: int64(5)},
+		{name: "integer and float", a: int64(2), b: 0.5, expected: 2.5},
+		{name: "integer overflow", a: int64(math.MaxInt64), b: int64(1), expected: float64(math.MaxInt64) + 1},
+		{name: "null left", a: nil, b: "x", expected: "x"},
+		{name: "null right", a: int64(1), b: nil, expected: int64(1)},
+		{
+			name:     "arrays of tables",
+			a:        []map[string]interface{}{{"a": int64(1)}},
+			b:        []interface{}{int64(2)},
+			expected: []interface{}{map[string]interface{}{"a": int64(1)}, int64(2)},
+		},
+		{
+			name:     "tables merge",
+			a:        map[string]interface{}{"a": int64(1), "b": int64(2)},
+			b:        map[string]interface{}{"b": int64(3)},
+			expected: map[string]interface{}{"a": int64(1), "b": int64(3)},
+		},
+		{name: "string and number", a: "a", b: int64(1), errMsg: "string and number cannot be added"},
+		{name: "booleans", a: true, b: true, errMsg: "boolean and boolean cannot be added"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := addValues(tt.a, tt.b)
+			if tt.errMsg != "" {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Fatalf("expected error %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(result, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestExecute_TomlType(t *testing.T) {
+	var data map[string]interface{}
+	_, err := toml.Decode(`
+port = 8080
+ratio = 0.5
+name = "demo"
+enabled = true
+expires = 2026-11-01T00:00:00Z
+at = 2024-01-31T09:30:00
+date = 2024-01-31
+time = 07:30:00
+ports = [80, 443]
+inline = [{ host = "a" }]
+point = { x = 1 }
+
+[[servers]]
+host = "web"
+
+[database]
+host = "db"
+`, &data)
+	if err != nil {
+		t.Fatalf("failed to decode test data: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "integer", query: ".port | toml_type", expected: []interface{}{"integer"}},
+		{name: "float", query: ".ratio | toml_type", expected: []interface{}{"float"}},
+		{name: "string", query: ".name | toml_type", expected: []interface{}{"string"}},
+		{name: "boolean", query: ".enabled | toml_type", expected: []interface{}{"boolean"}},
+		{name: "offset datetime", query: ".expires | toml_type", expected: []interface{}{"offset-datetime"}},
+		{name: "local datetime", query: ".at | toml_type", expected: []interface{}{"local-datetime"}},
+		{name: "local date", query: ".date | toml_type", expected: []interface{}{"local-date"}},
+		{name: "local time", query: ".time | toml_type", expected: []interface{}{"local-time"}},
+		{name: "array", query: ".ports | toml_type", expected: []interface{}{"array"}},
+		{name: "array of inline tables", query: ".inline | toml_type", expected: []interface{}{"array"}},
+		{name: "array of tables", query: ".servers | toml_type", expected: []interface{}{"array-of-tables"}},
+		{name: "table", query: ".database | toml_type", expected: []interface{}{"table"}},
+		{name: "inline table", query: ".point | toml_type", expected: []interface{}{"table"}},
+		{name: "null", query: "null | toml_type", expected: []interface{}{"null"}},
+		{name: "computed values", query: "[1 + 1, 1 / 2, [.servers[]]] | map(toml_type)", expected: []interface{}{[]interface{}{"integer", "float", "array"}}},
+		{name: "type check", query: "[to_entries[] | select(.value | toml_type == \"integer\") | .key]", expected: []interface{}{[]interface{}{"port"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
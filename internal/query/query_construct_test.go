@@ -0,0 +1,146 @@
+package query
+
+import (
+	"testing"
+)
+
+func createConstructTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"project": map[string]interface{}{
+			"name":    "demo",
+			"version": "1.2.0",
+		},
+		"servers": []map[string]interface{}{
+			{"host": "web1", "port": int64(80)},
+			{"host": "web2", "port": int64(8080)},
+		},
+		"env": map[string]interface{}{"k": "region", "v": "eu"},
+	}
+}
+
+func TestExecute_Construct(t *testing.T) {
+	data := createConstructTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// Comma
+		{name: "comma", query: ".project.name, .project.version", expected: []interface{}{"demo", "1.2.0"}},
+		{name: "comma binds tighter than pipe", query: ".servers[] | .host, .port", expected: []interface{}{"web1", int64(80), "web2", int64(8080)}},
+		{name: "comma binds looser than alternative", query: ".a // 1, 2", expected: []interface{}{int64(1), int64(2)}},
+		{name: "pipe after comma", query: ".project, .env | length", expected: []interface{}{int64(2), int64(2)}},
+
+		// Arrays
+		{name: "collect", query: "[.servers[].host]", expected: []interface{}{[]interface{}{"web1", "web2"}}},
+		{name: "empty array", query: "[]", expected: []interface{}{[]interface{}{}}},
+		{name: "no results", query: "[.servers[] | select(.port > 9000)]", expected: []interface{}{[]interface{}{}}},
+		{name: "several elements", query: "[1, .project.name, null]", expected: []interface{}{[]interface{}{int64(1), "demo", nil}}},
+		{name: "index constructed array", query: "[.servers[].port][1]", expected: []interface{}{int64(8080)}},
+		{name: "nested arrays", query: "[[1], []]", expected: []interface{}{[]interface{}{[]interface{}{int64(1)}, []interface{}{}}}},
+		{name: "error in element", query: "[.missing]", errMsg: "key 'missing' not found"},
+
+		// Objects
+		{
+			name:     "projection",
+			query:    "{name: .project.name, version: .project.version}",
+			expected: []interface{}{map[string]interface{}{"name": "demo", "version": "1.2.0"}},
+		},
+		{
+			name:     "shorthand",
+			query:    ".project | {name, version}",
+			expected: []interface{}{map[string]interface{}{"name": "demo", "version": "1.2.0"}},
+		},
+		{
+			name:     "quoted keys",
+			query:    `.project | {"full name": .name, "version"}`,
+			expected: []interface{}{map[string]interface{}{"full name": "demo", "version": "1.2.0"}},
+		},
+		{
+			name:     "computed key",
+			query:    ".env | {(.k): .v}",
+			expected: []interface{}{map[string]interface{}{"region": "eu"}},
+		},
+		{
+			name:     "keyword keys",
+			query:    "{if: 1, and: 2, null: 3}",
+			expected: []interface{}{map[string]interface{}{"if": int64(1), "and": int64(2), "null": int64(3)}},
+		},
+		{name: "empty object", query: "{}", expected: []interface{}{map[string]interface{}{}}},
+		{
+			name:  "value per result",
+			query: "{host: .servers[].host}",
+			expected: []interface{}{
+				map[string]interface{}{"host": "web1"},
+				map[string]interface{}{"host": "web2"},
+			},
+		},
+		{
+			name:  "every combination",
+			query: "{a: (1, 2), b: (3, 4)}",
+			expected: []interface{}{
+				map[string]interface{}{"a": int64(1), "b": int64(3)},
+				map[string]interface{}{"a": int64(1), "b": int64(4)},
+				map[string]interface{}{"a": int64(2), "b": int64(3)},
+				map[string]interface{}{"a": int64(2), "b": int64(4)},
+			},
+		},
+		{
+			name:     "values take operators and pipes",
+			query:    "{next: .servers[0].port + 1 | . * 2, hosts: [.servers[].host]}",
+			expected: []interface{}{map[string]interface{}{"next": int64(162), "hosts": []interface{}{"web1", "web2"}}},
+		},
+		{
+			name:  "objects in arrays",
+			query: "[.servers[] | {name: .host}]",
+			expected: []interface{}{[]interface{}{
+				map[string]interface{}{"name": "web1"},
+				map[string]interface{}{"name": "web2"},
+			}},
+		},
+		{name: "later key wins", query: "{a: 1, a: 2}", expected: []interface{}{map[string]interface{}{"a": int64(2)}}},
+		{name: "key not a string", query: "{(1): 2}", errMsg: "object key must be a string, got number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestNew_Construct(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		errMsg  string
+		wantStr string
+	}{
+		{query: ".a,.b|.c", wantStr: ".a, .b | .c"},
+		{query: "(.a, .b) // .c", wantStr: "(.a, .b) // .c"},
+		{query: "[.a[]]", wantStr: "[.a[]]"},
+		{query: "[ ]", wantStr: "[]"},
+		{query: "{a:.b,c}", wantStr: "{a: .b, c}"},
+		{query: `{"a b": 1, "c"}`, wantStr: `{"a b": 1, c}`},
+		{query: "{(.k): .v | .w}", wantStr: "{(.k): (.v | .w)}"},
+		{query: "{a: (1, 2)}", wantStr: "{a: (1, 2)}"},
+		{query: "{a: 1 + 2}", wantStr: "{a: 1 + 2}"},
+		{query: "{a: 1", wantErr: true, errMsg: "expected '}', got end of query"},
+		{query: "{a: 1,}", wantErr: true, errMsg: "expected object key, got '}'"},
+		{query: "{.a}", wantErr: true, errMsg: "expected object key, got '.a'"},
+		{query: "{(.k)}", wantErr: true, errMsg: "expected ':', got '}'"},
+		{query: "[.a", wantErr: true, errMsg: "expected ']', got end of query"},
+		{query: ".a,", wantErr: true, errMsg: "unexpected end of query"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, tt.wantErr, tt.errMsg)
+			if q != nil && !tt.wantErr {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package query
+
+import (
+	"testing"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// createDateTestData decodes a document with every TOML datetime type, so
+// that the local values carry the decoder's own locations
+func createDateTestData(t *testing.T) map[string]interface{} {
+	t.Helper()
+
+	var data map[string]interface{}
+	_, err := toml.Decode(`
+[[certs]]
+name = "api"
+expires = 2026-11-01T00:00:00Z
+
+[[certs]]
+name = "web"
+expires = 2027-03-15T12:00:00+02:00
+
+[release]
+date = 2024-01-31
+at = 2024-01-31T09:30:00
+time = 07:30:00
+`, &data)
+	if err != nil {
+		t.Fatalf("failed to decode test data: %v", err)
+	}
+	return data
+}
+
+func TestExecute_Dates(t *testing.T) {
+	data := createDateTestData(t)
+	expires := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// now, todate and fromdate
+		{name: "now is an offset datetime", query: "now | strftime(\"%Z\")", expected: []interface{}{"UTC"}},
+		{name: "todate from unix seconds", query: "1793491200 | todate", expected: []interface{}{expires}},
+		{name: "todate from offset string", query: "(\"2026-11-01T00:00:00Z\" | todate) == .certs[0].expires", expected: []interface{}{true}},
+		{name: "todate from local date string", query: "(\"2024-01-31\" | todate) == .release.date", expected: []interface{}{true}},
+		{name: "todate from local datetime string", query: "(\"2024-01-31 09:30:00\" | todate) == .release.at", expected: []interface{}{true}},
+		{name: "todate from local time string", query: "(\"07:30:00\" | todate) == .release.time", expected: []interface{}{true}},
+		{name: "todate keeps datetimes", query: "(.release.date | todate) == .release.date", expected: []interface{}{true}},
+		{name: "todate of an invalid string", query: "\"tomorrow\" | todate", errMsg: "cannot parse \"tomorrow\" as a TOML datetime"},
+		{name: "todate of a boolean", query: "true | todate", errMsg: "todate cannot be applied to boolean"},
+		{name: "fromdate", query: ".certs[].expires | fromdate", expected: []interface{}{int64(1793491200), int64(1805104800)}},
+		{name: "fromdate of a string", query: "\"1970-01-01T00:01:00Z\" | fromdate", expected: []interface{}{int64(60)}},
+		{name: "fromdate with fraction", query: "\"1970-01-01T00:00:01.5Z\" | fromdate", expected: []interface{}{1.5}},
+		{name: "fromdate round trip", query: ".certs[0].expires | fromdate | todate", expected: []interface{}{expires}},
+		{name: "fromdate of a local date", query: ".release.date | fromdate", errMsg: "fromdate cannot convert a local date to Unix time"},
+
+		// comparisons
+		{name: "offset datetimes compare as instants", query: ".certs[0].expires < .certs[1].expires", expected: []interface{}{true}},
+		{name: "same instant in different offsets", query: "(\"2026-11-01T02:00:00+02:00\" | todate) == .certs[0].expires", expected: []interface{}{true}},
+		{name: "local dates compare", query: ".release.date < (\"2024-02-01\" | todate)", expected: []interface{}{true}},
+		{name: "local times compare", query: ".release.time > (\"07:00\" | todate)", expected: []interface{}{true}},
+		{name: "expiring within 30 days", query: "[.certs[] | select(.expires < (\"2026-10-16T00:00:00Z\" | todate | dateadd(\"days\"; 30))) | .name]", expected: []interface{}{[]interface{}{"api"}}},
+		{name: "local and offset are never equal", query: "(\"2026-11-01T00:00:00\" | todate) == .certs[0].expires", expected: []interface{}{false}},
+		{name: "local and offset cannot be ordered", query: ".release.date < .certs[0].expires", errMsg: "cannot compare local date with offset datetime"},
+		{name: "sort orders by kind", query: "([.release.time, .release.date, .release.at, .certs[0].expires] | sort) == [.certs[0].expires, .release.at, .release.date, .release.time]", expected: []interface{}{true}},
+
+		// dateadd and datesub
+		{name: "add days", query: ".certs[0].expires | dateadd(\"days\"; 30) | strftime(\"%F\")", expected: []interface{}{"2026-12-01"}},
+		{name: "subtract hours", query: ".certs[0].expires | datesub(\"hours\"; 1) | strftime(\"%F %T\")", expected: []interface{}{"2026-10-31 23:00:00"}},
+		{name: "add fractional minutes", query: ".certs[0].expires | dateadd(\"minute\"; 1.5) | strftime(\"%T\")", expected: []interface{}{"00:01:30"}},
+		{name: "add a month to the end of january", query: ".release.date | dateadd(\"months\"; 1) | strftime(\"%F\")", expected: []interface{}{"2024-02-29"}},
+		{name: "subtract a year", query: ".release.date | datesub(\"years\"; 1) | strftime(\"%F\")", expected: []interface{}{"2023-01-31"}},
+		{name: "add weeks", query: ".release.at | dateadd(\"weeks\"; 2) | strftime(\"%F %R\")", expected: []interface{}{"2024-02-14 09:30"}},
+		{name: "adding keeps local dates local", query: ".release.date as $d | $d | dateadd(\"days\"; 1) > $d", expected: []interface{}{true}},
+		{name: "local times wrap around midnight", query: ".release.time | datesub(\"hours\"; 8) | strftime(\"%T\")", expected: []interface{}{"23:30:00"}},
+		{name: "hours of a local date", query: ".release.date | dateadd(\"hours\"; 1)", errMsg: "dateadd cannot add hours to a local date"},
+		{name: "days of a local time", query: ".release.time | datesub(\"days\"; 1)", errMsg: "datesub cannot add days to a local time"},
+		{name: "fractional days", query: ".release.date | dateadd(\"days\"; 1.5)", errMsg: "dateadd expects a whole number of days, got 1.5"},
+		{name: "unknown unit", query: ".release.date | dateadd(\"fortnights\"; 1)", errMsg: "dateadd: unknown unit \"fortnights\""},
+		{name: "dateadd of a string", query: "\"2024-01-31\" | dateadd(\"days\"; 1)", errMsg: "dateadd cannot be applied to string, expected a datetime"},
+
+		// strftime
+		{name: "strftime", query: ".certs[0].expires | strftime(\"%A %d %B %Y %H:%M %Z\")", expected: []interface{}{"Sunday 01 November 2026 00:00 UTC"}},
+		{name: "strftime keeps the offset", query: ".certs[1].expires | strftime(\"%FT%T%z\")", expected: []interface{}{"2027-03-15T12:00:00+0200"}},
+		{name: "strftime of unix seconds", query: "0 | strftime(\"%F %T %s\")", expected: []interface{}{"1970-01-01 00:00:00 0"}},
+		{name: "strftime 12 hour clock", query: ".release.at | strftime(\"%I:%M %p, day %j, %a %b %e\")", expected: []interface{}{"09:30 AM, day 031, Wed Jan 31"}},
+		{name: "strftime of a local time", query: ".release.time | strftime(\"%H:%M %%\")", expected: []interface{}{"07:30 %"}},
+		{name: "strftime date of a local time", query: ".release.time | strftime(\"%Y\")", errMsg: "strftime: %Y needs a date, got a local time"},
+		{name: "strftime offset of a local datetime", query: ".release.at | strftime(\"%z\")", errMsg: "strftime: %z needs an offset, got a local datetime"},
+		{name: "strftime unsupported conversion", query: ".release.at | strftime(\"%Q\")", errMsg: "unsupported conversion %Q"},
+
+		// strptime
+		{name: "strptime local date", query: "(\"31/01/2024\" | strptime(\"%d/%m/%Y\")) == .release.date", expected: []interface{}{true}},
+		{name: "strptime local datetime", query: "(\"Jan 31 2024 9:30 am\" | strptime(\"%b %d %Y %I:%M %p\")) == .release.at", expected: []interface{}{true}},
+		{name: "strptime local time", query: "(\"7.30\" | strptime(\"%H.%M\")) == .release.time", expected: []interface{}{true}},
+		{name: "strptime literal Z is UTC", query: "\"2026-11-01T00:00:00Z\" | strptime(\"%Y-%m-%dT%H:%M:%SZ\")", expected: []interface{}{expires}},
+		{name: "strptime offset", query: "(\"2027-03-15 12:00 +02:00\" | strptime(\"%F %R %z\")) == .certs[1].expires", expected: []interface{}{true}},
+		{name: "strptime unix seconds", query: "\"1793491200\" | strptime(\"%s\")", expected: []interface{}{expires}},
+		{name: "strptime mismatch", query: "\"2024-01-31\" | strptime(\"%d/%m/%Y\")", errMsg: "date \"2024-01-31\" does not match format \"%d/%m/%Y\""},
+		{name: "strptime trailing input", query: "\"2024-01-31T10\" | strptime(\"%F\")", errMsg: "does not match format"},
+		{name: "strptime impossible date", query: "\"2023-02-29\" | strptime(\"%F\")", errMsg: "date \"2023-02-29\" is not a valid date"},
+		{name: "strptime zone without date", query: "\"10:00 UTC\" | strptime(\"%R %Z\")", errMsg: "has a zone but no date"},
+		{name: "strptime of a number", query: "1 | strptime(\"%s\")", errMsg: "strptime cannot be applied to number, expected a string"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+// TestDates_KeepLocalTypes checks that datetimes built by queries carry the
+// decoder's own locations, which the encoder recognizes by identity
+func TestDates_KeepLocalTypes(t *testing.T) {
+	data := createDateTestData(t)
+	release := data["release"].(map[string]interface{})
+
+	tests := []struct {
+		name  string
+		query string
+		zone  *time.Location
+	}{
+		{name: "todate local datetime", query: `"2024-01-31T09:30:00" | todate`, zone: release["at"].(time.Time).Location()},
+		{name: "strptime local date", query: `"2024-01-31" | strptime("%F")`, zone: release["date"].(time.Time).Location()},
+		{name: "local time arithmetic", query: `.release.time | dateadd("hours"; 20)`, zone: release["time"].(time.Time).Location()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			result, err := executeSingle(t, q, data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if zone := result.(time.Time).Location(); zone != tt.zone {
+				t.Errorf("expected location %p (%s), got %p (%s)", tt.zone, tt.zone, zone, zone)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package query
+
+import (
+	"testing"
+)
+
+func createDocumentTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"settings": `{"retries": 3, "ratio": 0.5, "hosts": ["a", "b"]}`,
+		"compose":  "services:\n  web:\n    ports: [80, 443]\n",
+		"defaults": "[server]\nport = 8080\n",
+		"server":   map[string]interface{}{"host": "web", "port": int64(80)},
+	}
+}
+
+func TestExecute_Documents(t *testing.T) {
+	data := createDocumentTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// parsing
+		{name: "fromjson", query: ".settings | fromjson | .retries", expected: []interface{}{int64(3)}},
+		{name: "fromjson keeps floats", query: ".settings | fromjson | .ratio", expected: []interface{}{0.5}},
+		{name: "fromjson of a scalar", query: `"[1, null]" | fromjson`, expected: []interface{}{[]interface{}{int64(1), nil}}},
+		{name: "fromyaml", query: ".compose | fromyaml | .services.web.ports", expected: []interface{}{[]interface{}{int64(80), int64(443)}}},
+		{name: "fromtoml document", query: ".defaults | fromtoml | .server.port", expected: []interface{}{int64(8080)}},
+		{name: "fromtoml value", query: `"[1, 2]" | fromtoml`, expected: []interface{}{[]interface{}{int64(1), int64(2)}}},
+		{name: "fromjson of invalid JSON", query: `"{" | fromjson`, errMsg: "fromjson: invalid JSON value"},
+		{name: "fromyaml of invalid YAML", query: `"a: [" | fromyaml`, errMsg: "fromyaml: invalid YAML value"},
+		{name: "fromtoml of invalid TOML", query: `"a = " | fromtoml`, errMsg: "fromtoml: invalid TOML document"},
+		{name: "fromjson of a table", query: ".server | fromjson", errMsg: "fromjson cannot be applied to table, expected a string"},
+
+		// serializing
+		{name: "tojson", query: ".server | tojson", expected: []interface{}{"{\n  \"host\": \"web\",\n  \"port\": 80\n}"}},
+		{name: "tojson of a string", query: ".server.host | tojson", expected: []interface{}{`"web"`}},
+		{name: "totoml of a table", query: ".server | totoml", expected: []interface{}{"host = \"web\"\nport = 80"}},
+		{name: "totoml of a value", query: "[1, 2] | totoml", expected: []interface{}{"[1, 2]"}},
+		{name: "toyaml", query: ".server | toyaml", expected: []interface{}{"host: web\nport: 80\n"}},
+		{name: "tojson of datetimes", query: `"od = 2026-11-01T07:32:00Z\nldt = 2026-11-01T07:32:00\nld = 2026-11-01\nlt = 07:32:00" | fromtoml | tojson`, expected: []interface{}{"{\n  \"ld\": \"2026-11-01\",\n  \"ldt\": \"2026-11-01T07:32:00\",\n  \"lt\": \"07:32:00\",\n  \"od\": \"2026-11-01T07:32:00Z\"\n}"}},
+		{name: "toyaml of datetimes", query: `"ld = 2026-11-01\nlt = 07:32:00" | fromtoml | toyaml`, expected: []interface{}{"ld: 2026-11-01\nlt: \"07:32:00\"\n"}},
+
+		// round trips
+		{name: "json round trip", query: "(.server | tojson | fromjson) == .server", expected: []interface{}{true}},
+		{name: "toml round trip", query: "(.server | totoml | fromtoml) == .server", expected: []interface{}{true}},
+		{name: "toml value round trip", query: `("x" | totoml | fromtoml) == "x"`, expected: []interface{}{true}},
+		{name: "yaml round trip", query: "(.server | toyaml | fromyaml) == .server", expected: []interface{}{true}},
+		{name: "convert between formats", query: ".settings | fromjson | {hosts} | totoml", expected: []interface{}{`hosts = ["a", "b"]`}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
@@ -50,10 +50,54 @@ func createNestedTestData() map[string]interface{} {
 }
 
 // Helper functions
+
+// executeSingle runs a query that is expected to produce exactly one result
+func executeSingle(t *testing.T, q *Query, data interface{}) (interface{}, error) {
+	t.Helper()
+
+	results, err := q.Execute(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != 1 {
+		t.Fatalf("expected exactly one result, got %d: %v", len(results), results)
+	}
+	return results[0], nil
+}
+
+// assertResults compiles and runs query, expecting either all of its
+// results or, when errMsg is set, an error containing errMsg
+func assertResults(t *testing.T, query string, data interface{}, expected []interface{}, errMsg string) {
+	t.Helper()
+
+	q, err := New(query)
+	var results []interface{}
+	if err == nil {
+		results, err = q.Execute(data)
+	}
+
+	if errMsg != "" {
+		if err == nil {
+			t.Fatalf("expected error, got results %v", results)
+		}
+		if !strings.Contains(err.Error(), errMsg) {
+			t.Errorf("expected error containing %q, got %q", errMsg, err.Error())
+		}
+		return
+	}
+
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %#v, got %#v", expected, results)
+	}
+}
+
 func assertQueryExecution(t *testing.T, q *Query, data interface{}, wantErr bool, errMsg string, expected interface{}) {
 	t.Helper()
 
-	result, err := q.Execute(data)
+	result, err := executeSingle(t, q, data)
 
 	if wantErr {
 		if err == nil {
@@ -210,7 +254,7 @@ func TestExecute_EdgeCases(t *testing.T) {
 		}
 
 		simpleValue := "just a string"
-		result, err := q.Execute(simpleValue)
+		result, err := executeSingle(t, q, simpleValue)
 		if err != nil {
 			t.Errorf("unexpected error for root query: %v", err)
 		}
@@ -235,7 +279,7 @@ func TestExecute_WithMapInterface(t *testing.T) {
 			t.Fatalf("failed to create query: %v", err)
 		}
 
-		result, err := q.Execute(data)
+		result, err := executeSingle(t, q, data)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			return
@@ -253,7 +297,7 @@ func TestExecute_WithMapInterface(t *testing.T) {
 			t.Fatalf("failed to create query: %v", err)
 		}
 
-		result, err := q.Execute(data)
+		result, err := executeSingle(t, q, data)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			return
@@ -290,7 +334,7 @@ func TestExecute_ArrayHandling(t *testing.T) {
 			t.Fatalf("failed to create query: %v", err)
 		}
 
-		result, err := q.Execute(data)
+		result, err := executeSingle(t, q, data)
 		if err != nil {
 			t.Errorf("unexpected error accessing array: %v", err)
 			return
@@ -327,7 +371,7 @@ func TestExecute_ComplexNesting(t *testing.T) {
 			t.Fatalf("failed to create query: %v", err)
 		}
 
-		result, err := q.Execute(data)
+		result, err := executeSingle(t, q, data)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			return
@@ -345,7 +389,7 @@ func TestExecute_ComplexNesting(t *testing.T) {
 			t.Fatalf("failed to create query: %v", err)
 		}
 
-		result, err := q.Execute(data)
+		result, err := executeSingle(t, q, data)
 		if err != nil {
 			t.Errorf("unexpected error: %v", err)
 			return
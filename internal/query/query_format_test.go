@@ -0,0 +1,137 @@
+package query
+
+import (
+	"testing"
+)
+
+func createFormatTestData(t *testing.T) map[string]interface{} {
+	t.Helper()
+	data := createDateTestData(t)
+	data["host"] = "db.local"
+	data["port"] = int64(5432)
+	data["tags"] = []interface{}{"web", "it's"}
+	data["row"] = []interface{}{"a,b", `say "hi"`, int64(1), 2.5, true, nil}
+	data["server"] = map[string]interface{}{"name": "api", "ports": []interface{}{int64(80), int64(443)}}
+	return data
+}
+
+func TestExecute_Interpolation(t *testing.T) {
+	data := createFormatTestData(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "host and port", query: `"\(.host):\(.port)"`, expected: []interface{}{"db.local:5432"}},
+		{name: "only an interpolation", query: `"\(.port)"`, expected: []interface{}{"5432"}},
+		{name: "escapes around interpolations", query: `"\t\(.host)\n"`, expected: []interface{}{"\tdb.local\n"}},
+		{name: "table as JSON", query: `"server: \(.server)"`, expected: []interface{}{`server: {"name":"api","ports":[80,443]}`}},
+		{name: "null and booleans", query: `"\(null) \(true)"`, expected: []interface{}{"null true"}},
+		{name: "datetimes as TOML", query: `"\(.release.date) \(.release.time) \(.certs[0].expires)"`, expected: []interface{}{"2024-01-31 07:30:00 2026-11-01T00:00:00Z"}},
+		{name: "pipes inside", query: `"\(.tags | length) tags"`, expected: []interface{}{"2 tags"}},
+		{name: "parentheses and strings inside", query: `"\((.port + 1) | "(\(.))")"`, expected: []interface{}{"(5433)"}},
+		{name: "one string per result", query: `"port \(.server.ports[])"`, expected: []interface{}{"port 80", "port 443"}},
+		{name: "last interpolation varies slowest", query: `"\(1, 2)-\(3, 4)"`, expected: []interface{}{"1-3", "2-3", "1-4", "2-4"}},
+		{name: "variables in scope", query: `.host as $h | "\($h)"`, expected: []interface{}{"db.local"}},
+		{name: "functions in scope", query: `def twice: . * 2; "\(.port | twice)"`, expected: []interface{}{"10864"}},
+		{name: "computed key", query: `{"\(.server.name)_port": .port}`, expected: []interface{}{map[string]interface{}{"api_port": int64(5432)}}},
+		{name: "error inside", query: `"\(.host | keys)"`, errMsg: "string has no keys"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestExecute_Formats(t *testing.T) {
+	data := createFormatTestData(t)
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// text and json
+		{name: "text of a string", query: `.host | @text`, expected: []interface{}{"db.local"}},
+		{name: "text of a table", query: `.server | @text`, expected: []interface{}{`{"name":"api","ports":[80,443]}`}},
+		{name: "json of a string", query: `.host | @json`, expected: []interface{}{`"db.local"`}},
+		{name: "json of infinity", query: `"-inf" | fromtoml | @json`, expected: []interface{}{`"-inf"`}},
+		{name: "json of local datetimes", query: `"[2026-11-01, 07:32:00, 2026-11-01T07:32:00]" | fromtoml | @json`, expected: []interface{}{`["2026-11-01","07:32:00","2026-11-01T07:32:00"]`}},
+		{name: "json of an offset datetime", query: `"2026-11-01T07:32:00+03:30" | fromtoml | @json`, expected: []interface{}{`"2026-11-01T07:32:00+03:30"`}},
+		{name: "json of a big integer", query: `9007199254740993 | @json`, expected: []interface{}{"9007199254740993"}},
+		{name: "json interpolation", query: `@json "host=\(.host)"`, expected: []interface{}{`host="db.local"`}},
+
+		// html and uri
+		{name: "html", query: `"<a href='x'>\"&\"</a>" | @html`, expected: []interface{}{"&lt;a href=&#39;x&#39;&gt;&quot;&amp;&quot;&lt;/a&gt;"}},
+		{name: "html escapes only interpolations", query: `@html "<b>\("<i>")</b>"`, expected: []interface{}{"<b>&lt;i&gt;</b>"}},
+		{name: "uri", query: `"a b/é~_-." | @uri`, expected: []interface{}{"a%20b%2F%C3%A9~_-."}},
+		{name: "uri of a number", query: `.port | @uri`, expected: []interface{}{"5432"}},
+		{name: "uri query string", query: `@uri "https://x.test/?q=\(.tags[1])"`, expected: []interface{}{"https://x.test/?q=it%27s"}},
+
+		// csv and tsv
+		{name: "csv", query: `.row | @csv`, expected: []interface{}{`"a,b","say ""hi""",1,2.5,true,`}},
+		{name: "csv datetimes are quoted", query: `[.release.date] | @csv`, expected: []interface{}{`"2024-01-31"`}},
+		{name: "csv of a table element", query: `[.server] | @csv`, errMsg: "table is not valid in a csv row"},
+		{name: "csv of a string", query: `.host | @csv`, errMsg: "@csv cannot be applied to string, expected an array"},
+		{name: "tsv", query: "[\"a\\tb\", \"c\\\\d\", \"e\\nf\", 1, null] | @tsv", expected: []interface{}{"a\\tb\tc\\\\d\te\\nf\t1\t"}},
+		{name: "tsv of nested arrays", query: `[[1]] | @tsv`, errMsg: "array is not valid in a tsv row"},
+
+		// sh
+		{name: "sh of a string", query: `.tags[1] | @sh`, expected: []interface{}{`'it'\''s'`}},
+		{name: "sh of an array", query: `.tags | @sh`, expected: []interface{}{`'web' 'it'\''s'`}},
+		{name: "sh of scalars", query: `[.port, true, null] | @sh`, expected: []interface{}{"5432 true null"}},
+		{name: "sh interpolation", query: `@sh "echo \(.tags)"`, expected: []interface{}{`echo 'web' 'it'\''s'`}},
+		{name: "sh of a table", query: `.server | @sh`, errMsg: "table cannot be escaped for shell"},
+		{name: "sh of nested arrays", query: `[.tags] | @sh`, errMsg: "array cannot be escaped for shell"},
+
+		// base64
+		{name: "base64", query: `.host | @base64`, expected: []interface{}{"ZGIubG9jYWw="}},
+		{name: "base64 round trip", query: `.tags[1] | @base64 | @base64d`, expected: []interface{}{"it's"}},
+		{name: "base64d without padding", query: `"ZGIubG9jYWw" | @base64d`, expected: []interface{}{"db.local"}},
+		{name: "base64d of invalid data", query: `"not base64!" | @base64d`, errMsg: `"not base64!" is not valid base64 data`},
+		{name: "format without interpolations", query: `@base64 "plain"`, expected: []interface{}{"plain"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestNew_Interpolation(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		errMsg  string
+		wantStr string
+	}{
+		{query: `"\(.host):\(.port)"`, wantStr: `"\(.host):\(.port)"`},
+		{query: `"a\tb\(.x|.y)"`, wantStr: `"a\tb\(.x | .y)"`},
+		{query: `@sh "echo \(.a)"`, wantStr: `@sh "echo \(.a)"`},
+		{query: `.a|@base64`, wantStr: `.a | @base64`},
+		{query: `"\("\(.a)")"`, wantStr: `"\("\(.a)")"`},
+		{query: `@nope`, wantErr: true, errMsg: "column 1: @nope is not a valid format"},
+		{query: `"\(.a`, wantErr: true, errMsg: "column 1: unterminated interpolation"},
+		{query: `"\(.a)`, wantErr: true, errMsg: "unterminated string"},
+		{query: `"\()"`, wantErr: true, errMsg: "column 4: unexpected ')'"},
+		{query: `"x\(.a | )"`, wantErr: true, errMsg: "column 10: unexpected ')'"},
+		{query: `"\(.a 1)"`, wantErr: true, errMsg: "column 7: expected ')', got '1'"},
+		{query: `"\($x)"`, wantErr: true, errMsg: "$x is not defined"},
+		{query: `'\(.a)'`, wantStr: `"\\(.a)"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, tt.wantErr, tt.errMsg)
+			if q != nil && !tt.wantErr {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package query
+
+import (
+	"testing"
+)
+
+func TestExecute_Functions(t *testing.T) {
+	data := createVariableTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "no parameters", query: "def host: .host; .servers[] | host", expected: []interface{}{"web1", "web2"}},
+		{name: "filter parameter", query: "def ports(f): [.servers[] | f]; ports(.port)", expected: []interface{}{[]interface{}{int64(80), int64(8080)}}},
+		{name: "filter runs on each use", query: "def twice(f): f | f; 1 | twice(. * 3)", expected: []interface{}{int64(9)}},
+		{name: "filter sees the caller's input", query: "def at(f): .servers[] | f; at(.host)", expected: []interface{}{"web1", "web2"}},
+		{name: "value parameter", query: "def above($n): select(.port > $n); .servers[] | above(100) | .host", expected: []interface{}{"web2"}},
+		{name: "value parameter per result", query: "def add($x): . + $x; 1 | add(1, 2)", expected: []interface{}{int64(2), int64(3)}},
+		{name: "value parameter runs against the input", query: "def add($x): . + $x; .min | add(.)", expected: []interface{}{int64(2000)}},
+		{name: "value parameter is also a filter", query: "def f($a): a + $a; f(1)", expected: []interface{}{int64(2)}},
+		{name: "several parameters", query: "def pick($k; f): {($k): f}; pick(\"n\"; .project.name)", expected: []interface{}{map[string]interface{}{"n": "demo"}}},
+		{name: "recursion", query: "def down: select(. > 0) | ., (. - 1 | down); [3 | down]", expected: []interface{}{[]interface{}{int64(3), int64(2), int64(1)}}},
+		{name: "arity overloads", query: "def f: 1; def f(x): 2; [f, f(0)]", expected: []interface{}{[]interface{}{int64(1), int64(2)}}},
+		{name: "shadows builtin", query: "def length: 42; .servers | length", expected: []interface{}{int64(42)}},
+		{name: "later definition shadows", query: "def f: 1; def f: 2; f", expected: []interface{}{int64(2)}},
+		{name: "lexical scope", query: "def f: 1; def g: f; def f: 2; g", expected: []interface{}{int64(1)}},
+		{name: "closure sees caller's variables", query: "def f(g): 10 as $x | g; 1 as $x | f($x)", expected: []interface{}{int64(1)}},
+		{name: "body sees definition's variables", query: "1 as $x | def f: $x; 2 as $x | f", expected: []interface{}{int64(1)}},
+		{name: "nested definition", query: "def outer: def inner: . * 2; inner + 1; 5 | outer", expected: []interface{}{int64(11)}},
+		{name: "definition after pipe", query: ".min | def half: . / 2; half", expected: []interface{}{int64(500)}},
+		{name: "definition in parentheses", query: "(def f: 1; f) + 1", expected: []interface{}{int64(2)}},
+		{name: "definition in object value", query: "{a: def f: 1; f, b: 2}", expected: []interface{}{map[string]interface{}{"a": int64(1), "b": int64(2)}}},
+		{name: "parameter shadows function", query: "def f: 1; def g(f): f; g(2)", expected: []interface{}{int64(2)}},
+		{name: "error in body", query: "def f: .missing; f", errMsg: "key 'missing' not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestNew_Functions(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		errMsg  string
+		wantStr string
+	}{
+		{query: "def f:.a;f", wantStr: "def f: .a; f"},
+		{query: "def f(g;$x): g+$x; f(.;1)", wantStr: "def f(g; $x): g + $x; f(.; 1)"},
+		{query: "(def f: 1; f) | f2", wantErr: true, errMsg: "f2/0 is not defined"},
+		{query: "(def f: 1; f) | .a", wantStr: "(def f: 1; f) | .a"},
+		{query: ".a | def f: 1; f | .b", wantStr: ".a | def f: 1; f | .b"},
+		{query: "def f: 1; f(2)", wantErr: true, errMsg: "f/1 is not defined"},
+		{query: "def f(g): 1; g", wantErr: true, errMsg: "g/0 is not defined"},
+		{query: "def f($x): 1; $x", wantErr: true, errMsg: "$x is not defined"},
+		{query: "def f: 1", wantErr: true, errMsg: "expected ';', got end of query"},
+		{query: "def f 1; f", wantErr: true, errMsg: "expected ':', got '1'"},
+		{query: "def and: 1; 2", wantErr: true, errMsg: "expected function name after 'def', got 'and'"},
+		{query: "def f(1): 1; 2", wantErr: true, errMsg: "expected parameter name, got '1'"},
+		{query: "def f(a;): 1; 2", wantErr: true, errMsg: "expected parameter name, got ')'"},
+		{query: "1 + def f: 1; f", wantErr: true, errMsg: "unexpected 'def'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, tt.wantErr, tt.errMsg)
+			if q != nil && !tt.wantErr {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
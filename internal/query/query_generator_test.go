@@ -0,0 +1,58 @@
+package query
+
+import (
+	"testing"
+)
+
+func TestExecute_Generators(t *testing.T) {
+	data := createReduceTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// limit, first and last
+		{name: "limit", query: "[limit(2; .items[].name)]", expected: []interface{}{[]interface{}{"a", "b"}}},
+		{name: "limit above count", query: "[limit(5; .items[].name)]", expected: []interface{}{[]interface{}{"a", "b", "c"}}},
+		{name: "limit zero", query: "[limit(0; .items[].name)]", expected: []interface{}{[]interface{}{}}},
+		{name: "limit stops the generator", query: "[limit(1; .items[].name, .missing)]", expected: []interface{}{[]interface{}{"a"}}},
+		{name: "nested limits", query: "[limit(3; limit(2; 1, 2, 3), 4, 5)]", expected: []interface{}{[]interface{}{int64(1), int64(2), int64(4)}}},
+		{name: "limit count not a number", query: "limit(\"2\"; 1)", errMsg: "limit count must be a number, got string"},
+		{name: "first", query: "first(.items[].name)", expected: []interface{}{"a"}},
+		{name: "first stops the generator", query: "first(.items[0].name, .missing)", expected: []interface{}{"a"}},
+		{name: "first of nothing", query: "[first(select(false))]", expected: []interface{}{[]interface{}{}}},
+		{name: "last", query: "last(.items[].name)", expected: []interface{}{"c"}},
+		{name: "last of nothing", query: "[last(select(false))]", expected: []interface{}{[]interface{}{}}},
+		{name: "first element", query: ".words | first", expected: []interface{}{"x"}},
+		{name: "last element", query: ".items | last | .name", expected: []interface{}{"c"}},
+		{name: "first of empty array", query: "[] | first", expected: []interface{}{nil}},
+		{name: "first of a table", query: "first", errMsg: "first cannot be applied to table, expected an array"},
+
+		// range
+		{name: "range upto", query: "[range(3)]", expected: []interface{}{[]interface{}{int64(0), int64(1), int64(2)}}},
+		{name: "range from upto", query: "[range(2; 5)]", expected: []interface{}{[]interface{}{int64(2), int64(3), int64(4)}}},
+		{name: "range with step", query: "[range(0; 10; 4)]", expected: []interface{}{[]interface{}{int64(0), int64(4), int64(8)}}},
+		{name: "range downwards", query: "[range(3; 0; -1)]", expected: []interface{}{[]interface{}{int64(3), int64(2), int64(1)}}},
+		{name: "range with float step", query: "[range(0; 1; 0.5)]", expected: []interface{}{[]interface{}{int64(0), 0.5}}},
+		{name: "range wrong direction", query: "[range(3; 0)]", expected: []interface{}{[]interface{}{}}},
+		{name: "range zero step", query: "[range(0; 3; 0)]", expected: []interface{}{[]interface{}{}}},
+		{name: "range per argument result", query: "[range(1, 2)]", expected: []interface{}{[]interface{}{int64(0), int64(0), int64(1)}}},
+		{name: "range of array length", query: "[range(.items | length) as $i | .items[$i].name]", expected: []interface{}{[]interface{}{"a", "b", "c"}}},
+		{name: "range bound not a number", query: "range(\"3\")", errMsg: "range bounds must be numbers, got string"},
+
+		// until and while
+		{name: "until", query: "1 | until(. > 100; . * 2)", expected: []interface{}{int64(128)}},
+		{name: "until already true", query: "5 | until(. > 0; . - 1)", expected: []interface{}{int64(5)}},
+		{name: "while", query: "[1 | while(. < 10; . * 3)]", expected: []interface{}{[]interface{}{int64(1), int64(3), int64(9)}}},
+		{name: "while never true", query: "[1 | while(. > 10; . + 1)]", expected: []interface{}{[]interface{}{}}},
+		{name: "limit of endless while", query: "[limit(4; 1 | while(true; . + 1))]", expected: []interface{}{[]interface{}{int64(1), int64(2), int64(3), int64(4)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func createIterateTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"servers": []map[string]interface{}{
+			{"host": "web1", "port": int64(80)},
+			{"host": "web2", "port": int64(8080)},
+		},
+		"dependencies": map[string]interface{}{
+			"requests": "2.31.0",
+			"click":    "8.1.7",
+			"toml":     "0.10.2",
+		},
+		"tags":  []interface{}{"web", "api"},
+		"empty": []interface{}{},
+		"name":  "app",
+	}
+}
+
+func TestExecute_Iteration(t *testing.T) {
+	data := createIterateTestData()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected []interface{}
+		wantErr  bool
+		errMsg   string
+	}{
+		{name: "array elements", path: ".tags[]", expected: []interface{}{"web", "api"}},
+		{name: "field of every table", path: ".servers[].host", expected: []interface{}{"web1", "web2"}},
+		{name: "wildcard on table", path: ".dependencies.*", expected: []interface{}{"8.1.7", "2.31.0", "0.10.2"}},
+		{name: "brackets on table", path: ".dependencies[]", expected: []interface{}{"8.1.7", "2.31.0", "0.10.2"}},
+		{name: "wildcard on array", path: ".servers.*.port", expected: []interface{}{int64(80), int64(8080)}},
+		{name: "empty array", path: ".empty[]", expected: nil},
+		{name: "iterate then index", path: ".servers[][0]", wantErr: true, errMsg: "cannot navigate into"},
+		{name: "iterate scalar", path: ".name[]", wantErr: true, errMsg: "cannot iterate over string"},
+		{name: "missing key in element", path: ".servers[].missing", wantErr: true, errMsg: "key 'missing' not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := New(tt.path)
+			if err != nil {
+				t.Fatalf("failed to create query: %v", err)
+			}
+
+			results, err := q.Execute(data)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got results %v", results)
+				}
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("expected error containing %q, got %q", tt.errMsg, err.Error())
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(results, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, results)
+			}
+		})
+	}
+}
+
+func TestNew_IterationPaths(t *testing.T) {
+	tests := []struct {
+		path    string
+		wantStr string
+	}{
+		{path: ".servers[]", wantStr: ".servers[]"},
+		{path: ".servers[].host", wantStr: ".servers[].host"},
+		{path: ".dependencies.*", wantStr: ".dependencies[]"},
+		{path: ".*", wantStr: ".[]"},
+		{path: ".[]", wantStr: ".[]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.path, false, "")
+			if q != nil {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func createQuotedKeyTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"example.com": map[string]interface{}{"port": int64(443)},
+		"tool": map[string]interface{}{
+			"black-config": map[string]interface{}{"line-length": int64(88)},
+		},
+		"key with spaces": "spaced",
+		"quote\"key":      "quoted",
+		"ʎǝʞ":             "unicode",
+		"":                "empty",
+	}
+}
+
+func TestNew_QuotedKeys(t *testing.T) {
+	tests := []struct {
+		name          string
+		path          string
+		expectedParts []interface{}
+		wantStr       string
+	}{
+		{name: "basic quoted key", path: `."example.com".port`, expectedParts: []interface{}{"example.com", "port"}, wantStr: `."example.com".port`},
+		{name: "bare key with dash", path: `.tool.black-config`, expectedParts: []interface{}{"tool", "black-config"}, wantStr: `.tool.black-config`},
+		{name: "quoted bare key", path: `.tool."black-config"`, expectedParts: []interface{}{"tool", "black-config"}, wantStr: `.tool.black-config`},
+		{name: "literal key", path: `.'key with spaces'`, expectedParts: []interface{}{"key with spaces"}, wantStr: `."key with spaces"`},
+		{name: "literal key keeps backslash", path: `.'C:\dir'`, expectedParts: []interface{}{`C:\dir`}, wantStr: `."C:\\dir"`},
+		{name: "escaped quote", path: `."quote\"key"`, expectedParts: []interface{}{`quote"key`}, wantStr: `."quote\"key"`},
+		{name: "unicode escape", path: `."\u028E\u01DD\u029E"`, expectedParts: []interface{}{"ʎǝʞ"}, wantStr: `."ʎǝʞ"`},
+		{name: "long unicode escape", path: `."\U0001F600"`, expectedParts: []interface{}{"😀"}, wantStr: `."😀"`},
+		{name: "empty quoted key", path: `.""`, expectedParts: []interface{}{""}, wantStr: `.""`},
+		{name: "quoted wildcard is a key", path: `."*"`, expectedParts: []interface{}{"*"}, wantStr: `."*"`},
+		{name: "whitespace around dots", path: `.tool . "black-config"`, expectedParts: []interface{}{"tool", "black-config"}, wantStr: `.tool.black-config`},
+		{name: "quoted key then index", path: `."my servers"[0]`, expectedParts: []interface{}{"my servers", 0}, wantStr: `."my servers"[0]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.path, false, "")
+			if q == nil {
+				return
+			}
+			if !reflect.DeepEqual(q.Parts(), tt.expectedParts) {
+				t.Errorf("expected parts %#v, got %#v", tt.expectedParts, q.Parts())
+			}
+			assertQueryString(t, q, tt.wantStr)
+		})
+	}
+}
+
+func TestNew_InvalidQuotedKeys(t *testing.T) {
+	tests := []struct {
+		name   string
+		path   string
+		errMsg string
+	}{
+		{name: "unterminated basic", path: `."example.com`, errMsg: "unterminated string"},
+		{name: "unterminated literal", path: `.'example.com`, errMsg: "unterminated string"},
+		{name: "unknown escape", path: `."a\qb"`, errMsg: `invalid escape '\q'`},
+		{name: "short unicode escape", path: `."\u12"`, errMsg: "invalid escape"},
+		{name: "invalid code point", path: `."\uD800"`, errMsg: "invalid escape"},
+		{name: "trailing dot", path: `.a.`, errMsg: "expected key after '.'"},
+		{name: "invalid bare character", path: `.a$b`, errMsg: "unexpected '$b'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertQueryCreation(t, tt.path, true, tt.errMsg)
+		})
+	}
+}
+
+func TestExecute_QuotedKeys(t *testing.T) {
+	data := createQuotedKeyTestData()
+
+	tests := []struct {
+		path     string
+		expected interface{}
+	}{
+		{path: `."example.com".port`, expected: int64(443)},
+		{path: `.tool."black-config".line-length`, expected: int64(88)},
+		{path: `.'key with spaces'`, expected: "spaced"},
+		{path: `."quote\"key"`, expected: "quoted"},
+		{path: `."\u028E\u01DD\u029E"`, expected: "unicode"},
+		{path: `.""`, expected: "empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			q, err := New(tt.path)
+			if err != nil {
+				t.Fatalf("failed to create query: %v", err)
+			}
+
+			assertQueryExecution(t, q, data, false, "", tt.expected)
+		})
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	q, rest, err := ParsePrefix(`."a=b".c = "x=y"`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := q.String(); got != `."a=b".c` {
+		t.Errorf("expected path %q, got %q", `."a=b".c`, got)
+	}
+	if rest != ` = "x=y"` {
+		t.Errorf("expected rest %q, got %q", ` = "x=y"`, rest)
+	}
+
+	// As in a TOML file, the leading dot may be left out
+	for _, tt := range []struct{ s, path, rest string }{
+		{s: `title = "y"`, path: ".title", rest: ` = "y"`},
+		{s: ` owner.name = "x"`, path: ".owner.name", rest: ` = "x"`},
+		{s: `"a b".c[0] = 1`, path: `."a b".c[0]`, rest: ` = 1`},
+		{s: `_x=1`, path: "._x", rest: `=1`},
+	} {
+		q, rest, err := ParsePrefix(tt.s)
+		if err != nil {
+			t.Fatalf("ParsePrefix(%q): unexpected error: %v", tt.s, err)
+		}
+		if q.String() != tt.path || rest != tt.rest {
+			t.Errorf("ParsePrefix(%q) = %q, %q; want %q, %q", tt.s, q.String(), rest, tt.path, tt.rest)
+		}
+	}
+
+	if _, _, err := ParsePrefix(`= 1`); err == nil {
+		t.Error("expected error for missing path, got nil")
+	}
+}
@@ -0,0 +1,133 @@
+package query
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeModules creates the module files, keyed by path relative to dir
+func writeModules(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, src := range files {
+		file := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestNewWithOptions_Modules(t *testing.T) {
+	lib := t.TempDir()
+	extra := t.TempDir()
+	writeModules(t, lib, map[string]string{
+		"servers.tmq": "# Server helpers\n" +
+			"def hosts: [.servers[].host];\n" +
+			"def above($n): .servers[] | select(.port > $n);\n",
+		"deps/deps.tmq": "import \"servers\" as s;\n" +
+			"include \"util\";\n" +
+			"def summary: {hosts: s::hosts, name: name};\n",
+		"deps/util.tmq": "def name: .project.name;\n",
+		"self.tmq":      "import \"self\" as self;\n",
+		"broken.tmq":    "def ok: 1;\ndef bad: .a |;\n",
+		"body.tmq":      "def ok: 1;\n.a\n",
+		"shadow.tmq":    "def name: \"lib\";\n",
+	})
+	writeModules(t, extra, map[string]string{
+		"shadow.tmq": "def name: \"extra\";\n",
+		"other.tmq":  "def other: 2;\n",
+	})
+	data := createVariableTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		path     []string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "import", query: `import "servers" as s; s::hosts`, expected: []interface{}{[]interface{}{"web1", "web2"}}},
+		{name: "value parameter", query: `import "servers" as s; s::above(100) | .host`, expected: []interface{}{"web2"}},
+		{name: "include", query: `include "servers"; hosts | length`, expected: []interface{}{int64(2)}},
+		{
+			name:     "directory module with its own imports",
+			query:    `import "deps" as d; d::summary`,
+			expected: []interface{}{map[string]interface{}{"hosts": []interface{}{"web1", "web2"}, "name": "demo"}},
+		},
+		{name: "included functions are exported", query: `import "deps" as d; d::name`, expected: []interface{}{"demo"}},
+		{name: "imports are not exported", query: `import "deps" as d; d::s::hosts`, errMsg: "d::s::hosts/0 is not defined"},
+		{name: "nested path", query: `import "deps/util" as u; u::name`, expected: []interface{}{"demo"}},
+		{name: "two aliases", query: `import "servers" as a; import "servers" as b; a::hosts == b::hosts`, expected: []interface{}{true}},
+		{name: "query definitions shadow includes", query: `include "deps/util"; def name: "mine"; name`, expected: []interface{}{"mine"}},
+		{name: "first directory wins", query: `include "shadow"; name`, path: []string{"extra", "lib"}, expected: []interface{}{"extra"}},
+		{name: "later directories are searched", query: `include "other"; other`, expected: []interface{}{int64(2)}},
+		{name: "unknown alias", query: `import "servers" as s; t::hosts`, errMsg: "t::hosts/0 is not defined"},
+		{name: "missing module", query: `import "nope" as n; 1`, errMsg: `module "nope" not found in `},
+		{name: "no search path", query: `import "servers" as s; 1`, path: []string{}, errMsg: `module "servers" not found: no library path is set`},
+		{name: "parent directory", query: `import "../servers" as s; 1`, errMsg: `invalid module path "../servers"`},
+		{name: "absolute path", query: `include "/etc/x";`, errMsg: `invalid module path "/etc/x"`},
+		{name: "cycle", query: `import "self" as s; 1`, errMsg: `module "self" imports itself`},
+		{name: "error in module", query: `include "broken"; ok`, errMsg: "broken.tmq at line 2, column 14: unexpected ';'"},
+		{name: "body in module", query: `include "body"; ok`, errMsg: "body.tmq at line 2, column 1: expected 'def', got '.a'"},
+		{name: "missing alias", query: `import "servers"; 1`, errMsg: "expected 'as', got ';'"},
+		{name: "path not a string", query: `include servers; 1`, errMsg: "expected module path after 'include', got 'servers'"},
+		{name: "directive after the start", query: `1 | include "servers"; 2`, errMsg: "unexpected 'include'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			searchPath := []string{lib, extra}
+			if tt.path != nil {
+				searchPath = nil
+				for _, name := range tt.path {
+					searchPath = append(searchPath, map[string]string{"lib": lib, "extra": extra}[name])
+				}
+			}
+
+			q, err := NewWithOptions(tt.query, Options{SearchPath: searchPath})
+			if tt.errMsg != "" {
+				if err == nil {
+					t.Fatalf("expected error containing %q, got nil", tt.errMsg)
+				}
+				if !strings.Contains(err.Error(), tt.errMsg) {
+					t.Errorf("expected error containing %q, got %q", tt.errMsg, err.Error())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			results, err := q.Execute(data)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(results, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, results)
+			}
+		})
+	}
+}
+
+func TestNewWithOptions_ModuleString(t *testing.T) {
+	lib := t.TempDir()
+	writeModules(t, lib, map[string]string{"m.tmq": "def f: 1;\n"})
+
+	q, err := NewWithOptions(`import "m" as m;include "m";m::f+f`, Options{SearchPath: []string{lib}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assertQueryString(t, q, `import "m" as m; include "m"; m::f + f`)
+}
+
+func TestSyntaxError_File(t *testing.T) {
+	err := &SyntaxError{File: "lib/m.tmq", Line: 1, Column: 3, Msg: "unexpected ';'"}
+	expected := "syntax error in lib/m.tmq at line 1, column 3: unexpected ';'"
+	if err.Error() != expected {
+		t.Errorf("expected %q, got %q", expected, err.Error())
+	}
+}
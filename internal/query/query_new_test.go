@@ -91,10 +91,12 @@ func TestNew(t *testing.T) {
 			wantStr: deepNestedPath,
 		},
 		{
+			// A bare name is a function call, so queries need the leading
+			// dot; only set expressions accept bare keys, through ParsePrefix
 			name:    "path without leading dot",
 			path:    noDotPath,
-			wantErr: false,
-			wantStr: ".key.subkey",
+			wantErr: true,
+			errMsg:  "key/0 is not defined",
 		},
 	}
 
@@ -120,10 +122,19 @@ func TestNew_EdgeCases(t *testing.T) {
 	})
 
 	t.Run("multiple dots", func(t *testing.T) {
-		q := assertQueryCreation(t, "..key", false, "")
+		// Empty bare keys are not valid TOML; an empty key must be quoted,
+		// and ".." on its own is recursive descent
+		assertQueryCreation(t, "..key", true, "unexpected 'key'")
+		assertQueryCreation(t, ".a..b", true, "unexpected '..'")
+		if q := assertQueryCreation(t, "..|.a?", false, ""); q != nil {
+			assertQueryString(t, q, ".. | .a?")
+		}
+		assertQueryCreation(t, ".key.", true, "expected key after '.'")
+
+		q := assertQueryCreation(t, `."".key`, false, "")
 		if q != nil {
-			assertQueryString(t, q, "..key")
-			expectedParts := []string{"", "key"}
+			assertQueryString(t, q, `."".key`)
+			expectedParts := []interface{}{"", "key"}
 			parts := q.Parts()
 			if len(parts) != len(expectedParts) {
 				t.Errorf("expected %d parts, got %d", len(expectedParts), len(parts))
@@ -170,11 +181,6 @@ func TestParts(t *testing.T) {
 			path:          deepNestedPath,
 			expectedParts: []string{"a", "b", "c", "d", "e"},
 		},
-		{
-			name:          "path without leading dot",
-			path:          noDotPath,
-			expectedParts: []string{"key", "subkey"},
-		},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,105 @@
+package query
+
+import (
+	"testing"
+)
+
+func createOptionalTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"server": map[string]interface{}{
+			"host":    "localhost",
+			"enabled": false,
+			"retries": nil,
+		},
+		"servers": []map[string]interface{}{
+			{"name": "web1", "port": int64(80)},
+			{"name": "web2"},
+			{"name": "web3", "port": int64(443)},
+		},
+		"name": "app",
+	}
+}
+
+func TestExecute_Optional(t *testing.T) {
+	data := createOptionalTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "missing key", query: ".a.b?", expected: nil},
+		{name: "present key", query: ".server.host?", expected: []interface{}{"localhost"}},
+		{name: "wrong type", query: ".name.first?", expected: nil},
+		{name: "iterate scalar", query: ".name[]?", expected: nil},
+		{name: "optional in the middle", query: ".a?.b", expected: nil},
+		{name: "missing key without ?", query: ".a.b", errMsg: "key 'a' not found"},
+		{name: "each element", query: ".servers[] | .port?", expected: []interface{}{int64(80), int64(443)}},
+		{name: "stops at first error", query: "(.servers[] | .port)?", expected: []interface{}{int64(80)}},
+		{name: "later errors propagate", query: ".name? | keys", errMsg: "string has no keys"},
+		{name: "later errors after output propagate", query: ".servers[]? | .port", errMsg: "key 'port' not found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestExecute_Alternative(t *testing.T) {
+	data := createOptionalTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "missing key", query: ".server.timeout // 30", expected: []interface{}{int64(30)}},
+		{name: "present key", query: `.server.host // "0.0.0.0"`, expected: []interface{}{"localhost"}},
+		{name: "null value", query: ".server.retries // 3", expected: []interface{}{int64(3)}},
+		{name: "false value", query: ".server.enabled // true", expected: []interface{}{true}},
+		{name: "wrong type on the left", query: ".name.first // 1", expected: []interface{}{int64(1)}},
+		{name: "chained", query: ".a // .b // .name", expected: []interface{}{"app"}},
+		{name: "optional then default", query: ".a? // 1", expected: []interface{}{int64(1)}},
+		{name: "generator on the left", query: "(.servers[] | .port?) // 0", expected: []interface{}{int64(80), int64(443)}},
+		{name: "per element default", query: ".servers[] | .port // 8080", expected: []interface{}{int64(80), int64(8080), int64(443)}},
+		{name: "right side errors", query: ".a // .b", errMsg: "key 'b' not found"},
+		{name: "later errors propagate", query: ".name // 1 | keys", errMsg: "string has no keys"},
+		{name: "binds looser than arithmetic", query: ".a // 1 + 2", expected: []interface{}{int64(3)}},
+		{name: "binds looser than or", query: "null // false or true", expected: []interface{}{true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestNew_OptionalString(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantStr string
+	}{
+		{query: ".a.b?", wantStr: ".a.b?"},
+		{query: ".a?.b", wantStr: ".a?.b"},
+		{query: ".a[]?", wantStr: ".a[]?"},
+		{query: "(.a | .b)?", wantStr: "(.a | .b)?"},
+		{query: ".a//.b//.c", wantStr: ".a // .b // .c"},
+		{query: "(.a // .b) // .c", wantStr: "(.a // .b) // .c"},
+		{query: ".a // 1 + 2", wantStr: ".a // 1 + 2"},
+		{query: "(.a // 1) + 2", wantStr: "(.a // 1) + 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, false, "")
+			if q != nil {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
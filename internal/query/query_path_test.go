@@ -0,0 +1,146 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func createPathTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"project": map[string]interface{}{"name": "demo", "extra": map[string]interface{}{}},
+		"servers": []map[string]interface{}{
+			{"host": "web1", "port": int64(80)},
+			{"host": "web2", "port": int64(8080)},
+		},
+		"tags":  []interface{}{"a", "b"},
+		"empty": map[string]interface{}{},
+	}
+}
+
+// p builds a path array of the query language
+func p(parts ...interface{}) []interface{} {
+	return append([]interface{}{}, parts...)
+}
+
+func TestExecute_PathFunctions(t *testing.T) {
+	data := createPathTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// path
+		{name: "path of key", query: "path(.project.name)", expected: []interface{}{p("project", "name")}},
+		{name: "path of root", query: "path(.)", expected: []interface{}{p()}},
+		{name: "path of missing key", query: "path(.nope.deeper)", expected: []interface{}{p("nope", "deeper")}},
+		{name: "path of index", query: "path(.servers[-1].host)", expected: []interface{}{p("servers", int64(-1), "host")}},
+		{name: "path of iteration", query: "[path(.servers[].port)]", expected: []interface{}{[]interface{}{p("servers", int64(0), "port"), p("servers", int64(1), "port")}}},
+		{name: "path of table iteration", query: "[path(.project[])]", expected: []interface{}{[]interface{}{p("project", "extra"), p("project", "name")}}},
+		{
+			name:     "path of slice",
+			query:    "path(.tags[1:])",
+			expected: []interface{}{p("tags", map[string]interface{}{"start": int64(1), "end": nil})},
+		},
+		{name: "path through select", query: "[path(.servers[] | select(.port > 100))]", expected: []interface{}{[]interface{}{p("servers", int64(1))}}},
+		{name: "path of alternatives", query: "[path(.a, .b)]", expected: []interface{}{[]interface{}{p("a"), p("b")}}},
+		{name: "path of computed index", query: `path(.servers[.tags | length - 1])`, expected: []interface{}{p("servers", int64(1))}},
+		{name: "path through function", query: "def port: .port; [path(.servers[] | port)] | length", expected: []interface{}{int64(2)}},
+		{name: "path through first", query: "path(first(.servers[]))", expected: []interface{}{p("servers", int64(0))}},
+		{name: "path through getpath", query: `path(.servers | getpath([0, "host"]))`, expected: []interface{}{p("servers", int64(0), "host")}},
+		{name: "path through if", query: `[path(.servers[] | if .port > 100 then .host else .port end)]`, expected: []interface{}{[]interface{}{p("servers", int64(0), "port"), p("servers", int64(1), "host")}}},
+		{name: "path through try", query: `[path(try (.servers[0], error("x")) catch .)]`, errMsg: `invalid path expression with result "x"`},
+		{name: "path of computed value", query: "path(.servers | length)", errMsg: "invalid path expression with result 2"},
+		{name: "path of literal", query: `path("x")`, errMsg: `invalid path expression with result "x"`},
+
+		// paths and leaf_paths
+		{
+			name:  "paths",
+			query: "[paths]",
+			expected: []interface{}{[]interface{}{
+				p("empty"), p("project"), p("project", "extra"), p("project", "name"),
+				p("servers"), p("servers", int64(0)), p("servers", int64(0), "host"), p("servers", int64(0), "port"),
+				p("servers", int64(1)), p("servers", int64(1), "host"), p("servers", int64(1), "port"),
+				p("tags"), p("tags", int64(0)), p("tags", int64(1)),
+			}},
+		},
+		{name: "paths with filter", query: "[paths(. == {})]", expected: []interface{}{[]interface{}{p("empty"), p("project", "extra")}}},
+		{name: "paths with type filter", query: `[paths(type == "number")]`, expected: []interface{}{[]interface{}{p("servers", int64(0), "port"), p("servers", int64(1), "port")}}},
+		{name: "leaf paths", query: "[.servers[0] | leaf_paths]", expected: []interface{}{[]interface{}{p("host"), p("port")}}},
+		{name: "paths of a scalar", query: "[1 | paths]", expected: []interface{}{[]interface{}{}}},
+
+		// getpath
+		{name: "getpath", query: `getpath(["servers", 1, "host"])`, expected: []interface{}{"web2"}},
+		{name: "getpath missing", query: `getpath(["nope", "deeper"])`, expected: []interface{}{nil}},
+		{name: "getpath out of range", query: `getpath(["tags", 5])`, expected: []interface{}{nil}},
+		{name: "getpath of path", query: `getpath(path(.servers[0].port))`, expected: []interface{}{int64(80)}},
+		{name: "getpath into a scalar", query: `getpath(["project", "name", "x"])`, errMsg: "cannot navigate into string at 'x'"},
+		{name: "getpath not an array", query: `getpath("servers")`, errMsg: "path must be an array, got string"},
+		{name: "getpath bad component", query: `getpath([true])`, errMsg: "invalid path component true"},
+
+		// setpath, delpaths and del
+		{name: "setpath", query: `setpath(["servers", 0, "port"]; 81) | .servers[0].port`, expected: []interface{}{int64(81)}},
+		{name: "setpath creates tables", query: `setpath(["a", "b"]; 1) | .a`, expected: []interface{}{map[string]interface{}{"b": int64(1)}}},
+		{name: "setpath root", query: `setpath([]; 1)`, expected: []interface{}{int64(1)}},
+		{name: "setpath keeps the input", query: `[(setpath(["tags", 0]; "z") | .tags), .tags]`, expected: []interface{}{[]interface{}{[]interface{}{"z", "b"}, []interface{}{"a", "b"}}}},
+		{name: "setpath appends", query: `setpath(["tags", 2]; "c") | .tags`, expected: []interface{}{[]interface{}{"a", "b", "c"}}},
+		{name: "setpath creates arrays", query: `setpath(["bin", 0, "name"]; "x") | .bin`, expected: []interface{}{[]interface{}{map[string]interface{}{"name": "x"}}}},
+		{name: "setpath out of range", query: `setpath(["tags", 5]; 1)`, errMsg: "index out of range: .tags[5] (length 2)"},
+		{name: "delpaths", query: `delpaths([["tags", 0], ["project"]]) | keys`, expected: []interface{}{p("empty", "servers", "tags")}},
+		{name: "delpaths later indexes first", query: `delpaths([["tags", 0], ["tags", 1]]) | .tags`, expected: []interface{}{[]interface{}{}}},
+		{name: "delpaths of paths", query: `delpaths([paths(. == {})]) | keys`, expected: []interface{}{p("project", "servers", "tags")}},
+		{name: "delpaths nested first", query: `delpaths([["project"], ["project", "name"]]) | has("project")`, expected: []interface{}{false}},
+		{name: "delpaths root", query: `delpaths([[]])`, expected: []interface{}{nil}},
+		{name: "delpaths missing key", query: `delpaths([["nope"]])`, errMsg: "key not found: .nope"},
+		{name: "delpaths not an array", query: `delpaths(["tags"])`, errMsg: "path must be an array, got string"},
+		{name: "del", query: `.servers | del(.[] | select(.port < 100)) | map(.host)`, expected: []interface{}{[]interface{}{"web2"}}},
+		{name: "del keeps the input", query: `[del(.tags), .tags] | .[1]`, expected: []interface{}{[]interface{}{"a", "b"}}},
+		{name: "del keeps arrays of tables", query: `del(.servers[0].port) | .servers`, expected: []interface{}{[]map[string]interface{}{{"host": "web1"}, {"host": "web2", "port": int64(8080)}}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestQuery_Paths(t *testing.T) {
+	data := createPathTestData()
+	one := 1
+
+	tests := []struct {
+		query    string
+		expected [][]interface{}
+		errMsg   string
+	}{
+		{query: ".project.name", expected: [][]interface{}{{"project", "name"}}},
+		{query: ".servers[] | select(.port > 100) | .host", expected: [][]interface{}{{"servers", 1, "host"}}},
+		{query: ".tags[1:]", expected: [][]interface{}{{"tags", Slice{Start: &one}}}},
+		{query: "paths(. == {}) as $p | getpath($p)", expected: [][]interface{}{{"empty"}, {"project", "extra"}}},
+		{query: ".servers | map(.port)", errMsg: "invalid path expression with result [80,8080]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := New(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			paths, err := q.Paths(data)
+			if tt.errMsg != "" {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Fatalf("expected error %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(paths, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, paths)
+			}
+		})
+	}
+}
@@ -0,0 +1,171 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+func createPipeTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"tool": map[string]interface{}{
+			"poetry": map[string]interface{}{
+				"name": "demo",
+				"dependencies": map[string]interface{}{
+					"requests": "2.31.0",
+					"click":    "8.1.7",
+				},
+			},
+		},
+		"servers": []map[string]interface{}{
+			{"host": "web1", "port": int64(80)},
+			{"host": "web2", "port": int64(8080)},
+		},
+		"ports": []interface{}{int64(80), int64(443), int64(8080)},
+	}
+}
+
+func TestExecute_Pipe(t *testing.T) {
+	data := createPipeTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{
+			name:     "chained paths",
+			query:    ".tool.poetry | .dependencies | keys",
+			expected: []interface{}{[]interface{}{"click", "requests"}},
+		},
+		{
+			name:     "identity in pipe",
+			query:    ". | .tool | . | .poetry.name",
+			expected: []interface{}{"demo"},
+		},
+		{
+			name:     "pipe runs once per result",
+			query:    ".servers[] | .host",
+			expected: []interface{}{"web1", "web2"},
+		},
+		{
+			name:     "keys of array",
+			query:    ".ports | keys",
+			expected: []interface{}{[]interface{}{int64(0), int64(1), int64(2)}},
+		},
+		{
+			name:     "parenthesized pipe with suffix",
+			query:    "(.tool | .poetry).name",
+			expected: []interface{}{"demo"},
+		},
+		{
+			name:     "index from expression",
+			query:    ".ports[.servers | keys | .[1]]",
+			expected: []interface{}{int64(443)},
+		},
+		{
+			name:     "key from string literal",
+			query:    `.tool["poetry"].name`,
+			expected: []interface{}{"demo"},
+		},
+		{
+			name:     "literal ignores input",
+			query:    `.tool | "x"`,
+			expected: []interface{}{"x"},
+		},
+		{
+			name:     "negative literal",
+			query:    "-2.5",
+			expected: []interface{}{-2.5},
+		},
+		{
+			name:     "comments are ignored",
+			query:    ".tool # the tool table\n| .poetry.name",
+			expected: []interface{}{"demo"},
+		},
+		{
+			name:   "keys of scalar",
+			query:  ".tool.poetry.name | keys",
+			errMsg: "string has no keys",
+		},
+		{
+			name:   "error in right side",
+			query:  ".tool | .missing",
+			errMsg: "key 'missing' not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestNew_SyntaxErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		query  string
+		line   int
+		column int
+		msg    string
+	}{
+		{name: "missing right side", query: ".a |", line: 1, column: 5, msg: "unexpected end of query"},
+		{name: "missing left side", query: "| .a", line: 1, column: 1, msg: "unexpected '|'"},
+		{name: "unclosed parenthesis", query: "(.a | .b", line: 1, column: 9, msg: "expected ')', got end of query"},
+		{name: "stray parenthesis", query: ".a)", line: 1, column: 3, msg: "unexpected ')'"},
+		{name: "undefined function", query: ".a | nope", line: 1, column: 6, msg: "nope/0 is not defined"},
+		{name: "wrong arity", query: "keys(.a)", line: 1, column: 1, msg: "keys/1 is not defined"},
+		{name: "invalid character", query: ".a | $", line: 1, column: 6, msg: "unexpected '$'"},
+		{name: "second line", query: ".a |\n  .b |", line: 2, column: 7, msg: "unexpected end of query"},
+		{name: "bad escape", query: `.a | "\q"`, line: 1, column: 6, msg: `invalid escape '\q'`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.query)
+			var syntaxErr *SyntaxError
+			if !errors.As(err, &syntaxErr) {
+				t.Fatalf("expected SyntaxError, got %v", err)
+			}
+			if syntaxErr.Line != tt.line || syntaxErr.Column != tt.column {
+				t.Errorf("expected error at %d:%d, got %d:%d (%v)", tt.line, tt.column, syntaxErr.Line, syntaxErr.Column, err)
+			}
+			if syntaxErr.Msg != tt.msg {
+				t.Errorf("expected message %q, got %q", tt.msg, syntaxErr.Msg)
+			}
+		})
+	}
+}
+
+func TestNew_PipeString(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantStr   string
+		wantParts bool
+	}{
+		{query: ".tool.poetry|.dependencies|keys", wantStr: ".tool.poetry | .dependencies | keys"},
+		{query: "(.a | .b).c", wantStr: "(.a | .b).c"},
+		{query: "(.a).b", wantStr: ".a.b", wantParts: true},
+		{query: `.a . b . "c d"`, wantStr: `.a.b."c d"`, wantParts: true},
+		{query: `.a["b"][0]`, wantStr: `.a["b"][0]`, wantParts: true},
+		{query: ".a[.i]", wantStr: ".a[.i]"},
+		{query: ".a[-1:]", wantStr: ".a[-1:]", wantParts: true},
+		{query: "1.5", wantStr: "1.5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q, err := New(tt.query)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := q.String(); got != tt.wantStr {
+				t.Errorf("expected %q, got %q", tt.wantStr, got)
+			}
+			if got := q.Parts() != nil; got != tt.wantParts {
+				t.Errorf("expected Parts() != nil to be %v, got parts %v", tt.wantParts, q.Parts())
+			}
+		})
+	}
+}
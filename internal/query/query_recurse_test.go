@@ -0,0 +1,100 @@
+package query
+
+import (
+	"testing"
+)
+
+func createRecurseTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"name": "Demo",
+		"database": map[string]interface{}{
+			"host":     "DB",
+			"password": "secret",
+		},
+		"servers": []map[string]interface{}{
+			{"name": "WEB", "password": "hunter2", "ports": []interface{}{int64(80), int64(443)}},
+		},
+	}
+}
+
+func TestExecute_Recurse(t *testing.T) {
+	data := createRecurseTestData()
+	servers := data["servers"].([]map[string]interface{})
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// .. and recurse
+		{name: "every value", query: "[..] | length", expected: []interface{}{int64(12)}},
+		{name: "scalar", query: "[1 | ..]", expected: []interface{}{[]interface{}{int64(1)}}},
+		{name: "parents before contents", query: "[.servers[0].ports | ..]", expected: []interface{}{[]interface{}{[]interface{}{int64(80), int64(443)}, int64(80), int64(443)}}},
+		{name: "tables in key order", query: "[.database | .. | select(type == \"string\")]", expected: []interface{}{[]interface{}{"DB", "secret"}}},
+		{name: "find a key anywhere", query: "[.. | select(type == \"object\" and has(\"password\")) | .password]", expected: []interface{}{[]interface{}{"secret", "hunter2"}}},
+		{name: "recurse is ..", query: "[recurse] == [..]", expected: []interface{}{true}},
+		{name: "recurse with a filter", query: "[2 | recurse(select(. < 20) | . * 3)]", expected: []interface{}{[]interface{}{int64(2), int64(6), int64(18), int64(54)}}},
+		{name: "recurse with a condition", query: "[2 | recurse(. * 3; . < 20)]", expected: []interface{}{[]interface{}{int64(2), int64(6), int64(18)}}},
+		{name: "recurse into tables only", query: "[recurse(.[]?; type == \"object\")] | length", expected: []interface{}{int64(2)}},
+		{name: "recurse with a failing filter", query: "[.name | recurse(.[])]", errMsg: "cannot iterate over string"},
+		{name: "children of every value", query: "[.. | .[]?] | length", expected: []interface{}{int64(11)}},
+
+		// walk
+		{name: "lowercase every string", query: "walk(if type == \"string\" then ascii_downcase else . end) | .servers[0].name, .database.host", expected: []interface{}{"web", "db"}},
+		{name: "walk is bottom-up", query: "[[1, [2]] | walk(if type == \"array\" then length else . end)]", expected: []interface{}{[]interface{}{int64(2)}}},
+		{name: "walk keeps arrays of tables", query: "walk(.) | .servers", expected: []interface{}{servers}},
+		{name: "walk drops table values without results", query: "{a: 1, b: \"x\"} | walk(select(type != \"number\"))", expected: []interface{}{map[string]interface{}{"b": "x"}}},
+		{name: "walk keeps every array result", query: "[1, 2] | walk(if type == \"number\" then (., .) else . end)", expected: []interface{}{[]interface{}{int64(1), int64(1), int64(2), int64(2)}}},
+		{name: "walk leaves the input alone", query: "(walk(if type == \"string\" then \"x\" else . end) | .name), .name", expected: []interface{}{"x", "Demo"}},
+
+		// paths
+		{name: "paths of ..", query: "[path(..)] | .[0:3]", expected: []interface{}{[]interface{}{p(), p("database"), p("database", "host")}}},
+		{name: "delete a key anywhere", query: "del(.. | select(type == \"object\" and has(\"password\")) | .password) | [.. | select(type == \"object\") | has(\"password\")] | any", expected: []interface{}{false}},
+		{name: "paths of recurse", query: "[path(.servers[0].ports | recurse(.[]?; . > 100))]", expected: []interface{}{[]interface{}{p("servers", int64(0), "ports"), p("servers", int64(0), "ports", int64(1))}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestExecute_Streams(t *testing.T) {
+	data := createRecurseTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "tostream of a table", query: "[.database | tostream]", expected: []interface{}{[]interface{}{
+			[]interface{}{p("host"), "DB"},
+			[]interface{}{p("password"), "secret"},
+			[]interface{}{p("password")},
+		}}},
+		{name: "tostream of nested arrays", query: "[{a: [1, []]} | tostream]", expected: []interface{}{[]interface{}{
+			[]interface{}{p("a", int64(0)), int64(1)},
+			[]interface{}{p("a", int64(1)), []interface{}{}},
+			[]interface{}{p("a", int64(1))},
+			[]interface{}{p("a")},
+		}}},
+		{name: "tostream of a scalar", query: "[1 | tostream]", expected: []interface{}{[]interface{}{[]interface{}{p(), int64(1)}}}},
+		{name: "leaf paths and values", query: "[tostream | select(length == 2) | .[0] | join(\".\")] | .[0:2]", expected: []interface{}{[]interface{}{"database.host", "database.password"}}},
+		{name: "round trip", query: "fromstream(tostream) == .", expected: []interface{}{true}},
+		{name: "round trip of a scalar", query: "fromstream(\"x\" | tostream)", expected: []interface{}{"x"}},
+		{name: "one value per top-level close", query: "[fromstream(([1, 2], {a: {}}) | tostream)]", expected: []interface{}{[]interface{}{[]interface{}{int64(1), int64(2)}, map[string]interface{}{"a": map[string]interface{}{}}}}},
+		{name: "rewrite leaves", query: "fromstream(.database | tostream | if length == 2 then [.[0], (.[1] | ascii_downcase)] else . end)", expected: []interface{}{map[string]interface{}{"host": "db", "password": "secret"}}},
+		{name: "invalid event", query: "fromstream(1)", errMsg: "1 is not a valid stream event"},
+		{name: "event out of order", query: "fromstream([[1], \"x\"], [[1]])", errMsg: "stream event index 1 is out of order"},
+		{name: "event that does not fit", query: "fromstream([[0], 1], [[\"a\"], 2], [[\"a\"]])", errMsg: "stream event key \"a\" does not fit in array"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package query
+
+import (
+	"testing"
+)
+
+func createReduceTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"items": []map[string]interface{}{
+			{"name": "a", "size": int64(3)},
+			{"name": "b", "size": int64(5)},
+			{"name": "c", "size": int64(2)},
+		},
+		"words": []interface{}{"x", "y"},
+	}
+}
+
+func TestExecute_Reduce(t *testing.T) {
+	data := createReduceTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// reduce
+		{name: "sum", query: "reduce .items[] as $i (0; . + $i.size)", expected: []interface{}{int64(10)}},
+		{
+			name:     "build a table",
+			query:    "reduce .items[] as $i ({}; . + {($i.name): $i.size})",
+			expected: []interface{}{map[string]interface{}{"a": int64(3), "b": int64(5), "c": int64(2)}},
+		},
+		{name: "source runs against the input", query: "reduce .words[] as $w (\"\"; . + $w)", expected: []interface{}{"xy"}},
+		{name: "no source results", query: "reduce select(false) as $x (7; . + 1)", expected: []interface{}{int64(7)}},
+		{name: "update keeps its last result", query: "reduce (1, 2) as $x (0; . + $x, 100)", expected: []interface{}{int64(100)}},
+		{name: "update produces nothing", query: "reduce (1, 2) as $x (0; select(false))", expected: []interface{}{nil}},
+		{name: "one reduction per init", query: "reduce (1, 2) as $x (0, 10; . + $x)", expected: []interface{}{int64(3), int64(13)}},
+		{name: "error in update", query: "reduce .items[] as $i (0; . + $i.name)", errMsg: "number and string cannot be added"},
+
+		// foreach
+		{name: "running total", query: "[foreach .items[] as $i (0; . + $i.size)]", expected: []interface{}{[]interface{}{int64(3), int64(8), int64(10)}}},
+		{
+			name:     "extract",
+			query:    "[foreach .items[] as $i (0; . + $i.size; [$i.name, .])]",
+			expected: []interface{}{[]interface{}{[]interface{}{"a", int64(3)}, []interface{}{"b", int64(8)}, []interface{}{"c", int64(10)}}},
+		},
+		{name: "update produces several states", query: "[foreach (1, 2) as $x (0; . + $x, . + 10)]", expected: []interface{}{[]interface{}{int64(1), int64(10), int64(12), int64(20)}}},
+		{name: "empty update keeps state", query: "[foreach (1, 2, 3) as $x (0; select($x != 2) | . + $x)]", expected: []interface{}{[]interface{}{int64(1), int64(4)}}},
+		{name: "extract produces nothing", query: "[foreach .items[] as $i (0; . + 1; select(. > 1))]", expected: []interface{}{[]interface{}{int64(2), int64(3)}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestNew_Reduce(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		errMsg  string
+		wantStr string
+	}{
+		{query: "reduce .a[] as $x (0;.+$x)", wantStr: "reduce .a[] as $x (0; . + $x)"},
+		{query: "reduce (.a, .b) as $x (0; $x)", wantStr: "reduce (.a, .b) as $x (0; $x)"},
+		{query: "foreach .a[] as $x (0; . + 1)", wantStr: "foreach .a[] as $x (0; . + 1)"},
+		{query: "foreach .a[] as $x (0; . + 1; [$x, .])", wantStr: "foreach .a[] as $x (0; . + 1; [$x, .])"},
+		{query: "reduce .a[] as $x (0; .) | . + 1", wantStr: "reduce .a[] as $x (0; .) | . + 1"},
+		{query: "reduce .a[] as $x ($x; .)", wantErr: true, errMsg: "$x is not defined"},
+		{query: "reduce .a[] as $x (0; .) | $x", wantErr: true, errMsg: "$x is not defined"},
+		{query: "reduce .a[] as $x (0; .; .)", wantErr: true, errMsg: "expected ')', got ';'"},
+		{query: "reduce .a[] $x (0; .)", wantErr: true, errMsg: "expected 'as' after reduce source, got '$x'"},
+		{query: "foreach .a[] as x (0; .)", wantErr: true, errMsg: "expected variable after 'as', got 'x'"},
+		{query: "reduce .a[] as $x (0)", wantErr: true, errMsg: "expected ';', got ')'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, tt.wantErr, tt.errMsg)
+			if q != nil && !tt.wantErr {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
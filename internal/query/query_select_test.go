@@ -0,0 +1,184 @@
+package query
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func createSelectTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"servers": []map[string]interface{}{
+			{"name": "web1", "port": int64(80), "enabled": true},
+			{"name": "web2", "port": int64(8080), "enabled": false},
+			{"name": "api", "port": int64(9000), "enabled": true},
+			{"name": "db", "port": 5432.0, "enabled": true},
+		},
+		"released": time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+		"updated":  time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestExecute_Select(t *testing.T) {
+	data := createSelectTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{
+			name:     "comparison and field",
+			query:    ".servers[] | select(.port > 1024 and .enabled) | .name",
+			expected: []interface{}{"api", "db"},
+		},
+		{
+			name:     "equality on strings",
+			query:    `.servers[] | select(.name == "web2") | .port`,
+			expected: []interface{}{int64(8080)},
+		},
+		{
+			name:     "or",
+			query:    ".servers[] | select(.port < 100 or .port >= 9000) | .name",
+			expected: []interface{}{"web1", "api"},
+		},
+		{
+			name:     "not",
+			query:    ".servers[] | select(.enabled | not) | .name",
+			expected: []interface{}{"web2"},
+		},
+		{
+			name:     "false condition drops everything",
+			query:    ".servers[] | select(false)",
+			expected: nil,
+		},
+		{
+			name:     "datetimes compare chronologically",
+			query:    ".released < .updated",
+			expected: []interface{}{true},
+		},
+		{
+			name:   "and short-circuits",
+			query:  ".servers[0] | .enabled and .missing",
+			errMsg: "key 'missing' not found",
+		},
+		{
+			name:     "false and skips right side",
+			query:    ".servers[1] | .enabled and .missing",
+			expected: []interface{}{false},
+		},
+		{
+			name:     "true or skips right side",
+			query:    ".servers[0] | .enabled or .missing",
+			expected: []interface{}{true},
+		},
+		{
+			name:   "condition error",
+			query:  ".servers[] | select(.missing)",
+			errMsg: "key 'missing' not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestExecute_Comparison(t *testing.T) {
+	data := map[string]interface{}{}
+
+	tests := []struct {
+		query    string
+		expected interface{}
+	}{
+		{query: "1 == 1.0", expected: true},
+		{query: "1 != 2", expected: true},
+		{query: "9007199254740993 > 9007199254740992", expected: true},
+		{query: `"abc" < "abd"`, expected: true},
+		{query: `"10" < 9`, expected: false},
+		{query: "null < false", expected: true},
+		{query: "false < true", expected: true},
+		{query: "true < 0", expected: true},
+		{query: "null == false", expected: false},
+		{query: "1 <= 1", expected: true},
+		{query: "2 >= 3", expected: false},
+		{query: "(1 < 2) == true", expected: true},
+		{query: "true and null", expected: false},
+		{query: "null or 0", expected: true},
+		{query: "1 < 2 and 3 < 2 or true", expected: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			assertResults(t, tt.query, data, []interface{}{tt.expected}, "")
+		})
+	}
+}
+
+func TestCompareValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		a, b     interface{}
+		expected int
+	}{
+		{name: "int and float", a: int64(2), b: 2.5, expected: -1},
+		{name: "nan below numbers", a: math.NaN(), b: math.Inf(-1), expected: -1},
+		{name: "number below datetime", a: int64(1), b: time.Time{}, expected: -1},
+		{name: "datetime below string", a: time.Time{}, b: "", expected: -1},
+		{name: "arrays by element", a: []interface{}{int64(1), int64(2)}, b: []interface{}{int64(1), int64(3)}, expected: -1},
+		{name: "shorter array first", a: []interface{}{int64(1)}, b: []interface{}{int64(1), int64(0)}, expected: -1},
+		{
+			name:     "array of tables equals generic array",
+			a:        []map[string]interface{}{{"a": int64(1)}},
+			b:        []interface{}{map[string]interface{}{"a": int64(1)}},
+			expected: 0,
+		},
+		{name: "tables by keys first", a: map[string]interface{}{"b": int64(0)}, b: map[string]interface{}{"a": int64(9), "c": int64(0)}, expected: 1},
+		{name: "tables by values", a: map[string]interface{}{"a": int64(1)}, b: map[string]interface{}{"a": int64(2)}, expected: -1},
+		{name: "interface tables", a: map[interface{}]interface{}{"a": int64(1)}, b: map[string]interface{}{"a": int64(1)}, expected: 0},
+		{name: "array below table", a: []interface{}{}, b: map[string]interface{}{}, expected: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareValues(tt.a, tt.b); got != tt.expected {
+				t.Errorf("expected %d, got %d", tt.expected, got)
+			}
+			if got := compareValues(tt.b, tt.a); got != -tt.expected {
+				t.Errorf("expected %d with operands swapped, got %d", -tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestNew_OperatorSyntax(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantStr string
+		errMsg  string
+	}{
+		{query: ".a==1", wantStr: ".a == 1"},
+		{query: ".a and .b or .c", wantStr: ".a and .b or .c"},
+		{query: ".a and (.b or .c)", wantStr: ".a and (.b or .c)"},
+		{query: "(.a < .b) == false", wantStr: "(.a < .b) == false"},
+		{query: "(.a | .b) and null", wantStr: "(.a | .b) and null"},
+		{query: "select(.x >= 1)", wantStr: "select(.x >= 1)"},
+		{query: ".a < .b < .c", errMsg: "column 9: unexpected '<'"},
+		{query: ".a and", errMsg: "unexpected end of query"},
+		{query: "and .a", errMsg: "column 1: unexpected 'and'"},
+		{query: ".a = 1 = 2", errMsg: "column 8: unexpected '='"},
+		{query: "select", errMsg: "select/0 is not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, tt.errMsg != "", tt.errMsg)
+			if q != nil {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
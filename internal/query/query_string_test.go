@@ -0,0 +1,110 @@
+package query
+
+import (
+	"testing"
+)
+
+func createStringTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"project": map[string]interface{}{
+			"name":    "Demo-App",
+			"version": "2.31.0",
+		},
+		"dependencies": map[string]interface{}{
+			"pytest":     "8.0",
+			"pytest-cov": "4.1",
+			"requests":   "2.31.0",
+		},
+		"words": []interface{}{"a", int64(1), nil, true, 2.5},
+		"path":  "/usr/local/bin",
+		"text":  "héllo wörld",
+	}
+}
+
+func TestExecute_StringBuiltins(t *testing.T) {
+	data := createStringTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "major version", query: `.project.version | split(".") | .[0]`, expected: []interface{}{"2"}},
+		{name: "split empty string", query: `"" | split(",")`, expected: []interface{}{[]interface{}{}}},
+		{name: "split non-string", query: `.words | split(",")`, errMsg: "split cannot be applied to array, expected a string"},
+		{name: "split with regex", query: `"a1b22c" | split("[0-9]+"; null)`, expected: []interface{}{[]interface{}{"a", "b", "c"}}},
+		{name: "join", query: `.path | split("/") | join("-")`, expected: []interface{}{"-usr-local-bin"}},
+		{name: "join scalars", query: `.words | join(",")`, expected: []interface{}{"a,1,,true,2.5"}},
+		{name: "join table", query: `.words | join(.)`, errMsg: "join expects a string argument, got array"},
+		{name: "ascii_downcase", query: ".project.name | ascii_downcase", expected: []interface{}{"demo-app"}},
+		{name: "ascii_upcase leaves other letters", query: ".text | ascii_upcase", expected: []interface{}{"HéLLO WöRLD"}},
+		{name: "startswith", query: `.dependencies | keys | map(select(startswith("pytest")))`, expected: []interface{}{[]interface{}{"pytest", "pytest-cov"}}},
+		{name: "endswith", query: `.path | endswith("/bin")`, expected: []interface{}{true}},
+		{name: "startswith non-string", query: `.words | startswith("a")`, errMsg: "startswith cannot be applied to array"},
+		{name: "ltrimstr", query: `.path | ltrimstr("/usr")`, expected: []interface{}{"/local/bin"}},
+		{name: "rtrimstr", query: `.path | rtrimstr("/bin")`, expected: []interface{}{"/usr/local"}},
+		{name: "ltrimstr no match", query: `.path | ltrimstr("bin")`, expected: []interface{}{"/usr/local/bin"}},
+		{name: "ltrimstr non-string", query: `.words | ltrimstr("a") | length`, expected: []interface{}{int64(5)}},
+		{name: "arguments are generators", query: `.path | startswith(split("/") | .[0:2] | join("/"))`, expected: []interface{}{true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestExecute_RegexBuiltins(t *testing.T) {
+	data := createStringTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "test", query: `.project.version | test("^[0-9]+\\.")`, expected: []interface{}{true}},
+		{name: "test with flags", query: `.project.name | test("demo"; "i")`, expected: []interface{}{true}},
+		{name: "test case sensitive", query: `.project.name | test("demo")`, expected: []interface{}{false}},
+		{name: "filter keys by regex", query: `.dependencies | keys[] | select(test("^py"))`, expected: []interface{}{"pytest", "pytest-cov"}},
+		{name: "invalid regex", query: `.path | test("(")`, errMsg: "test: invalid regex"},
+		{name: "unsupported flag", query: `.path | test("a"; "x")`, errMsg: "unsupported regex flag 'x'"},
+		{name: "test non-string", query: `.words | test("a")`, errMsg: "test cannot be applied to array"},
+		{
+			name:  "match",
+			query: `.text | match("(w)(?P<rest>ö\\w*)")`,
+			expected: []interface{}{map[string]interface{}{
+				"offset": int64(6),
+				"length": int64(5),
+				"string": "wörld",
+				"captures": []interface{}{
+					map[string]interface{}{"offset": int64(6), "length": int64(1), "string": "w", "name": nil},
+					map[string]interface{}{"offset": int64(7), "length": int64(4), "string": "örld", "name": "rest"},
+				},
+			}},
+		},
+		{name: "match global", query: `"a1b2" | match("[0-9]"; "g") | .offset`, expected: []interface{}{int64(1), int64(3)}},
+		{name: "match none", query: `"abc" | match("[0-9]")`, expected: nil},
+		{name: "match ignores empty", query: `"ab" | match("x*"; "gn")`, expected: nil},
+		{
+			name:     "capture",
+			query:    `.project.version | capture("(?P<major>\\d+)\\.(?P<minor>\\d+)(?P<pre>-\\w+)?")`,
+			expected: []interface{}{map[string]interface{}{"major": "2", "minor": "31", "pre": nil}},
+		},
+		{name: "sub", query: `.project.version | sub("\\."; "-")`, expected: []interface{}{"2-31.0"}},
+		{name: "gsub", query: `.project.version | gsub("\\."; "-")`, expected: []interface{}{"2-31-0"}},
+		{name: "sub with global flag", query: `.project.version | sub("\\."; "-"; "g")`, expected: []interface{}{"2-31-0"}},
+		{name: "gsub uses captures", query: `"a-b" | gsub("(?P<c>[a-z])"; .c | ascii_upcase)`, expected: []interface{}{"A-B"}},
+		{name: "gsub without match", query: `"abc" | gsub("x"; "y")`, expected: []interface{}{"abc"}},
+		{name: "gsub case insensitive", query: `"aAa" | gsub("a"; "b"; "i")`, expected: []interface{}{"bbb"}},
+		{name: "replacement not a string", query: `"abc" | sub("b"; 1)`, errMsg: "replacement must be a string, got number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
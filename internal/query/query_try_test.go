@@ -0,0 +1,116 @@
+package query
+
+import (
+	"errors"
+	"testing"
+)
+
+func createTryTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"servers": []map[string]interface{}{
+			{"name": "web", "port": int64(8080), "tls": true},
+			{"name": "ssh", "port": int64(22), "tls": false},
+		},
+		"name": "demo",
+	}
+}
+
+func TestExecute_TryCatch(t *testing.T) {
+	data := createTryTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		// error
+		{name: "error with a message", query: "error(\"privileged port\")", errMsg: "privileged port"},
+		{name: "error of the input", query: ".name | error", errMsg: "demo"},
+		{name: "error of a table", query: "error({code: 1})", errMsg: "{\"code\":1} (not a string)"},
+		{name: "error stops the stream", query: "1, error(\"stop\"), 2", errMsg: "stop"},
+
+		// try and catch
+		{name: "catch a raised value", query: "try error(\"bad\") catch .", expected: []interface{}{"bad"}},
+		{name: "catch a raised table", query: "try error({code: 1}) catch .code", expected: []interface{}{int64(1)}},
+		{name: "catch a builtin error", query: "try (.name | keys) catch .", expected: []interface{}{"string has no keys"}},
+		{name: "try without catch", query: "[try error(\"bad\")]", expected: []interface{}{[]interface{}{}}},
+		{name: "no error", query: "try .name catch \"none\"", expected: []interface{}{"demo"}},
+		{name: "results before the error", query: "[try (1, error(\"x\"), 2) catch \"caught\"]", expected: []interface{}{[]interface{}{int64(1), "caught"}}},
+		{name: "error in the handler", query: "try error(\"a\") catch error(\"b\")", errMsg: "b"},
+		{name: "rethrow from the handler", query: "try (try error(\"inner\") catch error(. + \"!\")) catch .", expected: []interface{}{"inner!"}},
+		{name: "handler is a term", query: "try error(\"abc\") catch . | length", expected: []interface{}{int64(3)}},
+		{name: "later errors are not caught", query: "try .name catch \"none\" | error", errMsg: "demo"},
+		{name: "question mark", query: "[.servers[] | (.port | error)?]", expected: []interface{}{[]interface{}{}}},
+		{name: "try inside limit", query: "[limit(1; try (1, 2) catch 0)]", expected: []interface{}{[]interface{}{int64(1)}}},
+
+		// if
+		{name: "if then else", query: "[.servers[] | if .tls then \"https\" else \"http\" end]", expected: []interface{}{[]interface{}{"https", "http"}}},
+		{name: "elif", query: "[.servers[].port | if . < 1024 then \"low\" elif . < 8000 then \"mid\" else \"high\" end]", expected: []interface{}{[]interface{}{"high", "low"}}},
+		{name: "if without else", query: "[.servers[].port | if . < 1024 then . + 1000 end]", expected: []interface{}{[]interface{}{int64(8080), int64(1022)}}},
+		{name: "if per condition result", query: "[if (true, false) then 1 else 2 end]", expected: []interface{}{[]interface{}{int64(1), int64(2)}}},
+		{name: "null is false", query: "if null then 1 else 2 end", expected: []interface{}{int64(2)}},
+		{name: "raise on a condition", query: ".servers[] | if .port < 1024 then error(\"privileged port\") else .name end", errMsg: "privileged port"},
+		{name: "catch a raised condition", query: "[.servers[] | try (if .port < 1024 then error(.name) else . end) catch (\"rejected \" + .)]", expected: []interface{}{[]interface{}{data["servers"].([]map[string]interface{})[0], "rejected ssh"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestExecute_UserError(t *testing.T) {
+	q, err := New(`error({code: 7})`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	_, err = q.Execute(createTryTestData())
+
+	var userErr *UserError
+	if !errors.As(err, &userErr) {
+		t.Fatalf("expected a UserError, got %T: %v", err, err)
+	}
+	if code := userErr.Value.(map[string]interface{})["code"]; code != int64(7) {
+		t.Errorf("expected the raised value, got %#v", userErr.Value)
+	}
+
+	q, _ = New(`.name | keys`)
+	if _, err := q.Execute(createTryTestData()); errors.As(err, &userErr) {
+		t.Errorf("expected a builtin error not to be a UserError")
+	}
+}
+
+func TestNew_TryCatch(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		errMsg  string
+		wantStr string
+	}{
+		{query: "try .a catch .b", wantStr: "try .a catch .b"},
+		{query: "try .a", wantStr: ".a?"},
+		{query: "try (.a | .b) catch (. | length)", wantStr: "try (.a | .b) catch (. | length)"},
+		{query: "(try .a catch .b)[0]", wantStr: "(try .a catch .b)[0]"},
+		{query: "try .a catch .b + 1", wantStr: "try .a catch .b + 1"},
+		{query: "if . then 1 else 2 end", wantStr: "if . then 1 else 2 end"},
+		{query: "if .a then 1 elif .b then 2 end", wantStr: "if .a then 1 elif .b then 2 end"},
+		{query: "if .a|.b then .c|.d end|.e", wantStr: "if .a | .b then .c | .d end | .e"},
+		{query: "if . then 1", wantErr: true, errMsg: "expected 'elif', 'else' or 'end', got end of query"},
+		{query: "if . 1 end", wantErr: true, errMsg: "expected 'then', got '1'"},
+		{query: "if . then 1 else 2", wantErr: true, errMsg: "expected 'end', got end of query"},
+		{query: "try", wantErr: true, errMsg: "unexpected end of query"},
+		{query: "catch", wantErr: true, errMsg: "unexpected 'catch'"},
+		{query: "def end: 1; end", wantErr: true, errMsg: "expected function name after 'def'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, tt.wantErr, tt.errMsg)
+			if q != nil && !tt.wantErr {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
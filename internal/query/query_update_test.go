@@ -0,0 +1,95 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSetPath(t *testing.T) {
+	two := 2
+	tests := []struct {
+		name     string
+		parts    []interface{}
+		value    interface{}
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "key", parts: []interface{}{"a", "b"}, value: int64(2), expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(2)}, "list": []interface{}{int64(1), int64(2), int64(3)}}},
+		{name: "new table", parts: []interface{}{"x", "y"}, value: true, expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "list": []interface{}{int64(1), int64(2), int64(3)}, "x": map[string]interface{}{"y": true}}},
+		{name: "negative index", parts: []interface{}{"list", -1}, value: int64(9), expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "list": []interface{}{int64(1), int64(2), int64(9)}}},
+		{name: "slice", parts: []interface{}{"list", Slice{End: &two}}, value: []interface{}{}, expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "list": []interface{}{int64(3)}}},
+		{name: "iterate", parts: []interface{}{"list", Iterate{}}, value: int64(0), expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "list": []interface{}{int64(0), int64(0), int64(0)}}},
+		{name: "append", parts: []interface{}{"list", 3}, value: int64(4), expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "list": []interface{}{int64(1), int64(2), int64(3), int64(4)}}},
+		{name: "new array", parts: []interface{}{"x", 0, "y"}, value: true, expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "list": []interface{}{int64(1), int64(2), int64(3)}, "x": []interface{}{map[string]interface{}{"y": true}}}},
+		{name: "past the end", parts: []interface{}{"list", 4}, value: int64(4), errMsg: "index out of range: .list[4] (length 3)"},
+		{name: "empty path", parts: []interface{}{}, value: "x", expected: "x"},
+		{name: "into a scalar", parts: []interface{}{"a", "b", "c"}, value: 1, errMsg: "cannot navigate into int64 at .a.b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{
+				"a":    map[string]interface{}{"b": int64(1)},
+				"list": []interface{}{int64(1), int64(2), int64(3)},
+			}
+			result, err := SetPath(data, tt.parts, tt.value)
+			assertUpdate(t, result, err, tt.expected, tt.errMsg)
+
+			// The input is never changed
+			original := map[string]interface{}{
+				"a":    map[string]interface{}{"b": int64(1)},
+				"list": []interface{}{int64(1), int64(2), int64(3)},
+			}
+			if !reflect.DeepEqual(data, original) {
+				t.Errorf("input changed to %#v", data)
+			}
+		})
+	}
+}
+
+func TestDeletePaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		paths    [][]interface{}
+		expected interface{}
+		errMsg   string
+	}{
+		{name: "key", paths: [][]interface{}{{"a", "b"}}, expected: map[string]interface{}{"a": map[string]interface{}{}, "list": []interface{}{int64(1), int64(2), int64(3)}}},
+		{name: "indexes in any order", paths: [][]interface{}{{"list", 0}, {"list", 2}}, expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "list": []interface{}{int64(2)}}},
+		{name: "parent and child", paths: [][]interface{}{{"a"}, {"a", "b"}}, expected: map[string]interface{}{"list": []interface{}{int64(1), int64(2), int64(3)}}},
+		{name: "iterate", paths: [][]interface{}{{"list", Iterate{}}}, expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "list": []interface{}{}}},
+		{name: "no paths", paths: nil, expected: map[string]interface{}{"a": map[string]interface{}{"b": int64(1)}, "list": []interface{}{int64(1), int64(2), int64(3)}}},
+		{name: "missing key", paths: [][]interface{}{{"a", "x"}}, errMsg: "key not found: .a.x"},
+		{name: "out of range", paths: [][]interface{}{{"list", 3}}, errMsg: "index out of range: .list[3] (length 3)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := map[string]interface{}{
+				"a":    map[string]interface{}{"b": int64(1)},
+				"list": []interface{}{int64(1), int64(2), int64(3)},
+			}
+			result, err := DeletePaths(data, tt.paths)
+			assertUpdate(t, result, err, tt.expected, tt.errMsg)
+			if len(data["a"].(map[string]interface{})) != 1 || len(data["list"].([]interface{})) != 3 {
+				t.Errorf("input changed to %#v", data)
+			}
+		})
+	}
+}
+
+func assertUpdate(t *testing.T, result interface{}, err error, expected interface{}, errMsg string) {
+	t.Helper()
+	if errMsg != "" {
+		if err == nil || err.Error() != errMsg {
+			t.Fatalf("expected error %q, got %v", errMsg, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("expected %#v, got %#v", expected, result)
+	}
+}
@@ -0,0 +1,118 @@
+package query
+
+import (
+	"reflect"
+	"testing"
+)
+
+func createVariableTestData() map[string]interface{} {
+	return map[string]interface{}{
+		"project": map[string]interface{}{"name": "demo", "version": "1.2.0"},
+		"servers": []map[string]interface{}{
+			{"host": "web1", "port": int64(80)},
+			{"host": "web2", "port": int64(8080)},
+		},
+		"min": int64(1000),
+	}
+}
+
+func TestExecute_Variables(t *testing.T) {
+	data := createVariableTestData()
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "bind and use", query: ".min as $m | .servers[] | select(.port > $m) | .host", expected: []interface{}{"web2"}},
+		{name: "body gets the same input", query: ".min as $m | .project.name", expected: []interface{}{"demo"}},
+		{name: "one run per result", query: ".servers[].port as $p | $p + 1", expected: []interface{}{int64(81), int64(8081)}},
+		{name: "shadowing", query: "1 as $x | 2 as $x | $x", expected: []interface{}{int64(2)}},
+		{name: "outer variable after inner scope", query: "1 as $x | (2 as $x | $x), $x", expected: []interface{}{int64(2), int64(1)}},
+		{name: "root reference", query: ". as $root | .servers[] | .host + \"@\" + $root.project.name", expected: []interface{}{"web1@demo", "web2@demo"}},
+		{name: "binds a term", query: "1 + 2 as $x | $x * 10", expected: []interface{}{int64(21)}},
+		{name: "variable shorthand in object", query: ".project.name as $name | {$name}", expected: []interface{}{map[string]interface{}{"name": "demo"}}},
+		{name: "inside object value", query: "{a: (1 as $x | $x), b: 2}", expected: []interface{}{map[string]interface{}{"a": int64(1), "b": int64(2)}}},
+		{name: "body stops at object entry", query: "{a: 1 as $x | $x, b: 2}", expected: []interface{}{map[string]interface{}{"a": int64(1), "b": int64(2)}}},
+		{name: "in function argument", query: ".servers | map(.port as $p | $p * 2)", expected: []interface{}{[]interface{}{int64(160), int64(16160)}}},
+		{name: "no source results", query: ".servers[] | select(.port > 9000) as $x | 1", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assertResults(t, tt.query, data, tt.expected, tt.errMsg)
+		})
+	}
+}
+
+func TestNewWithVariables(t *testing.T) {
+	data := createVariableTestData()
+	vars := map[string]interface{}{
+		"v":   `2.0" = "x`,
+		"min": int64(100),
+	}
+
+	tests := []struct {
+		name     string
+		query    string
+		expected []interface{}
+		errMsg   string
+	}{
+		{name: "string variable", query: "$v", expected: []interface{}{`2.0" = "x`}},
+		{name: "compare", query: ".servers[] | select(.port >= $min) | .host", expected: []interface{}{"web2"}},
+		{name: "shadowed by binding", query: "1 as $min | $min", expected: []interface{}{int64(1)}},
+		{name: "undefined", query: "$other", errMsg: "syntax error at column 1: $other is not defined"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := NewWithVariables(tt.query, vars)
+			var results []interface{}
+			if err == nil {
+				results, err = q.Execute(data)
+			}
+			if tt.errMsg != "" {
+				if err == nil || err.Error() != tt.errMsg {
+					t.Fatalf("expected error %q, got %v", tt.errMsg, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(results, tt.expected) {
+				t.Errorf("expected %#v, got %#v", tt.expected, results)
+			}
+		})
+	}
+}
+
+func TestNew_VariableString(t *testing.T) {
+	tests := []struct {
+		query   string
+		wantErr bool
+		errMsg  string
+		wantStr string
+	}{
+		{query: ".a as $x|$x", wantStr: ".a as $x | $x"},
+		{query: "1 + 2 as $x | $x", wantStr: "1 + (2 as $x | $x)"},
+		{query: "(. as $x | $x) | .a", wantStr: "(. as $x | $x) | .a"},
+		{query: ". as $x | {$x, y: $x}", wantStr: ". as $x | {$x, y: $x}"},
+		{query: "$x", wantErr: true, errMsg: "$x is not defined"},
+		{query: "(. as $x | $x), $x", wantErr: true, errMsg: "column 17: $x is not defined"},
+		{query: ". as x | x", wantErr: true, errMsg: "expected variable after 'as', got 'x'"},
+		{query: ". as $x", wantErr: true, errMsg: "expected '|', got end of query"},
+		{query: "as", wantErr: true, errMsg: "unexpected 'as'"},
+		{query: "$", wantErr: true, errMsg: "unexpected '$'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			q := assertQueryCreation(t, tt.query, tt.wantErr, tt.errMsg)
+			if q != nil && !tt.wantErr {
+				assertQueryString(t, q, tt.wantStr)
+			}
+		})
+	}
+}
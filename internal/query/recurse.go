@@ -0,0 +1,126 @@
+package query
+
+// recurseValues emits v and everything inside it, each table or array
+// before its contents
+func recurseValues(v interface{}, emit emitFunc) error {
+	if err := emit(v); err != nil {
+		return err
+	}
+	if table := asTable(v); table != nil {
+		for _, key := range sortedKeys(table) {
+			if err := recurseValues(table[key], emit); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !isArray(v) {
+		return nil
+	}
+	values, _ := iterate(v)
+	for _, value := range values {
+		if err := recurseValues(value, emit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (recurseNode) eval(_ *environment, in interface{}, emit emitFunc) error {
+	return recurseValues(in, emit)
+}
+
+func (recurseNode) evalPaths(_ *environment, in pathValue, emit pathEmitFunc) error {
+	if err := emit(in); err != nil {
+		return err
+	}
+	return walkPaths(in, emit)
+}
+
+// funcRecurse emits its input and recursively the results of f applied
+// to it: recurse is "..", recurse(f) stops where f produces nothing and
+// recurse(f; cond) also where cond is false
+func funcRecurse(env *environment, in interface{}, args []node, emit emitFunc) error {
+	if len(args) == 0 {
+		return recurseValues(in, emit)
+	}
+	if err := emit(in); err != nil {
+		return err
+	}
+	return args[0].eval(env, in, func(v interface{}) error {
+		if len(args) == 1 {
+			return funcRecurse(env, v, args, emit)
+		}
+		return args[1].eval(env, v, func(cond interface{}) error {
+			if !isTruthy(cond) {
+				return nil
+			}
+			return funcRecurse(env, v, args, emit)
+		})
+	})
+}
+
+// pathRecurse is the path form of recurse, for which f must be a path
+// expression itself
+func pathRecurse(env *environment, in pathValue, args []node, emit pathEmitFunc) error {
+	if len(args) == 0 {
+		return recurseNode{}.evalPaths(env, in, emit)
+	}
+	if err := emit(in); err != nil {
+		return err
+	}
+	return evalPaths(env, args[0], in, func(pv pathValue) error {
+		if len(args) == 1 {
+			return pathRecurse(env, pv, args, emit)
+		}
+		return args[1].eval(env, pv.value, func(cond interface{}) error {
+			if !isTruthy(cond) {
+				return nil
+			}
+			return pathRecurse(env, pv, args, emit)
+		})
+	})
+}
+
+// funcWalk rewrites its input bottom-up: f is applied to every element and
+// value first, then to the table or array rebuilt from the results. As in
+// jq, a table value takes the first result of f and is dropped when there
+// is none, while an array takes all of them.
+func funcWalk(env *environment, in interface{}, args []node, emit emitFunc) error {
+	rebuilt := in
+	if table := asTable(in); table != nil {
+		result := make(map[string]interface{}, len(table))
+		for key, value := range table {
+			outputs, err := walkResults(env, value, args)
+			if err != nil {
+				return err
+			}
+			if len(outputs) > 0 {
+				result[key] = outputs[0]
+			}
+		}
+		rebuilt = result
+	} else if isArray(in) {
+		values, _ := iterate(in)
+		items := []interface{}{}
+		for _, value := range values {
+			outputs, err := walkResults(env, value, args)
+			if err != nil {
+				return err
+			}
+			items = append(items, outputs...)
+		}
+		rebuilt = fromArray(in, items)
+	}
+	return args[0].eval(env, rebuilt, emit)
+}
+
+// walkResults collects the results of walking v
+func walkResults(env *environment, v interface{}, args []node) ([]interface{}, error) {
+	var results []interface{}
+	err := funcWalk(env, v, args, func(result interface{}) error {
+		results = append(results, result)
+		return nil
+	})
+	return results, err
+}
@@ -0,0 +1,122 @@
+package query
+
+import (
+	"fmt"
+)
+
+// funcTostream flattens its input into stream events, as in jq: [path,
+// leaf] for every value that is not a non-empty table or array, and
+// [path] closing each non-empty table or array, where path is that of its
+// last element or value
+func funcTostream(_ *environment, in interface{}, _ []node, emit emitFunc) error {
+	return streamEvents([]interface{}{}, in, emit)
+}
+
+func streamEvents(path []interface{}, v interface{}, emit emitFunc) error {
+	if asTable(v) == nil && !isArray(v) {
+		return emit([]interface{}{pathToValue(path), v})
+	}
+	var last []interface{}
+	err := emitChildPaths(pathValue{path: path, value: v}, func(child pathValue) error {
+		last = child.path
+		return streamEvents(child.path, child.value, emit)
+	})
+	if err != nil {
+		return err
+	}
+	if last == nil {
+		// Empty tables and arrays are leaves
+		return emit([]interface{}{pathToValue(path), v})
+	}
+	return emit([]interface{}{pathToValue(last)})
+}
+
+// funcFromstream rebuilds the values whose stream events f produces,
+// emitting each once its closing event arrives
+func funcFromstream(env *environment, in interface{}, args []node, emit emitFunc) error {
+	var value interface{}
+	return args[0].eval(env, in, func(event interface{}) error {
+		path, leaf, isLeaf, err := parseStreamEvent(event)
+		if err != nil {
+			return err
+		}
+		if isLeaf {
+			if len(path) == 0 {
+				return emit(leaf)
+			}
+			value, err = streamSet(value, path, leaf)
+			return err
+		}
+		if len(path) > 1 {
+			return nil
+		}
+		done := value
+		value = nil
+		return emit(done)
+	})
+}
+
+// parseStreamEvent splits a [path, leaf] or [path] event
+func parseStreamEvent(event interface{}) (path []interface{}, leaf interface{}, isLeaf bool, err error) {
+	items, ok := event.([]interface{})
+	if ok && (len(items) == 1 || len(items) == 2) && isArray(items[0]) {
+		path, err = valueToPath(items[0])
+		if err == nil && len(items) == 2 {
+			return path, items[1], true, nil
+		}
+		if err == nil && len(path) > 0 {
+			return path, nil, false, nil
+		}
+	}
+	if err == nil {
+		err = fmt.Errorf("%s is not a valid stream event", formatValue(event))
+	}
+	return nil, nil, false, err
+}
+
+// streamSet stores leaf at path inside v, which belongs to fromstream
+// alone and is changed in place. Events arrive in order, so arrays grow
+// by one element at a time.
+func streamSet(v interface{}, path []interface{}, leaf interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return leaf, nil
+	}
+	switch part := path[0].(type) {
+	case string:
+		table, ok := v.(map[string]interface{})
+		if v == nil {
+			table, ok = map[string]interface{}{}, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("stream event key %q does not fit in %s", part, typeName(v))
+		}
+		child, err := streamSet(table[part], path[1:], leaf)
+		if err != nil {
+			return nil, err
+		}
+		table[part] = child
+		return table, nil
+	case int:
+		items, ok := v.([]interface{})
+		if v == nil {
+			items, ok = []interface{}{}, true
+		}
+		if !ok {
+			return nil, fmt.Errorf("stream event index %d does not fit in %s", part, typeName(v))
+		}
+		switch {
+		case part == len(items):
+			items = append(items, nil)
+		case part < 0 || part > len(items):
+			return nil, fmt.Errorf("stream event index %d is out of order", part)
+		}
+		child, err := streamSet(items[part], path[1:], leaf)
+		if err != nil {
+			return nil, err
+		}
+		items[part] = child
+		return items, nil
+	default:
+		return nil, fmt.Errorf("stream event paths cannot contain slices")
+	}
+}
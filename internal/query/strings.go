@@ -0,0 +1,351 @@
+package query
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringInput returns the input of a string builtin
+func stringInput(name string, in interface{}) (string, error) {
+	s, ok := in.(string)
+	if !ok {
+		return "", fmt.Errorf("%s cannot be applied to %s, expected a string", name, typeName(in))
+	}
+	return s, nil
+}
+
+// stringArg returns a string argument of a builtin
+func stringArg(name string, arg interface{}) (string, error) {
+	s, ok := arg.(string)
+	if !ok {
+		return "", fmt.Errorf("%s expects a string argument, got %s", name, typeName(arg))
+	}
+	return s, nil
+}
+
+// funcSplit splits a string on a literal separator
+func funcSplit(in interface{}, args []interface{}) (interface{}, error) {
+	s, err := stringInput("split", in)
+	if err != nil {
+		return nil, err
+	}
+	sep, err := stringArg("split", args[0])
+	if err != nil {
+		return nil, err
+	}
+	if s == "" {
+		return []interface{}{}, nil
+	}
+	return stringsToValues(strings.Split(s, sep)), nil
+}
+
+// funcSplitRegex splits a string on the matches of a regex
+func funcSplitRegex(in interface{}, args []interface{}) (interface{}, error) {
+	s, err := stringInput("split", in)
+	if err != nil {
+		return nil, err
+	}
+	// As in jq, every match splits, with or without the "g" flag
+	re, _, err := compileRegex("split", args[0], args[1])
+	if err != nil {
+		return nil, err
+	}
+	return stringsToValues(re.Split(s, -1)), nil
+}
+
+// funcJoin joins the elements of an array with a separator. Numbers and
+// booleans are written out and null counts as an empty string.
+func funcJoin(in interface{}, args []interface{}) (interface{}, error) {
+	sep, err := stringArg("join", args[0])
+	if err != nil {
+		return nil, err
+	}
+	items, err := elements("join", in)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]string, len(items))
+	for i, item := range items {
+		switch v := item.(type) {
+		case nil:
+		case string:
+			parts[i] = v
+		case bool:
+			parts[i] = strconv.FormatBool(v)
+		case int64, int, float64:
+			parts[i] = formatNumber(v)
+		default:
+			return nil, fmt.Errorf("cannot join %s", typeName(item))
+		}
+	}
+	return strings.Join(parts, sep), nil
+}
+
+// formatNumber writes a number the way queries print it
+func formatNumber(v interface{}) string {
+	switch n := v.(type) {
+	case int64:
+		return strconv.FormatInt(n, 10)
+	case int:
+		return strconv.Itoa(n)
+	default:
+		return strconv.FormatFloat(toFloat(v), 'g', -1, 64)
+	}
+}
+
+// asciiCase returns a builtin mapping the ASCII letters of a string and
+// leaving every other character alone
+func asciiCase(name string, from, to byte) func(interface{}) (interface{}, error) {
+	return func(in interface{}) (interface{}, error) {
+		s, err := stringInput(name, in)
+		if err != nil {
+			return nil, err
+		}
+		b := []byte(s)
+		for i, c := range b {
+			if c >= from && c < from+26 {
+				b[i] = c - from + to
+			}
+		}
+		return string(b), nil
+	}
+}
+
+// affixTest returns startswith or endswith
+func affixTest(name string, test func(s, affix string) bool) func(interface{}, []interface{}) (interface{}, error) {
+	return func(in interface{}, args []interface{}) (interface{}, error) {
+		s, err := stringInput(name, in)
+		if err != nil {
+			return nil, err
+		}
+		affix, err := stringArg(name, args[0])
+		if err != nil {
+			return nil, err
+		}
+		return test(s, affix), nil
+	}
+}
+
+// affixTrim returns ltrimstr or rtrimstr. As in jq, inputs and arguments
+// that are not strings leave the input unchanged.
+func affixTrim(trim func(s, affix string) string) func(interface{}, []interface{}) (interface{}, error) {
+	return func(in interface{}, args []interface{}) (interface{}, error) {
+		s, ok := in.(string)
+		affix, argOK := args[0].(string)
+		if !ok || !argOK {
+			return in, nil
+		}
+		return trim(s, affix), nil
+	}
+}
+
+// compileRegex compiles a regex argument with jq flags, which may be null.
+// It reports whether the "g" flag asks for every match.
+//
+// Regexes use RE2 syntax. The flags are g (every match), i (ignore case),
+// s (dot matches newline), m (^ and $ match at line breaks), n (ignore
+// empty matches, see [regexMatches]) and l (leftmost-longest matches).
+func compileRegex(name string, pattern, flags interface{}) (*regexp.Regexp, bool, error) {
+	expr, err := stringArg(name, pattern)
+	if err != nil {
+		return nil, false, err
+	}
+	flagString := ""
+	if flags != nil {
+		if flagString, err = stringArg(name, flags); err != nil {
+			return nil, false, err
+		}
+	}
+
+	global, longest := false, false
+	var inline strings.Builder
+	for _, flag := range flagString {
+		switch flag {
+		case 'g':
+			global = true
+		case 'i', 's', 'm':
+			inline.WriteRune(flag)
+		case 'l':
+			longest = true
+		case 'n':
+			// Empty matches are dropped by the callers
+		default:
+			return nil, false, fmt.Errorf("%s: unsupported regex flag '%c'", name, flag)
+		}
+	}
+	if inline.Len() > 0 {
+		expr = "(?" + inline.String() + ")" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: invalid regex: %v", name, err)
+	}
+	if longest {
+		re.Longest()
+	}
+	return re, global, nil
+}
+
+// regexMatches returns the submatch indexes of the first match, or of
+// every match when global is set, dropping empty matches for the "n" flag
+func regexMatches(re *regexp.Regexp, s string, global bool, flags interface{}) [][]int {
+	limit := 1
+	if global {
+		limit = -1
+	}
+	matches := re.FindAllStringSubmatchIndex(s, limit)
+	if f, ok := flags.(string); ok && strings.ContainsRune(f, 'n') {
+		kept := matches[:0]
+		for _, m := range matches {
+			if m[1] > m[0] {
+				kept = append(kept, m)
+			}
+		}
+		matches = kept
+	}
+	return matches
+}
+
+// matchObject describes a match the way jq does, with offsets and lengths
+// counted in characters
+func matchObject(re *regexp.Regexp, s string, m []int) map[string]interface{} {
+	chars := func(from, to int) int64 {
+		return int64(utf8.RuneCountInString(s[from:to]))
+	}
+
+	names := re.SubexpNames()
+	captures := []interface{}{}
+	for i := 1; i < len(m)/2; i++ {
+		capture := map[string]interface{}{"name": nil}
+		if names[i] != "" {
+			capture["name"] = names[i]
+		}
+		if start, end := m[2*i], m[2*i+1]; start < 0 {
+			capture["offset"], capture["length"], capture["string"] = int64(-1), int64(0), nil
+		} else {
+			capture["offset"], capture["length"], capture["string"] = chars(0, start), chars(start, end), s[start:end]
+		}
+		captures = append(captures, capture)
+	}
+
+	return map[string]interface{}{
+		"offset":   chars(0, m[0]),
+		"length":   chars(m[0], m[1]),
+		"string":   s[m[0]:m[1]],
+		"captures": captures,
+	}
+}
+
+// captureObject maps the names of the named groups of a match to the text
+// they matched, or null for groups that did not take part
+func captureObject(re *regexp.Regexp, s string, m []int) map[string]interface{} {
+	result := make(map[string]interface{})
+	for i, name := range re.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+		if m[2*i] < 0 {
+			result[name] = nil
+		} else {
+			result[name] = s[m[2*i]:m[2*i+1]]
+		}
+	}
+	return result
+}
+
+// regexFunc returns a builtin that runs a regex given as the first
+// argument, with optional flags as the second, and emits the values that
+// build derives from the matches
+func regexFunc(name string, build func(re *regexp.Regexp, s string, matches [][]int, emit emitFunc) error) builtinFunc {
+	return func(env *environment, in interface{}, args []node, emit emitFunc) error {
+		s, err := stringInput(name, in)
+		if err != nil {
+			return err
+		}
+		return evalArgs(env, in, args, nil, func(values []interface{}) error {
+			var flags interface{}
+			if len(values) > 1 {
+				flags = values[1]
+			}
+			re, global, err := compileRegex(name, values[0], flags)
+			if err != nil {
+				return err
+			}
+			return build(re, s, regexMatches(re, s, global, flags), emit)
+		})
+	}
+}
+
+// funcTest reports whether the regex matches
+var funcTest = regexFunc("test", func(_ *regexp.Regexp, _ string, matches [][]int, emit emitFunc) error {
+	return emit(len(matches) > 0)
+})
+
+// funcMatch emits a match object for every match
+var funcMatch = regexFunc("match", func(re *regexp.Regexp, s string, matches [][]int, emit emitFunc) error {
+	for _, m := range matches {
+		if err := emit(matchObject(re, s, m)); err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
+// funcCapture emits a table of the named groups for every match
+var funcCapture = regexFunc("capture", func(re *regexp.Regexp, s string, matches [][]int, emit emitFunc) error {
+	for _, m := range matches {
+		if err := emit(captureObject(re, s, m)); err != nil {
+			return err
+		}
+	}
+	return nil
+})
+
+// substitute returns sub (global false) or gsub (global true). The
+// replacement is a query run against the table of named captures of each
+// match; when it produces several results, so does the substitution.
+func substitute(name string, global bool) builtinFunc {
+	return func(env *environment, in interface{}, args []node, emit emitFunc) error {
+		s, err := stringInput(name, in)
+		if err != nil {
+			return err
+		}
+		regexArgs := []node{args[0]}
+		if len(args) > 2 {
+			regexArgs = append(regexArgs, args[2])
+		}
+		return evalArgs(env, in, regexArgs, nil, func(values []interface{}) error {
+			var flags interface{}
+			if len(values) > 1 {
+				flags = values[1]
+			}
+			re, g, err := compileRegex(name, values[0], flags)
+			if err != nil {
+				return err
+			}
+			matches := regexMatches(re, s, global || g, flags)
+			return replaceMatches(env, re, s, matches, args[1], 0, "", emit)
+		})
+	}
+}
+
+// replaceMatches builds the result of a substitution from matches, having
+// already written done up to the byte offset pos
+func replaceMatches(env *environment, re *regexp.Regexp, s string, matches [][]int, replacement node, pos int, done string, emit emitFunc) error {
+	if len(matches) == 0 {
+		return emit(done + s[pos:])
+	}
+	m := matches[0]
+	return replacement.eval(env, captureObject(re, s, m), func(r interface{}) error {
+		text, ok := r.(string)
+		if !ok {
+			return fmt.Errorf("replacement must be a string, got %s", typeName(r))
+		}
+		return replaceMatches(env, re, s, matches[1:], replacement, m[1], done+s[pos:m[0]]+text, emit)
+	})
+}
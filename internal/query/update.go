@@ -0,0 +1,347 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+)
+
+// SetPath returns root with value stored at the path, whose parts are
+// those of [Query.Parts]. Tables and arrays along the path are copied
+// rather than changed, so root itself is left alone; missing tables and
+// arrays are created, and an index equal to the length of an array
+// appends to it. An empty path replaces root with value.
+func SetPath(root interface{}, parts []interface{}, value interface{}) (interface{}, error) {
+	if len(parts) == 0 {
+		return value, nil
+	}
+	return setIn(root, parts, 0, value)
+}
+
+// DeletePaths returns root without the values at the paths, whose parts
+// are those of [Query.Parts]. As in jq, the paths are deleted from the
+// last in sort order to the first, so that deleting an array element does
+// not move the elements later paths refer to. Like [SetPath], DeletePaths
+// copies what it changes. Deleting the empty path leaves null.
+func DeletePaths(root interface{}, paths [][]interface{}) (interface{}, error) {
+	sorted := append([][]interface{}(nil), paths...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return compareValues(pathToValue(sorted[i]), pathToValue(sorted[j])) > 0
+	})
+
+	for _, parts := range sorted {
+		if len(parts) == 0 {
+			return nil, nil
+		}
+		var err error
+		if root, err = deleteIn(root, parts, 0); err != nil {
+			return nil, err
+		}
+	}
+	return root, nil
+}
+
+// setIn sets value at path[depth:] inside container and returns the
+// updated copy of container
+func setIn(container interface{}, path []interface{}, depth int, value interface{}) (interface{}, error) {
+	last := depth == len(path)-1
+
+	switch part := path[depth].(type) {
+	case string:
+		var table map[string]interface{}
+		switch v := container.(type) {
+		case map[string]interface{}:
+			table = copyTable(v)
+		case nil:
+			// Create nested map
+			table = make(map[string]interface{})
+		default:
+			return nil, navigationError(container, path[:depth])
+		}
+
+		if last {
+			table[part] = value
+			return table, nil
+		}
+		child, err := setIn(table[part], path, depth+1, value)
+		if err != nil {
+			return nil, err
+		}
+		table[part] = child
+		return table, nil
+
+	case int:
+		// A missing array is created, and the index just past the end
+		// appends to the array
+		var items []interface{}
+		if container != nil {
+			var ok bool
+			if items, ok = toArray(container); !ok {
+				return nil, navigationError(container, path[:depth])
+			}
+		}
+		idx := part
+		if idx == len(items) {
+			items = append(items, nil)
+		} else {
+			var err error
+			if idx, err = resolveIndex(part, len(items), path[:depth+1]); err != nil {
+				return nil, err
+			}
+		}
+
+		if last {
+			items[idx] = value
+		} else {
+			child, err := setIn(items[idx], path, depth+1, value)
+			if err != nil {
+				return nil, err
+			}
+			items[idx] = child
+		}
+		return fromArray(container, items), nil
+
+	case Slice:
+		items, ok := toArray(container)
+		if !ok {
+			return nil, navigationError(container, path[:depth])
+		}
+		start, end := part.Bounds(len(items))
+
+		// The slice is replaced by an array, either the value itself or the
+		// slice with the rest of the path applied to it
+		replacement := value
+		if !last {
+			sub := append([]interface{}(nil), items[start:end]...)
+			var err error
+			replacement, err = setIn(sub, path, depth+1, value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		newItems, ok := toArray(replacement)
+		if !ok {
+			return nil, fmt.Errorf("cannot assign %T to array slice %s", replacement, FormatPath(path[:depth+1]))
+		}
+
+		spliced := make([]interface{}, 0, len(items)-(end-start)+len(newItems))
+		spliced = append(spliced, items[:start]...)
+		spliced = append(spliced, newItems...)
+		spliced = append(spliced, items[end:]...)
+		return fromArray(container, spliced), nil
+
+	case Iterate:
+		// Every element of an array or every value of a table is updated
+		if table, ok := container.(map[string]interface{}); ok {
+			table = copyTable(table)
+			for key, child := range table {
+				if last {
+					table[key] = value
+					continue
+				}
+				updated, err := setIn(child, path, depth+1, value)
+				if err != nil {
+					return nil, err
+				}
+				table[key] = updated
+			}
+			return table, nil
+		}
+
+		items, ok := toArray(container)
+		if !ok {
+			return nil, navigationError(container, path[:depth])
+		}
+		for i, child := range items {
+			if last {
+				items[i] = value
+				continue
+			}
+			updated, err := setIn(child, path, depth+1, value)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = updated
+		}
+		return fromArray(container, items), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported path part %T", part)
+	}
+}
+
+// deleteIn deletes path[depth:] inside container and returns the updated
+// copy of container
+func deleteIn(container interface{}, path []interface{}, depth int) (interface{}, error) {
+	last := depth == len(path)-1
+
+	switch part := path[depth].(type) {
+	case string:
+		table, ok := container.(map[string]interface{})
+		if !ok {
+			return nil, navigationError(container, path[:depth])
+		}
+		child, exists := table[part]
+		if last {
+			// Delete the final key
+			if !exists {
+				return nil, fmt.Errorf("key not found: %s", FormatPath(path))
+			}
+			table = copyTable(table)
+			delete(table, part)
+			return table, nil
+		}
+		if !exists {
+			return nil, fmt.Errorf("path not found: %s", FormatPath(path[:depth+1]))
+		}
+		child, err := deleteIn(child, path, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		table = copyTable(table)
+		table[part] = child
+		return table, nil
+
+	case int:
+		items, ok := toArray(container)
+		if !ok {
+			return nil, navigationError(container, path[:depth])
+		}
+		idx, err := resolveIndex(part, len(items), path[:depth+1])
+		if err != nil {
+			return nil, err
+		}
+
+		if last {
+			items = append(items[:idx:idx], items[idx+1:]...)
+		} else {
+			child, err := deleteIn(items[idx], path, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items[idx] = child
+		}
+		return fromArray(container, items), nil
+
+	case Slice:
+		items, ok := toArray(container)
+		if !ok {
+			return nil, navigationError(container, path[:depth])
+		}
+		start, end := part.Bounds(len(items))
+
+		if last {
+			items = append(items[:start:start], items[end:]...)
+			return fromArray(container, items), nil
+		}
+		for i := start; i < end; i++ {
+			child, err := deleteIn(items[i], path, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = child
+		}
+		return fromArray(container, items), nil
+
+	case Iterate:
+		// Every element of an array or every value of a table is affected
+		if table, ok := container.(map[string]interface{}); ok {
+			if last {
+				return map[string]interface{}{}, nil
+			}
+			table = copyTable(table)
+			for key, child := range table {
+				updated, err := deleteIn(child, path, depth+1)
+				if err != nil {
+					return nil, err
+				}
+				table[key] = updated
+			}
+			return table, nil
+		}
+
+		items, ok := toArray(container)
+		if !ok {
+			return nil, navigationError(container, path[:depth])
+		}
+		if last {
+			return fromArray(container, []interface{}{}), nil
+		}
+		for i, child := range items {
+			updated, err := deleteIn(child, path, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = updated
+		}
+		return fromArray(container, items), nil
+
+	default:
+		return nil, fmt.Errorf("unsupported path part %T", part)
+	}
+}
+
+// copyTable returns a shallow copy of a table
+func copyTable(table map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(table))
+	for key, value := range table {
+		result[key] = value
+	}
+	return result
+}
+
+// navigationError reports why a path could not be followed into container
+func navigationError(container interface{}, path []interface{}) error {
+	if container == nil {
+		return fmt.Errorf("path not found: %s", FormatPath(path))
+	}
+	return fmt.Errorf("cannot navigate into %T at %s", container, FormatPath(path))
+}
+
+// resolveIndex turns a possibly negative index into a position in an array
+// of the given length
+func resolveIndex(i, length int, path []interface{}) (int, error) {
+	idx := i
+	if idx < 0 {
+		idx += length
+	}
+	if idx < 0 || idx >= length {
+		return 0, fmt.Errorf("index out of range: %s (length %d)", FormatPath(path), length)
+	}
+	return idx, nil
+}
+
+// toArray returns the elements of a TOML array as a fresh []interface{}.
+// Arrays of tables ([[table]]) are decoded as []map[string]interface{},
+// so both shapes are accepted.
+func toArray(v interface{}) ([]interface{}, bool) {
+	switch arr := v.(type) {
+	case []interface{}:
+		return append([]interface{}(nil), arr...), true
+	case []map[string]interface{}:
+		items := make([]interface{}, len(arr))
+		for i, item := range arr {
+			items[i] = item
+		}
+		return items, true
+	default:
+		return nil, false
+	}
+}
+
+// fromArray converts items back to the shape of the original array, so an
+// array of tables stays an array of tables while every element is a table
+func fromArray(original interface{}, items []interface{}) interface{} {
+	if _, ok := original.([]map[string]interface{}); !ok {
+		return items
+	}
+
+	tables := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		table, ok := item.(map[string]interface{})
+		if !ok {
+			return items
+		}
+		tables[i] = table
+	}
+	return tables
+}
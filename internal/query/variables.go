@@ -0,0 +1,72 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+)
+
+// environment holds the bindings in scope during evaluation as a linked
+// list, innermost first. Variables are keyed by name; functions and their
+// filter parameters by their definition, which the parser has already
+// resolved. The nil environment is empty.
+type environment struct {
+	key    interface{}
+	value  interface{}
+	parent *environment
+}
+
+// bind returns env extended with a binding
+func (env *environment) bind(key, value interface{}) *environment {
+	return &environment{key: key, value: value, parent: env}
+}
+
+// find returns the innermost binding of key, or nil
+func (env *environment) find(key interface{}) *environment {
+	for e := env; e != nil; e = e.parent {
+		if e.key == key {
+			return e
+		}
+	}
+	return nil
+}
+
+// lookup returns the value of the innermost binding of key
+func (env *environment) lookup(key interface{}) (interface{}, bool) {
+	if e := env.find(key); e != nil {
+		return e.value, true
+	}
+	return nil, false
+}
+
+// newEnvironment binds vars in key order, so that building the same
+// variables twice gives the same environment
+func newEnvironment(vars map[string]interface{}) *environment {
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var env *environment
+	for _, name := range names {
+		env = env.bind(name, vars[name])
+	}
+	return env
+}
+
+func (n *varNode) eval(env *environment, _ interface{}, emit emitFunc) error {
+	value, ok := env.lookup(n.name)
+	if !ok {
+		// The parser rejects undefined variables, so this is a bug
+		return fmt.Errorf("$%s is not defined", n.name)
+	}
+	return emit(value)
+}
+
+// eval runs the body once for every result of the source, with the
+// variable bound to that result. The body receives the original input.
+func (n *bindNode) eval(env *environment, in interface{}, emit emitFunc) error {
+	return n.source.eval(env, in, func(v interface{}) error {
+		return n.body.eval(env.bind(n.name, v), in, emit)
+	})
+}